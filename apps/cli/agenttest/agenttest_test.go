@@ -0,0 +1,312 @@
+package agenttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+const sampleYAML = `
+name: support
+timeout: 10s
+cases:
+  - name: looks up orders
+    agent: SupportBot
+    prompt: Where is order 42?
+    expect:
+      contains: shipped
+      matches: ["order \\d+"]
+      tools:
+        - lookup_order
+        - name: lookup_order
+          args: {id: 42}
+      max_tool_calls: 2
+      within: 5
+  - name: email webhook
+    agent: SupportBot
+    trigger: email
+    event: {from: "a@example.com"}
+    skip: flaky upstream
+`
+
+func TestParse(t *testing.T) {
+	suite, err := Parse([]byte(sampleYAML), true)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if suite.Name != "support" || time.Duration(suite.Timeout) != 10*time.Second || len(suite.Cases) != 2 {
+		t.Fatalf("Unexpected suite: %+v", suite)
+	}
+	expect := suite.Cases[0].Expect
+	if len(expect.Contains) != 1 || expect.Contains[0] != "shipped" {
+		t.Errorf("Expected a single string to become a list, got %v", expect.Contains)
+	}
+	if len(expect.Tools) != 2 || expect.Tools[0].Name != "lookup_order" || expect.Tools[1].Args["id"] != float64(42) {
+		t.Errorf("Unexpected tools: %+v", expect.Tools)
+	}
+	if *expect.MaxToolCalls != 2 || time.Duration(expect.Within) != 5*time.Second {
+		t.Errorf("Unexpected limits: %d, %s", *expect.MaxToolCalls, time.Duration(expect.Within))
+	}
+	trigger := suite.Cases[1]
+	if !trigger.IsTrigger() || trigger.Target() != "SupportBot/email" || string(trigger.Event) != `{"from":"a@example.com"}` {
+		t.Errorf("Unexpected trigger case: %+v", trigger)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		suite, err := Parse([]byte(`{"cases": [{"agent": "Bot", "prompt": "hi", "expect": {"not_contains": ["error"]}}]}`), false)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if suite.Cases[0].Name != "case 1" || suite.Cases[0].Expect.NotContains[0] != "error" {
+			t.Errorf("Unexpected case: %+v", suite.Cases[0])
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, input := range map[string]string{
+			"unknown assertion": `cases: [{agent: Bot, prompt: hi, expect: {contain: x}}]`,
+			"no agent":          `cases: [{prompt: hi}]`,
+			"no prompt":         `cases: [{agent: Bot}]`,
+			"prompt to trigger": `cases: [{agent: Bot, trigger: email, prompt: hi}]`,
+			"bad regex":         `cases: [{agent: Bot, prompt: hi, expect: {matches: "("}}]`,
+			"bad duration":      `cases: [{agent: Bot, prompt: hi, expect: {within: soon}}]`,
+			"no cases":          `name: empty`,
+		} {
+			if _, err := Parse([]byte(input), true); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	suite, err := Parse([]byte(sampleYAML), true)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	expect := suite.Cases[0].Expect
+
+	passing := &Transcript{
+		Output:    "Your order 42 has shipped.",
+		ToolCalls: []ToolCall{{Name: "lookup_order", Arguments: json.RawMessage(`"{\"id\":42,\"verbose\":true}"`)}},
+		Duration:  time.Second,
+	}
+	if failures := expect.Check(passing); len(failures) != 0 {
+		t.Errorf("Expected no failures, got %v", failures)
+	}
+
+	failing := &Transcript{
+		Output: "Sorry, I don't know.",
+		ToolCalls: []ToolCall{
+			{Name: "lookup_order", Arguments: json.RawMessage(`{"id":7}`)},
+			{Name: "search", Arguments: json.RawMessage(`{}`)},
+			{Name: "search", Arguments: json.RawMessage(`{}`)},
+		},
+		Duration: 6 * time.Second,
+	}
+	failures := expect.Check(failing)
+	for _, expected := range []string{
+		`output does not contain "shipped"`,
+		`output does not match /order \d+/`,
+		`tool lookup_order was not called with {"id":42} (called with: {"id":7})`,
+		"3 tool calls made, at most 2 expected (lookup_order, search, search)",
+		"took 6s, more than the 5s budget",
+	} {
+		found := false
+		for _, failure := range failures {
+			found = found || failure == expected
+		}
+		if !found {
+			t.Errorf("Expected failure %q in %q", expected, failures)
+		}
+	}
+}
+
+func sampleReport() *Report {
+	suite, _ := Parse([]byte(sampleYAML), true)
+	suite.Cases = append(suite.Cases, Case{Name: "broken # case", Agent: "Bot", Prompt: "hi"})
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &Report{Suites: []SuiteResult{{
+		Suite:    suite,
+		Started:  started,
+		Duration: 3 * time.Second,
+		Cases: []CaseResult{
+			{Case: &suite.Cases[0], Status: StatusFailed, Failures: []string{"output does not contain \"shipped\""}, Duration: 1500 * time.Millisecond, Transcript: &Transcript{Output: "Sorry <no>"}},
+			{Case: &suite.Cases[1], Status: StatusSkipped},
+			{Case: &suite.Cases[2], Status: StatusError, Error: "did not complete within 2m0s"},
+		},
+	}}}
+}
+
+func TestReports(t *testing.T) {
+	report := sampleReport()
+	if !report.Failed() {
+		t.Error("Expected the report to have failed")
+	}
+
+	t.Run("tap", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, FormatTAP); err != nil {
+			t.Fatalf("WriteTAP failed: %v", err)
+		}
+		out := buf.String()
+		for _, expected := range []string{
+			"TAP version 13\n1..3\n",
+			"not ok 1 - support: looks up orders\n  ---\n  target: \"SupportBot\"\n",
+			"  failures:\n    - \"output does not contain \\\"shipped\\\"\"\n  duration_ms: 1500\n  ...\n",
+			"ok 2 - support: email webhook # SKIP flaky upstream\n",
+			`not ok 3 - support: broken \# case`,
+			`  error: "did not complete within 2m0s"`,
+		} {
+			if !strings.Contains(out, expected) {
+				t.Errorf("Expected TAP to contain %q:\n%s", expected, out)
+			}
+		}
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, FormatJUnit); err != nil {
+			t.Fatalf("WriteJUnit failed: %v", err)
+		}
+		var decoded junitTestSuites
+		if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("Report is not XML: %v\n%s", err, buf.String())
+		}
+		if decoded.Tests != 3 || decoded.Failures != 1 || decoded.Errors != 1 || decoded.Skipped != 1 {
+			t.Errorf("Unexpected totals: %+v", decoded)
+		}
+		suite := decoded.Suites[0]
+		if suite.Timestamp != "2025-01-02T03:04:05Z" || suite.Time != "3.000" {
+			t.Errorf("Unexpected suite attributes: %+v", suite)
+		}
+		first := suite.Cases[0]
+		if first.ClassName != "support.SupportBot" || first.Time != "1.500" || first.Failure == nil || first.SystemOut != "Sorry <no>" {
+			t.Errorf("Unexpected test case: %+v", first)
+		}
+		if suite.Cases[1].Skipped == nil || suite.Cases[2].Error == nil {
+			t.Errorf("Expected a skipped case and an error, got %+v", suite.Cases[1:])
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := report.Write(&bytes.Buffer{}, "html"); err == nil {
+			t.Error("Expected an error for an unknown format")
+		}
+	})
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.test.yaml", "nested/b.test.json", "notes.yaml", "node_modules/c.test.yml", ".shuttl/d.test.yaml"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("{}"), 0644)
+	}
+	files, err := Find([]string{dir, filepath.Join(dir, "notes.yaml")})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	var names []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if strings.Join(names, ",") != "a.test.yaml,nested/b.test.json,notes.yaml" {
+		t.Errorf("Unexpected files: %v", names)
+	}
+}
+
+// agentApp answers pings, and answers every agent prompt and trigger event
+// by looking up order 42
+const agentApp = `
+while read -r line; do
+  id=$(printf '%s' "$line" | sed 's/.*"id":"\([^"]*\)".*/\1/')
+  case "$line" in
+  *'"method":"ping"'*)
+    printf '{"id":"%s","success":true,"result":{"pong":true,"timestamp":1,"protocol_version":"1.0"}}\n' "$id" ;;
+  *'"method":"listTriggers"'*)
+    printf '{"id":"%s","success":true,"result":[{"name":"email","triggerType":"webhook","agentName":"SupportBot"}]}\n' "$id" ;;
+  *'"method":"invokeTrigger"'*)
+    printf '{"id":"%s","success":true,"result":{"threadId":"t2","status":"acknowledged"}}\n' "$id"
+    printf '{"id":"%s","type":"tool_call","success":true,"result":{"toolCall":{"name":"lookup_order","arguments":"{\\"id\\":42}","callId":"c1"}}}\n' "$id"
+    printf '{"id":"%s","type":"output_text","success":true,"result":{"outputText":{"text":"Emailed: order 42 shipped"}}}\n' "$id"
+    printf '{"id":"%s","success":true,"result":{"threadId":"t2","status":"completed"}}\n' "$id" ;;
+  *'"method":"invokeAgent"'*)
+    printf '{"id":"%s","type":"status","success":true,"result":{"threadId":"t1","status":"invoked"}}\n' "$id"
+    printf '{"id":"%s","type":"tool_call","success":true,"result":{"toolCall":{"name":"lookup_order","arguments":{"id":42},"callId":"c1"}}}\n' "$id"
+    printf '{"id":"%s","type":"output_text_delta","success":true,"result":{"outputTextDelta":{"delta":"Order 42 ","sequenceNumber":1}}}\n' "$id"
+    printf '{"id":"%s","type":"output_text_delta","success":true,"result":{"outputTextDelta":{"delta":"has shipped","sequenceNumber":2}}}\n' "$id"
+    printf '{"id":"%s","type":"status","success":true,"result":{"threadId":"t1","status":"completed"}}\n' "$id" ;;
+  esac
+done`
+
+func TestRunner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	client := ipc.NewClient([]string{"sh", "-c", agentApp})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	suite, err := Parse([]byte(`
+name: orders
+cases:
+  - name: chat
+    agent: SupportBot
+    prompt: Where is order 42?
+    expect: {contains: shipped, tools: [{name: lookup_order, args: {id: 42}}], max_tool_calls: 1}
+  - name: webhook
+    agent: SupportBot
+    trigger: email
+    event: {subject: order 42}
+    expect: {matches: "^Emailed", tools: [lookup_order]}
+  - name: wrong answer
+    agent: SupportBot
+    prompt: Where is order 7?
+    expect: {contains: "order 7"}
+  - name: missing trigger
+    agent: SupportBot
+    trigger: sms
+`), true)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	runner := &Runner{Client: client, Timeout: 5 * time.Second}
+	report := runner.Run(context.Background(), []*Suite{suite})
+	results := report.Suites[0].Cases
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for i, expected := range []string{StatusPassed, StatusPassed, StatusFailed, StatusError} {
+		if results[i].Status != expected {
+			t.Errorf("%s: expected %s, got %s (%v %s)", results[i].Case.Name, expected, results[i].Status, results[i].Failures, results[i].Error)
+		}
+	}
+	if chat := results[0].Transcript; chat.Output != "Order 42 has shipped" || chat.ThreadID != "t1" {
+		t.Errorf("Unexpected chat transcript: %+v", chat)
+	}
+	if !strings.Contains(results[3].Error, "trigger SupportBot/sms not found") {
+		t.Errorf("Unexpected error: %s", results[3].Error)
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		runner.Filter = func(suite, name string) bool { return suite+"/"+name == "orders/chat" }
+		report := runner.Run(context.Background(), []*Suite{suite})
+		if len(report.Suites[0].Cases) != 1 || report.Failed() {
+			t.Errorf("Expected only the passing chat case, got %+v", report.Suites[0].Cases)
+		}
+	})
+}
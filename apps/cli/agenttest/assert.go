@@ -0,0 +1,137 @@
+package agenttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Transcript is what a case's agent did
+type Transcript struct {
+	// Output is the agent's final text
+	Output    string
+	ToolCalls []ToolCall
+	ThreadID  string
+	Duration  time.Duration
+}
+
+// ToolCall is a tool called by the agent
+type ToolCall struct {
+	Name      string
+	Arguments json.RawMessage
+}
+
+// Args decodes the arguments of the call. Arguments sent as a
+// JSON-encoded string are decoded first.
+func (c ToolCall) Args() map[string]any {
+	data := c.Arguments
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = json.RawMessage(encoded)
+	}
+	var args map[string]any
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil
+	}
+	return args
+}
+
+// Check returns a message for each assertion the transcript fails
+func (e *Expect) Check(t *Transcript) []string {
+	var failures []string
+
+	for _, text := range e.Contains {
+		if !strings.Contains(t.Output, text) {
+			failures = append(failures, fmt.Sprintf("output does not contain %q", text))
+		}
+	}
+	for _, text := range e.NotContains {
+		if strings.Contains(t.Output, text) {
+			failures = append(failures, fmt.Sprintf("output contains %q", text))
+		}
+	}
+	for _, pattern := range e.Matches {
+		// Patterns are checked when the suite is loaded
+		if !regexp.MustCompile(pattern).MatchString(t.Output) {
+			failures = append(failures, fmt.Sprintf("output does not match /%s/", pattern))
+		}
+	}
+
+	for _, expected := range e.Tools {
+		if !calledWith(t.ToolCalls, expected) {
+			failures = append(failures, toolFailure(t.ToolCalls, expected))
+		}
+	}
+	if e.MaxToolCalls != nil && len(t.ToolCalls) > *e.MaxToolCalls {
+		failures = append(failures, fmt.Sprintf("%d tool calls made, at most %d expected (%s)", len(t.ToolCalls), *e.MaxToolCalls, toolNames(t.ToolCalls)))
+	}
+
+	if e.Within > 0 && t.Duration > time.Duration(e.Within) {
+		failures = append(failures, fmt.Sprintf("took %s, more than the %s budget", t.Duration.Round(time.Millisecond), time.Duration(e.Within)))
+	}
+	return failures
+}
+
+// calledWith reports whether any call matches the expected tool
+func calledWith(calls []ToolCall, expected ToolExpectation) bool {
+	for _, call := range calls {
+		if call.Name == expected.Name && containsArgs(call.Args(), expected.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsArgs reports whether each expected argument has the same value in
+// args. Values are compared as JSON, so 42 matches 42.0.
+func containsArgs(args, expected map[string]any) bool {
+	for name, want := range expected {
+		got, ok := args[name]
+		if !ok || !reflect.DeepEqual(normalize(got), normalize(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize round-trips a value through JSON so that values decoded from
+// YAML and from JSON compare equal
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func toolFailure(calls []ToolCall, expected ToolExpectation) string {
+	var withName []string
+	for _, call := range calls {
+		if call.Name == expected.Name {
+			withName = append(withName, string(call.Arguments))
+		}
+	}
+	if len(withName) == 0 {
+		return fmt.Sprintf("tool %s was not called (called: %s)", expected.Name, toolNames(calls))
+	}
+	want, _ := json.Marshal(expected.Args)
+	return fmt.Sprintf("tool %s was not called with %s (called with: %s)", expected.Name, want, strings.Join(withName, ", "))
+}
+
+func toolNames(calls []ToolCall) string {
+	if len(calls) == 0 {
+		return "none"
+	}
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,215 @@
+package agenttest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report formats
+const (
+	FormatText  = "text"
+	FormatTAP   = "tap"
+	FormatJUnit = "junit"
+)
+
+// Write writes the report in a format
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		return r.WriteText(w)
+	case FormatTAP:
+		return r.WriteTAP(w)
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	}
+	return fmt.Errorf("unknown report format %q; use %s, %s or %s", format, FormatText, FormatTAP, FormatJUnit)
+}
+
+// WriteText writes a summary for people reading a terminal
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, suite := range r.Suites {
+		fmt.Fprintf(&b, "🧪 %s (%s)\n", suite.Suite.Name, suite.Suite.Path)
+		for _, result := range suite.Cases {
+			switch result.Status {
+			case StatusPassed:
+				fmt.Fprintf(&b, "  ✅ %s (%s)\n", result.Case.Name, result.Duration.Round(time.Millisecond))
+			case StatusSkipped:
+				fmt.Fprintf(&b, "  ⏭️  %s: skipped, %s\n", result.Case.Name, result.Case.Skip)
+			case StatusFailed:
+				fmt.Fprintf(&b, "  ❌ %s (%s)\n", result.Case.Name, result.Duration.Round(time.Millisecond))
+				for _, failure := range result.Failures {
+					fmt.Fprintf(&b, "     • %s\n", failure)
+				}
+			case StatusError:
+				fmt.Fprintf(&b, "  💥 %s: %s\n", result.Case.Name, result.Error)
+			}
+		}
+		b.WriteString("\n")
+	}
+	counts := r.Counts()
+	fmt.Fprintf(&b, "%d passed, %d failed, %d errors, %d skipped\n",
+		counts[StatusPassed], counts[StatusFailed], counts[StatusError], counts[StatusSkipped])
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteTAP writes the report as TAP version 13. Failures are described in
+// a YAML block after the test line.
+func (r *Report) WriteTAP(w io.Writer) error {
+	var b strings.Builder
+	total := 0
+	for _, suite := range r.Suites {
+		total += len(suite.Cases)
+	}
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", total)
+
+	n := 0
+	for _, suite := range r.Suites {
+		for _, result := range suite.Cases {
+			n++
+			description := tapEscape(suite.Suite.Name + ": " + result.Case.Name)
+			switch result.Status {
+			case StatusPassed:
+				fmt.Fprintf(&b, "ok %d - %s\n", n, description)
+			case StatusSkipped:
+				fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", n, description, tapEscape(result.Case.Skip))
+			default:
+				fmt.Fprintf(&b, "not ok %d - %s\n", n, description)
+				b.WriteString("  ---\n")
+				fmt.Fprintf(&b, "  target: %q\n", result.Case.Target())
+				if result.Error != "" {
+					fmt.Fprintf(&b, "  error: %q\n", result.Error)
+				}
+				if len(result.Failures) > 0 {
+					b.WriteString("  failures:\n")
+					for _, failure := range result.Failures {
+						fmt.Fprintf(&b, "    - %q\n", failure)
+					}
+				}
+				fmt.Fprintf(&b, "  duration_ms: %d\n", result.Duration.Milliseconds())
+				b.WriteString("  ...\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tapEscape keeps a description on one line and escapes the characters TAP
+// gives meaning to
+func tapEscape(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	return strings.ReplaceAll(text, "#", `\#`)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	File      string          `xml:"file,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the report as JUnit XML, one testsuite per suite file.
+// Failed assertions are failures; cases that could not run are errors.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	root := junitTestSuites{}
+	var total time.Duration
+	for _, suite := range r.Suites {
+		out := junitTestSuite{
+			Name:      suite.Suite.Name,
+			Time:      seconds(suite.Duration),
+			Timestamp: suite.Started.UTC().Format(time.RFC3339),
+			File:      suite.Suite.Path,
+		}
+		for _, result := range suite.Cases {
+			testCase := junitTestCase{
+				Name:      result.Case.Name,
+				ClassName: suite.Suite.Name + "." + result.Case.Target(),
+				Time:      seconds(result.Duration),
+			}
+			if result.Transcript != nil && result.Status != StatusPassed {
+				testCase.SystemOut = result.Transcript.Output
+			}
+			switch result.Status {
+			case StatusFailed:
+				out.Failures++
+				testCase.Failure = &junitProblem{
+					Message: result.Failures[0],
+					Type:    "AssertionError",
+					Text:    strings.Join(result.Failures, "\n"),
+				}
+			case StatusError:
+				out.Errors++
+				testCase.Error = &junitProblem{Message: result.Error, Type: "Error", Text: result.Error}
+			case StatusSkipped:
+				out.Skipped++
+				testCase.Skipped = &junitSkipped{Message: result.Case.Skip}
+			}
+			out.Cases = append(out.Cases, testCase)
+		}
+		out.Tests = len(out.Cases)
+		root.Tests += out.Tests
+		root.Failures += out.Failures
+		root.Errors += out.Errors
+		root.Skipped += out.Skipped
+		total += suite.Duration
+		root.Suites = append(root.Suites, out)
+	}
+	root.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats a duration as seconds with millisecond precision
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
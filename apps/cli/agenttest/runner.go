@@ -0,0 +1,290 @@
+package agenttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// DefaultTimeout is how long a case may run when neither the case nor its
+// suite sets a timeout
+const DefaultTimeout = 2 * time.Minute
+
+// Case outcomes
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusError   = "error"
+	StatusSkipped = "skipped"
+)
+
+// CaseResult is the outcome of a case
+type CaseResult struct {
+	Case   *Case
+	Status string
+	// Failures are the assertions that did not hold
+	Failures []string
+	// Error is why the case could not run to completion
+	Error      string
+	Transcript *Transcript
+	Duration   time.Duration
+}
+
+// SuiteResult holds the outcomes of a suite's cases
+type SuiteResult struct {
+	Suite    *Suite
+	Cases    []CaseResult
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Report holds the results of a test run
+type Report struct {
+	Suites []SuiteResult
+}
+
+// Counts returns the number of cases with each outcome
+func (r *Report) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, suite := range r.Suites {
+		for _, result := range suite.Cases {
+			counts[result.Status]++
+		}
+	}
+	return counts
+}
+
+// Failed reports whether any case failed or could not run
+func (r *Report) Failed() bool {
+	counts := r.Counts()
+	return counts[StatusFailed] > 0 || counts[StatusError] > 0
+}
+
+// Runner runs cases against an app
+type Runner struct {
+	Client *ipc.Client
+	// Timeout overrides the timeout of every case when set
+	Timeout time.Duration
+	// Filter selects the cases to run by "suite/case" name; nil runs all
+	Filter func(suite, name string) bool
+
+	triggers []ipc.TriggerInfo
+}
+
+// Run runs the cases of each suite in order
+func (r *Runner) Run(ctx context.Context, suites []*Suite) *Report {
+	report := &Report{}
+	for _, suite := range suites {
+		result := SuiteResult{Suite: suite, Started: time.Now()}
+		for i := range suite.Cases {
+			c := &suite.Cases[i]
+			if r.Filter != nil && !r.Filter(suite.Name, c.Name) {
+				continue
+			}
+			result.Cases = append(result.Cases, r.RunCase(ctx, suite, c))
+		}
+		result.Duration = time.Since(result.Started)
+		report.Suites = append(report.Suites, result)
+	}
+	return report
+}
+
+// RunCase runs one case and checks its assertions
+func (r *Runner) RunCase(ctx context.Context, suite *Suite, c *Case) CaseResult {
+	result := CaseResult{Case: c}
+	if c.Skip != "" {
+		result.Status = StatusSkipped
+		return result
+	}
+
+	timeout := r.timeout(suite, c)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	var transcript *Transcript
+	var err error
+	if c.IsTrigger() {
+		transcript, err = r.invokeTrigger(ctx, c)
+	} else {
+		transcript, err = r.chat(ctx, c)
+	}
+	result.Duration = time.Since(started)
+	transcript.Duration = result.Duration
+	result.Transcript = transcript
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("did not complete within %s", timeout)
+	}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+		return result
+	}
+	result.Failures = c.Expect.Check(transcript)
+	if len(result.Failures) > 0 {
+		result.Status = StatusFailed
+	} else {
+		result.Status = StatusPassed
+	}
+	return result
+}
+
+// timeout returns how long a case may run. A time budget longer than the
+// timeout extends it, so the budget is what fails the case.
+func (r *Runner) timeout(suite *Suite, c *Case) time.Duration {
+	timeout := DefaultTimeout
+	switch {
+	case r.Timeout > 0:
+		timeout = r.Timeout
+	case c.Timeout > 0:
+		timeout = time.Duration(c.Timeout)
+	case suite.Timeout > 0:
+		timeout = time.Duration(suite.Timeout)
+	}
+	if within := time.Duration(c.Expect.Within); within > timeout {
+		timeout = within
+	}
+	return timeout
+}
+
+// chat sends the case's prompt to its agent in a new thread
+func (r *Runner) chat(ctx context.Context, c *Case) (*Transcript, error) {
+	transcript := &Transcript{}
+	results, errs := r.Client.StartChat(ctx, c.Agent, c.Prompt)
+
+	var deltas strings.Builder
+	final := false
+	for result := range results {
+		switch result.Type {
+		case "output_text_delta":
+			deltas.WriteString(result.TextDelta.OutputTextDelta.Delta)
+		case "output_text":
+			transcript.Output = result.FinalOutput.OutputText.Text
+			final = true
+		case "tool_call":
+			transcript.ToolCalls = append(transcript.ToolCalls, ToolCall{
+				Name:      result.ToolCall.ToolCall.Name,
+				Arguments: result.ToolCall.ToolCall.Arguments,
+			})
+		case "response.requested":
+			if request := result.InputRequest; request != nil {
+				return finish(transcript, &deltas, final), fmt.Errorf("agent asked for input, which tests cannot give: %s", request.Prompt)
+			}
+		default:
+			if result.Status != nil && result.Status.ThreadID != "" {
+				transcript.ThreadID = result.Status.ThreadID
+			}
+		}
+	}
+
+	select {
+	case err := <-errs:
+		return finish(transcript, &deltas, final), err
+	default:
+	}
+	return finish(transcript, &deltas, final), ctx.Err()
+}
+
+// invokeTrigger sends the case's event to its trigger
+func (r *Runner) invokeTrigger(ctx context.Context, c *Case) (*Transcript, error) {
+	transcript := &Transcript{}
+	trigger, err := r.findTrigger(ctx, c)
+	if err != nil {
+		return transcript, err
+	}
+
+	body := c.Event
+	if len(body) == 0 {
+		body = json.RawMessage("{}")
+	}
+	events, errs := r.Client.InvokeTriggerStreaming(ctx, ipc.TriggerRequest{
+		AgentName:   c.Agent,
+		TriggerName: c.Trigger,
+		TriggerType: trigger.TriggerType,
+		HTTPRequest: &ipc.SerializedHTTPRequest{
+			Method:      "POST",
+			Path:        fmt.Sprintf("/%s/%s", c.Agent, c.Trigger),
+			Headers:     make(map[string][]string),
+			Query:       make(map[string][]string),
+			Body:        body,
+			ContentType: "application/json",
+			RemoteAddr:  "test",
+			Host:        "localhost",
+			Proto:       "TEST/1.0",
+			Timestamp:   time.Now(),
+		},
+	})
+
+	var deltas strings.Builder
+	final := false
+	for event := range events {
+		if event.ThreadID != "" {
+			transcript.ThreadID = event.ThreadID
+		}
+		switch event.Type {
+		case "error":
+			return finish(transcript, &deltas, final), errors.New(event.Error)
+		case "output_text_delta":
+			var delta ipc.TextDeltaResult
+			if json.Unmarshal(event.Data, &delta) == nil {
+				deltas.WriteString(delta.OutputTextDelta.Delta)
+			}
+		case "output_text":
+			var output ipc.FinalOutputResult
+			if json.Unmarshal(event.Data, &output) == nil {
+				transcript.Output = output.OutputText.Text
+				final = true
+			}
+		case "tool_call":
+			var call ipc.ToolCallResult
+			if json.Unmarshal(event.Data, &call) == nil {
+				transcript.ToolCalls = append(transcript.ToolCalls, ToolCall{Name: call.ToolCall.Name, Arguments: call.ToolCall.Arguments})
+			}
+		case "response.requested":
+			if request := ipc.ParseInputRequest(event.Data); request != nil {
+				return finish(transcript, &deltas, final), fmt.Errorf("agent asked for input, which tests cannot give: %s", request.Prompt)
+			}
+		}
+		if event.Completed {
+			return finish(transcript, &deltas, final), nil
+		}
+	}
+
+	select {
+	case err := <-errs:
+		return finish(transcript, &deltas, final), err
+	default:
+	}
+	return finish(transcript, &deltas, final), ctx.Err()
+}
+
+// findTrigger looks up the type of the case's trigger in the app
+func (r *Runner) findTrigger(ctx context.Context, c *Case) (*ipc.TriggerInfo, error) {
+	if r.triggers == nil {
+		triggers, err := r.Client.GetTriggers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list triggers: %w", err)
+		}
+		r.triggers = triggers
+	}
+	for i := range r.triggers {
+		if r.triggers[i].AgentName == c.Agent && r.triggers[i].Name == c.Trigger {
+			return &r.triggers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("trigger %s not found in app", c.Target())
+}
+
+// finish sets the output from the streamed deltas if no final text came
+func finish(transcript *Transcript, deltas *strings.Builder, final bool) *Transcript {
+	if !final {
+		transcript.Output = deltas.String()
+	}
+	return transcript
+}
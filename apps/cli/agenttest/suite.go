@@ -0,0 +1,290 @@
+// Package agenttest runs declarative test suites against the agents and
+// triggers of a Shuttl app.
+//
+// A suite is a YAML or JSON file of cases. Each case sends a prompt to an
+// agent, or an event to one of its triggers, and checks what came back: the
+// output text, the tools the agent called and how long it took. Results are
+// reported as text, TAP or JUnit XML.
+package agenttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a file of test cases
+type Suite struct {
+	// Name defaults to the file name
+	Name string `json:"name"`
+	// Timeout is how long each case may run, unless the case sets its own
+	Timeout Duration `json:"timeout,omitempty"`
+	Cases   []Case   `json:"cases"`
+	// Path is the file the suite was loaded from
+	Path string `json:"-"`
+}
+
+// Case sends one prompt or trigger event and checks the result
+type Case struct {
+	Name string `json:"name"`
+	// Agent is the agent to chat with, or the agent owning Trigger
+	Agent string `json:"agent"`
+	// Trigger is the name of the trigger to invoke; empty to chat with Agent
+	Trigger string `json:"trigger,omitempty"`
+	// Prompt is sent to the agent when there is no trigger
+	Prompt string `json:"prompt,omitempty"`
+	// Event is the JSON body sent to the trigger
+	Event   json.RawMessage `json:"event,omitempty"`
+	Timeout Duration        `json:"timeout,omitempty"`
+	// Skip is the reason the case is not run, if it is skipped
+	Skip   string `json:"skip,omitempty"`
+	Expect Expect `json:"expect"`
+}
+
+// Expect holds the assertions of a case. All of them must hold.
+type Expect struct {
+	// Contains are substrings the output must contain
+	Contains StringList `json:"contains,omitempty"`
+	// NotContains are substrings the output must not contain
+	NotContains StringList `json:"not_contains,omitempty"`
+	// Matches are regular expressions the output must match
+	Matches StringList `json:"matches,omitempty"`
+	// Tools must each be called at least once, in any order
+	Tools []ToolExpectation `json:"tools,omitempty"`
+	// MaxToolCalls caps the number of tool calls
+	MaxToolCalls *int `json:"max_tool_calls,omitempty"`
+	// Within is the time budget for the case to complete
+	Within Duration `json:"within,omitempty"`
+}
+
+// ToolExpectation is a tool the agent must call. Args must be a subset of
+// the call's arguments. In a suite it can be written as just the tool name.
+type ToolExpectation struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+func (t *ToolExpectation) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		t.Name = name
+		return nil
+	}
+	type plain ToolExpectation
+	return strictUnmarshal(data, (*plain)(t))
+}
+
+// StringList is a list of strings that can be written as a single string
+type StringList []string
+
+func (s *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StringList{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
+
+// Duration is a time.Duration written as a string such as "30s", or as a
+// number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\" or a number of seconds")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// IsTrigger reports whether the case invokes a trigger rather than chatting
+func (c *Case) IsTrigger() bool {
+	return c.Trigger != ""
+}
+
+// Target names what the case runs: the agent, or agent/trigger
+func (c *Case) Target() string {
+	if c.IsTrigger() {
+		return c.Agent + "/" + c.Trigger
+	}
+	return c.Agent
+}
+
+// Load reads a suite from a .yaml, .yml or .json file
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test suite: %w", err)
+	}
+	yamlFile := false
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		yamlFile = true
+	case ".json":
+	default:
+		return nil, fmt.Errorf("cannot tell the format of %s; use .yaml, .yml or .json", path)
+	}
+	suite, err := Parse(data, yamlFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	suite.Path = path
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		suite.Name = strings.TrimSuffix(suite.Name, ".test")
+	}
+	return suite, nil
+}
+
+// Parse reads a suite from YAML or JSON and checks it. Unknown fields are
+// errors, so typos in assertions do not pass silently.
+func Parse(data []byte, yamlFile bool) (*Suite, error) {
+	if yamlFile {
+		// YAML is converted to JSON so both formats are read the same way
+		var value any
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		converted, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("suite cannot be converted to JSON: %w", err)
+		}
+		data = converted
+	}
+	var suite Suite
+	if err := strictUnmarshal(data, &suite); err != nil {
+		return nil, err
+	}
+	if err := suite.check(); err != nil {
+		return nil, err
+	}
+	return &suite, nil
+}
+
+func strictUnmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// check reports the first case that cannot be run
+func (s *Suite) check() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("suite has no cases")
+	}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if err := c.check(); err != nil {
+			return fmt.Errorf("cases[%d] (%s): %w", i, c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Case) check() error {
+	if c.Agent == "" {
+		return fmt.Errorf("agent is required")
+	}
+	if c.IsTrigger() {
+		if c.Prompt != "" {
+			return fmt.Errorf("a trigger case sends an event, not a prompt")
+		}
+		if len(c.Event) > 0 && !json.Valid(c.Event) {
+			return fmt.Errorf("event is not valid JSON")
+		}
+	} else if c.Prompt == "" {
+		return fmt.Errorf("prompt is required to chat with an agent; set trigger to invoke a trigger")
+	}
+	for _, pattern := range c.Expect.Matches {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+	}
+	for _, tool := range c.Expect.Tools {
+		if tool.Name == "" {
+			return fmt.Errorf("expected tools need a name")
+		}
+	}
+	if c.Expect.MaxToolCalls != nil && *c.Expect.MaxToolCalls < 0 {
+		return fmt.Errorf("max_tool_calls cannot be negative")
+	}
+	return nil
+}
+
+// Find returns the suite files in paths. Directories are searched for files
+// named *.test.yaml, *.test.yml or *.test.json; files are taken as given.
+func Find(paths []string) ([]string, error) {
+	var found []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			found = append(found, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if file != path && skipDir(entry.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if IsSuiteFile(entry.Name()) {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// IsSuiteFile reports whether a file name follows the suite naming pattern
+func IsSuiteFile(name string) bool {
+	for _, suffix := range []string{".test.yaml", ".test.yml", ".test.json"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "dist", "__pycache__":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
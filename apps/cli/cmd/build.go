@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,22 +11,10 @@ import (
 
 	"github.com/shuttl-ai/cli/config"
 	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/manifest"
 	"github.com/spf13/cobra"
 )
 
-// Manifest represents the complete manifest structure
-type Manifest struct {
-	Version   string               `json:"version"`
-	BuildTime string               `json:"buildTime"`
-	App       string               `json:"app"`
-	Agents    []ipc.AgentInfo      `json:"agents"`
-	Toolkits  []ipc.ToolkitInfo    `json:"toolkits"`
-	Tools     []ipc.SingleToolInfo `json:"tools"`
-	Triggers  []ipc.TriggerInfo    `json:"triggers"`
-	Models    []ipc.ModelInfo      `json:"models"`
-	Prompts   []ipc.PromptInfo     `json:"prompts"`
-}
-
 var buildCmd = &cobra.Command{
 	Use:   "build [app]",
 	Short: "Build a manifest file from the app",
@@ -103,9 +92,6 @@ func runBuild(cmd *cobra.Command, args []string) {
 	}
 	defer client.Close()
 
-	// Give the app time to initialize
-	time.Sleep(500 * time.Millisecond)
-
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(client.Context(), 30*time.Second)
 	defer cancel()
@@ -160,8 +146,8 @@ func runBuild(cmd *cobra.Command, args []string) {
 	fmt.Printf("   Found %d prompt(s)\n", len(prompts))
 
 	// Build manifest
-	manifest := Manifest{
-		Version:   "1.0",
+	appManifest := Manifest{
+		Version:   manifest.CurrentVersion,
 		BuildTime: time.Now().UTC().Format(time.RFC3339),
 		App:       appPath,
 		Agents:    agents,
@@ -173,7 +159,7 @@ func runBuild(cmd *cobra.Command, args []string) {
 	}
 
 	// Marshal to JSON with indentation
-	jsonData, err := json.MarshalIndent(manifest, "", "  ")
+	jsonData, err := json.MarshalIndent(appManifest, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Error marshaling manifest: %v\n", err)
 		os.Exit(1)
@@ -191,4 +177,18 @@ func runBuild(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Printf("✅ Manifest written to: %s\n", absPath)
+
+	// Catch apps that produce manifests serve would reject
+	fmt.Println("🔍 Validating manifest...")
+	if err := manifest.Validate(jsonData); err != nil {
+		var validationErr *manifest.ValidationError
+		if !errors.As(err, &validationErr) {
+			fmt.Fprintf(os.Stderr, "❌ Error validating manifest: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "❌ Manifest is invalid:\n")
+		printManifestIssues(validationErr)
+		os.Exit(1)
+	}
+	fmt.Println("✅ Manifest is valid")
 }
@@ -3,10 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/shuttl-ai/cli/config"
 	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/log"
 	"github.com/shuttl-ai/cli/tui"
+	"github.com/shuttl-ai/cli/watch"
 	"github.com/spf13/cobra"
 )
 
@@ -28,58 +31,145 @@ Example shuttl.json:
     "app": "./my-agent"
   }
 
+The app is reloaded whenever files in the project directory change, keeping
+open chat sessions. Choose which files trigger a reload with the "watch"
+section of shuttl.json, or disable reloading with --no-watch:
+  {
+    "app": "node ./dist/main.js",
+    "watch": {
+      "include": ["dist/**/*.js"],
+      "exclude": ["dist/**/*.map"]
+    }
+  }
+
+With --restart the app is restarted with exponential backoff when it crashes
+instead of ending the session.
+
+With --record every request sent to the app and every message it sends back
+is written, with timestamps, to a recording. --replay answers requests from a
+recording instead of running the app, so a session can be reproduced offline
+without model credentials.
+
+With --attach the CLI connects to an app that is already running and
+listening on a Unix domain socket or TCP port, such as one started in a
+debugger or a container, instead of starting it. Files are not watched and
+--restart reconnects when the app goes away.
+
 Examples:
   shuttl dev
-  shuttl dev ./my-app`,
+  shuttl dev ./my-app
+  shuttl dev --restart
+  shuttl dev --record session.jsonl
+  shuttl dev --replay session.jsonl
+  shuttl dev --attach unix:///tmp/app.sock`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runDev,
 }
 
 func init() {
 	devCmd.Flags().String("config", "", "Path to shuttl.json (defaults to searching current and parent directories)")
+	devCmd.Flags().Bool("restart", false, "Restart the app with exponential backoff when it crashes")
+	devCmd.Flags().Int("max-restarts", 0, "Maximum number of times to restart the app with --restart (0 means unlimited)")
+	devCmd.Flags().Bool("no-watch", false, "Do not reload the app when project files change")
+	devCmd.Flags().String("record", "", "Record the requests and messages exchanged with the app to this file")
+	devCmd.Flags().String("replay", "", "Answer requests from a recording instead of running the app")
+	devCmd.Flags().String("attach", "", "Connect to an app that is already running at unix:///path/to/app.sock or tcp://host:port")
+	devCmd.MarkFlagsMutuallyExclusive("attach", "replay")
 	rootCmd.AddCommand(devCmd)
 }
 
 func runDev(cmd *cobra.Command, args []string) {
 	configPath, _ := cmd.Flags().GetString("config")
+	restart, _ := cmd.Flags().GetBool("restart")
+	maxRestarts, _ := cmd.Flags().GetInt("max-restarts")
+	noWatch, _ := cmd.Flags().GetBool("no-watch")
+	recordPath, _ := cmd.Flags().GetString("record")
+	replayPath, _ := cmd.Flags().GetString("replay")
+	attach, _ := cmd.Flags().GetString("attach")
+	logFile, _ := cmd.Flags().GetString("log-file")
+
+	// Try to load configuration; it is optional unless a path is given
+	cfg, projectDir, err := loadDevConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	var appPath string
 
 	// If app is provided as argument, use it directly
 	if len(args) > 0 {
 		appPath = args[0]
-	} else {
-		// Try to load configuration
-		var cfg *config.Config
-		var err error
+	} else if cfg != nil && cfg.App != "" {
+		appPath = cfg.App
+	}
 
-		if configPath != "" {
-			cfg, err = config.LoadConfigFromPath(configPath)
+	// Create IPC client if we have an app command
+	var client *ipc.Client
+	var reloads chan tui.AppReloadedMsg
+	if appPath != "" || replayPath != "" || attach != "" {
+		switch {
+		case attach != "":
+			if len(args) > 0 {
+				fmt.Fprintf(os.Stderr, "❌ Error: --attach connects to a running app; do not pass an app command\n")
+				os.Exit(1)
+			}
+			transport, err := ipc.ParseTransport(attach)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 				os.Exit(1)
 			}
-		} else {
-			cfg, _ = config.LoadConfig()
-			// Config file is optional
+			client = ipc.NewClientWithTransport(transport)
+			// The app runs elsewhere, so rebuilding it is not ours to do
+			noWatch = true
+		case replayPath != "":
+			command, err := replayAppCommand(replayPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			client = ipc.NewClient(command)
+			// The recording does not change, so there is nothing to reload
+			noWatch = true
+		default:
+			// Parse the app command string into an array
+			command := ipc.ParseCommand(appPath)
+			if len(command) == 0 {
+				fmt.Fprintf(os.Stderr, "❌ Error: empty app command\n")
+				os.Exit(1)
+			}
+			client = ipc.NewClient(command)
 		}
 
-		if cfg != nil && cfg.App != "" {
-			appPath = cfg.App
+		if recordPath != "" {
+			recorder, err := startRecording(client, recordPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer recorder.Close()
+		}
+		if restart {
+			policy := ipc.DefaultRestartPolicy()
+			policy.MaxRestarts = maxRestarts
+			client.SetRestartPolicy(policy)
 		}
-	}
 
-	// Create IPC client if we have an app command
-	var client *ipc.Client
-	if appPath != "" {
-		// Parse the app command string into an array
-		command := ipc.ParseCommand(appPath)
-		if len(command) == 0 {
-			fmt.Fprintf(os.Stderr, "❌ Error: empty app command\n")
-			os.Exit(1)
+		if !noWatch {
+			// Files the CLI writes must not trigger reloads of their own
+			watcher, err := newDevWatcher(projectDir, cfg, recordPath, logFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer watcher.Close()
+
+			// Keep the session alive after a crash so the fix can be reloaded
+			client.SetHoldOnCrash(true)
+			reloads = make(chan tui.AppReloadedMsg)
+			go reloadOnChanges(watcher, client, reloads)
 		}
 
-		client = ipc.NewClient(command)
 		if err := client.Start(); err != nil {
 			fmt.Fprintf(os.Stderr, "❌ Error starting app: %v\n", err)
 			os.Exit(1)
@@ -87,8 +177,65 @@ func runDev(cmd *cobra.Command, args []string) {
 	}
 
 	// Launch the TUI (client will be stopped when TUI exits)
-	if err := tui.Run(client); err != nil {
+	if err := tui.RunWithOptions(client, tui.Options{Reloads: reloads, ProjectDir: projectDir}); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// loadDevConfig loads shuttl.json and returns it with the project directory
+// to watch. Without a config file the current directory is used.
+func loadDevConfig(configPath string) (*config.Config, string, error) {
+	if configPath == "" {
+		found, err := config.FindConfigFile()
+		if err != nil {
+			// Config file is optional
+			cwd, err := os.Getwd()
+			return nil, cwd, err
+		}
+		configPath = found
+	}
+
+	cfg, err := config.LoadConfigFromPath(configPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, config.GetConfigDir(configPath), nil
+}
+
+// newDevWatcher watches the project directory using the patterns from
+// shuttl.json, ignoring the given output files
+func newDevWatcher(projectDir string, cfg *config.Config, outputs ...string) (*watch.Watcher, error) {
+	var include, exclude []string
+	if cfg != nil && cfg.Watch != nil {
+		include, exclude = cfg.Watch.Include, append(exclude, cfg.Watch.Exclude...)
+	}
+	for _, output := range outputs {
+		if output == "" {
+			continue
+		}
+		if pattern, ok := watch.FilePattern(projectDir, output); ok {
+			exclude = append(exclude, pattern)
+		}
+	}
+
+	matcher, err := watch.NewMatcher(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return watch.New(projectDir, matcher, watch.DefaultDebounce)
+}
+
+// reloadOnChanges reloads the app for every batch of changed files and
+// reports the outcome to the TUI
+func reloadOnChanges(watcher *watch.Watcher, client *ipc.Client, reloads chan<- tui.AppReloadedMsg) {
+	for files := range watcher.Changes() {
+		log.Info("🔄 Reloading app after changes to %s", strings.Join(files, ", "))
+		err := client.Reload()
+		select {
+		case reloads <- tui.AppReloadedMsg{Files: files, Err: err}:
+		case <-client.Context().Done():
+			return
+		}
+	}
+}
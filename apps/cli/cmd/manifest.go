@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/shuttl-ai/cli/log"
+	"github.com/shuttl-ai/cli/manifest"
+	"github.com/spf13/cobra"
+)
+
+// Manifest represents the complete manifest structure
+type Manifest = manifest.Manifest
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Work with shuttl-manifest.json files",
+	Long:  `Validate and compare manifest files written by 'shuttl build', and print the manifest JSON Schema.`,
+}
+
+var manifestValidateCmd = &cobra.Command{
+	Use:   "validate [manifest]",
+	Short: "Check a manifest file for problems",
+	Long: `Validate a manifest file against the JSON Schema for its version and check
+that it is consistent: agent, toolkit and trigger names are unique, triggers
+reference agents in the manifest and agents reference toolkits in the manifest.
+
+Every problem is printed with a JSON pointer to the offending value. The
+command exits with status 1 if the manifest is invalid.
+
+The same checks run when 'shuttl serve' loads a manifest and at the end of
+'shuttl build'.
+
+Examples:
+  shuttl manifest validate
+  shuttl manifest validate ./dist/shuttl-manifest.json
+  shuttl manifest validate --json`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runManifestValidate,
+}
+
+var manifestDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Show the semantic differences between two manifests",
+	Long: `Compare two manifest files and list what changed: agents, triggers,
+toolkits and tools added or removed, system prompt and model changes, and
+changes to tool argument schemas. Array order and the build time are ignored.
+
+Changes that can break existing callers, such as removed agents, triggers or
+tools, changed trigger types and removed or retyped tool arguments, are marked
+as breaking.
+
+Exit status:
+  0  no failing changes (see --fail-on)
+  1  a manifest could not be read
+  2  failing changes were found
+
+Examples:
+  git show HEAD:shuttl-manifest.json > /tmp/old.json
+  shuttl manifest diff /tmp/old.json shuttl-manifest.json
+  shuttl manifest diff old.json new.json --json
+  shuttl manifest diff old.json new.json --fail-on any`,
+	Args: cobra.ExactArgs(2),
+	Run:  runManifestDiff,
+}
+
+var manifestSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the manifest JSON Schema",
+	Long: `Print the JSON Schema for a manifest version, for use with editors and
+other tooling.
+
+Examples:
+  shuttl manifest schema > shuttl-manifest.schema.json
+  shuttl manifest schema --version 1.0`,
+	Args: cobra.NoArgs,
+	Run:  runManifestSchema,
+}
+
+func init() {
+	manifestValidateCmd.Flags().Bool("json", false, "Print the result as JSON")
+	manifestDiffCmd.Flags().Bool("json", false, "Print the changes as JSON")
+	manifestDiffCmd.Flags().String("fail-on", "breaking", "Exit with status 2 on \"breaking\" changes, \"any\" change, or \"none\"")
+	manifestSchemaCmd.Flags().String("version", manifest.CurrentVersion, "Manifest version to print the schema for")
+	manifestCmd.AddCommand(manifestValidateCmd)
+	manifestCmd.AddCommand(manifestDiffCmd)
+	manifestCmd.AddCommand(manifestSchemaCmd)
+	rootCmd.AddCommand(manifestCmd)
+}
+
+func runManifestValidate(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	path := manifest.DefaultPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error reading manifest: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = manifest.Validate(data)
+	var validationErr *manifest.ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		result := struct {
+			Path   string           `json:"path"`
+			Valid  bool             `json:"valid"`
+			Issues []manifest.Issue `json:"issues"`
+		}{Path: path, Valid: err == nil, Issues: []manifest.Issue{}}
+		if validationErr != nil {
+			result.Issues = validationErr.Issues
+		}
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	} else if validationErr != nil {
+		fmt.Fprintf(os.Stderr, "❌ %s is invalid:\n", path)
+		printManifestIssues(validationErr)
+	} else {
+		fmt.Printf("✅ %s is valid\n", path)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func runManifestDiff(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	failOn, _ := cmd.Flags().GetString("fail-on")
+	if failOn != "breaking" && failOn != "any" && failOn != "none" {
+		fmt.Fprintf(os.Stderr, "❌ Error: --fail-on must be breaking, any or none, got %q\n", failOn)
+		os.Exit(1)
+	}
+
+	oldManifest, err := readManifestFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	newManifest, err := readManifestFile(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	diff := manifest.Compare(oldManifest, newManifest)
+	breaking := diff.Breaking()
+
+	if asJSON {
+		result := struct {
+			Old      string            `json:"old"`
+			New      string            `json:"new"`
+			Breaking bool              `json:"breaking"`
+			Changes  []manifest.Change `json:"changes"`
+		}{Old: args[0], New: args[1], Breaking: len(breaking) > 0, Changes: diff.Changes}
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	} else if !diff.HasChanges() {
+		fmt.Println("✅ No changes")
+	} else {
+		fmt.Printf("📋 Changes from %s to %s:\n\n", args[0], args[1])
+		for _, change := range diff.Changes {
+			marker := "~"
+			switch change.Kind {
+			case manifest.ChangeAdded:
+				marker = "+"
+			case manifest.ChangeRemoved:
+				marker = "-"
+			}
+			line := fmt.Sprintf("  %s %s", marker, change.Summary())
+			if change.Breaking {
+				line += "  ⚠️  breaking"
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("\n%d change(s), %d breaking\n", len(diff.Changes), len(breaking))
+	}
+
+	if (failOn == "breaking" && len(breaking) > 0) || (failOn == "any" && diff.HasChanges()) {
+		os.Exit(2)
+	}
+}
+
+func runManifestSchema(cmd *cobra.Command, args []string) {
+	version, _ := cmd.Flags().GetString("version")
+
+	schema, err := manifest.Schema(version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(string(schema))
+}
+
+// printManifestIssues prints one line per problem found in a manifest
+func printManifestIssues(err *manifest.ValidationError) {
+	for _, issue := range err.Issues {
+		fmt.Fprintf(os.Stderr, "   • %s\n", issue)
+	}
+}
+
+// readManifestFile reads a manifest without validating it, so that diffs
+// also work for manifests written by older CLIs
+func readManifestFile(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// loadManifest reads and validates the manifest at path, logging every
+// problem found
+func loadManifest(path string) (*Manifest, error) {
+	loaded, err := manifest.Load(path)
+	var validationErr *manifest.ValidationError
+	if errors.As(err, &validationErr) {
+		log.Error("Manifest %s is invalid:", path)
+		for _, issue := range validationErr.Issues {
+			log.Error("   • %s", issue)
+		}
+		log.Error("Fix the app and rebuild the manifest with 'shuttl build', or check it with 'shuttl manifest validate'")
+		return nil, err
+	}
+	if err != nil {
+		log.Error("Error reading manifest file: %v", err)
+		return nil, err
+	}
+	return loaded, nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/log"
+	"github.com/spf13/cobra"
+)
+
+// replayCmd stands in for an app when --replay is given: the client spawns
+// it like any other app and it answers over stdin and stdout
+var replayCmd = &cobra.Command{
+	Use:    "replay <recording>",
+	Short:  "Answer IPC requests from a recording instead of running the app",
+	Args:   cobra.ExactArgs(1),
+	Hidden: true,
+	Run:    runReplay,
+}
+
+func init() {
+	replayCmd.Flags().Float64("speed", 1, "Scale the recorded delays between requests and replies (0 replies at once)")
+	rootCmd.AddCommand(replayCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) {
+	speed, _ := cmd.Flags().GetFloat64("speed")
+
+	// Stdout carries the replies
+	log.Default.SetConsole(os.Stderr)
+
+	replay, err := ipc.LoadReplay(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	replay.SetSpeed(speed)
+	if err := replay.Serve(cmd.Context(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// replayAppCommand returns the app command that replays a recording. The
+// recording is checked here so a bad file is reported before the app starts.
+func replayAppCommand(path string) ([]string, error) {
+	replay, err := ipc.LoadReplay(path)
+	if err != nil {
+		return nil, err
+	}
+	if replay.Requests() == 0 {
+		return nil, fmt.Errorf("recording %s has no requests", path)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the shuttl executable: %w", err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return []string{executable, "replay", abs}, nil
+}
+
+// startRecording records the client's traffic to path; the returned recorder
+// must be closed after the client stops
+func startRecording(client *ipc.Client, path string) (*ipc.Recorder, error) {
+	recorder, err := ipc.CreateRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRecorder(recorder)
+	return recorder, nil
+}
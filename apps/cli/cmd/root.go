@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
 	"github.com/shuttl-ai/cli/log"
 	"github.com/spf13/cobra"
 )
@@ -24,9 +29,45 @@ Use this tool to:
 		} else {
 			log.Default.SetLevel(log.LogLevelInfo)
 		}
+		if err := configureLogOutput(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if closer, ok := log.Default.SetFile(nil).(io.Closer); ok {
+			closer.Close()
+		}
 	},
 }
 
+// configureLogOutput applies the --log-format and --log-file flags
+func configureLogOutput(cmd *cobra.Command) error {
+	formatName, _ := cmd.Flags().GetString("log-format")
+	format, err := log.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	log.Default.SetFormat(format)
+
+	path, _ := cmd.Flags().GetString("log-file")
+	if path == "" {
+		return nil
+	}
+	maxSize, _ := cmd.Flags().GetInt64("log-max-size")
+	maxAge, _ := cmd.Flags().GetInt("log-max-age")
+	file, err := log.OpenRotatingFile(path, log.FileOptions{
+		MaxSize: maxSize * 1024 * 1024,
+		MaxAge:  time.Duration(maxAge) * 24 * time.Hour,
+	})
+	if err != nil {
+		return err
+	}
+	log.Default.SetFile(file)
+	log.Default.SetMode(log.LogToFile)
+	return nil
+}
+
 // Execute runs the root command
 func Execute() error {
 	return rootCmd.Execute()
@@ -35,4 +76,8 @@ func Execute() error {
 func init() {
 	// Global flags can be added here
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output (debug level logging)")
+	rootCmd.PersistentFlags().String("log-format", string(log.FormatText), "Log format: text or json")
+	rootCmd.PersistentFlags().String("log-file", "", "Write logs to this file instead of stdout, including while the TUI is open")
+	rootCmd.PersistentFlags().Int64("log-max-size", 100, "Rotate the log file once it reaches this many megabytes (0 to never rotate)")
+	rootCmd.PersistentFlags().Int("log-max-age", 7, "Delete rotated log files after this many days (0 to keep them)")
 }
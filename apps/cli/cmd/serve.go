@@ -9,32 +9,70 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/httpauth"
 	"github.com/shuttl-ai/cli/ipc"
 	"github.com/shuttl-ai/cli/log"
+	manifestpkg "github.com/shuttl-ai/cli/manifest"
+	"github.com/shuttl-ai/cli/metrics"
+	"github.com/shuttl-ai/cli/scheduler"
+	"github.com/shuttl-ai/cli/tracing"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/http2"
 )
 
+var serveTracer = tracing.Tracer("serve")
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Serve triggers for agents via HTTP/2",
-	Long: `Serve command creates an HTTP/2 server that exposes POST endpoints
+	Long: `Serve command creates an HTTP/2 server that exposes endpoints
 for each agent's triggers defined in the shuttl-manifest.json file.
 
-Endpoints are created in the format: /<agent_name>/<trigger_name>
+Endpoints accept POST at /<agent_name>/<trigger_name> unless an API trigger
+sets its own methods and route template, such as GET /orders/{id}. Path
+parameters and cookies are passed to the trigger with the request.
+
+Rate triggers (Rate.minutes(...), Rate.cron(...)) are also fired on their
+schedule by an in-process scheduler unless --no-schedule is set.
+
+Endpoints can require authentication with API keys, HMAC-signed requests or
+JWT bearer tokens, configured per agent or trigger in the "auth" section of
+shuttl.json. The verified caller is passed to the trigger as the request's
+principal.
+
+With --restart the app is restarted with exponential backoff when it crashes.
+Requests in flight during a crash fail with 503, and the server gives up if
+the app keeps crashing. /health reports the app state and restart count.
+
+/metrics serves Prometheus metrics: requests, latency and results per agent
+and trigger, streaming session durations, tool calls, app restarts and the
+state of the IPC queue.
+
+With --trace, every trigger request is traced with OpenTelemetry: a span for
+the HTTP request, a child span for the IPC request to the app and a span per
+tool call. Spans go to an OTLP/HTTP collector or to a file, and the trace
+context is passed to the app in the request's traceparent header.
 
 The server requires a manifest file generated by 'shuttl build'.
 If no manifest file is found, an error will be thrown.
@@ -43,6 +81,7 @@ Examples:
   shuttl serve
   shuttl serve --port 8443
   shuttl serve --manifest ./custom-manifest.json
+  shuttl serve --restart --max-restarts 10
   shuttl serve --agent my-agent --trigger my-trigger --event '{"name": "my-event"}' --thread_id my-thread-id`,
 	Run: runServe,
 }
@@ -58,16 +97,26 @@ func init() {
 	serveCmd.Flags().StringP("event_file", "f", "", "The optional event file to pass to the agent and the trigger to get a response back")
 	serveCmd.Flags().StringP("thread_id", "i", "", "the thread id to use for the conversation")
 	serveCmd.Flags().Bool("insecure", false, "Use HTTP/2 cleartext (h2c) without TLS (not recommended for production)")
+	serveCmd.Flags().Bool("no-schedule", false, "Do not run rate triggers on their schedules")
+	serveCmd.Flags().Bool("restart", false, "Restart the app with exponential backoff when it crashes")
+	serveCmd.Flags().Int("max-restarts", 0, "Maximum number of times to restart the app with --restart (0 means unlimited)")
+	serveCmd.Flags().String("trace", "", "Export OpenTelemetry traces: \"otlp\" to a collector or \"file\"")
+	serveCmd.Flags().String("trace-endpoint", "", "OTLP/HTTP collector URL for --trace otlp (default: OTEL_EXPORTER_OTLP_ENDPOINT or http://localhost:4318)")
+	serveCmd.Flags().String("trace-file", "shuttl-traces.jsonl", "File to write spans to for --trace file")
+	serveCmd.Flags().String("config", "", "Path to shuttl.json with the auth configuration (defaults to searching current and parent directories)")
 	rootCmd.AddCommand(serveCmd)
 }
 
 // TriggerEndpoint holds information about a registered trigger endpoint
 type TriggerEndpoint struct {
-	Path        string `json:"path"`
-	AgentName   string `json:"agentName"`
-	TriggerName string `json:"triggerName"`
-	TriggerType string `json:"triggerType"`
-	Description string `json:"description"`
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	PathParams  []string `json:"pathParams,omitempty"`
+	AgentName   string   `json:"agentName"`
+	TriggerName string   `json:"triggerName"`
+	TriggerType string   `json:"triggerType"`
+	Description string   `json:"description"`
+	Auth        string   `json:"auth"`
 }
 
 // triggerServer holds the server state including the IPC client
@@ -75,6 +124,20 @@ type triggerServer struct {
 	client    *ipc.Client
 	manifest  Manifest
 	endpoints []TriggerEndpoint
+	auth      *httpauth.Policy
+	metrics   *metrics.Metrics
+
+	// Agents waiting for a reply from the caller of a streaming request,
+	// by thread ID
+	inputMu  sync.Mutex
+	awaiting map[string]awaitingInput
+}
+
+// awaitingInput is an agent that asked the caller of a streaming request for
+// input. Replies are checked against the auth of the trigger it runs under.
+type awaitingInput struct {
+	endpoint TriggerEndpoint
+	request  *ipc.InputRequest
 }
 
 func runServe(cmd *cobra.Command, args []string) {
@@ -83,6 +146,13 @@ func runServe(cmd *cobra.Command, args []string) {
 	certPath, _ := cmd.Flags().GetString("cert")
 	keyPath, _ := cmd.Flags().GetString("key")
 	insecure, _ := cmd.Flags().GetBool("insecure")
+	noSchedule, _ := cmd.Flags().GetBool("no-schedule")
+	restart, _ := cmd.Flags().GetBool("restart")
+	maxRestarts, _ := cmd.Flags().GetInt("max-restarts")
+	configPath, _ := cmd.Flags().GetString("config")
+	traceExporter, _ := cmd.Flags().GetString("trace")
+	traceEndpoint, _ := cmd.Flags().GetString("trace-endpoint")
+	traceFile, _ := cmd.Flags().GetString("trace-file")
 
 	agent, _ := cmd.Flags().GetString("agent")
 	trigger, _ := cmd.Flags().GetString("trigger")
@@ -116,23 +186,29 @@ func runServe(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// Read and parse manifest file
-	manifestData, err := os.ReadFile(manifestPath)
+	// Read, parse and validate the manifest file
+	loaded, err := loadManifest(manifestPath)
 	if err != nil {
-		log.Error("Error reading manifest file: %v", err)
 		os.Exit(1)
 	}
+	manifest := *loaded
 
-	var manifest Manifest
-	if err := json.Unmarshal(manifestData, &manifest); err != nil {
-		log.Error("Error parsing manifest file: %v", err)
+	// Load the auth providers before starting the app so that bad keys or
+	// JWKS files fail fast
+	authPolicy, err := loadAuthPolicy(configPath)
+	if err != nil {
+		log.Error("Error loading auth configuration: %v", err)
 		os.Exit(1)
 	}
 
-	// Validate that the manifest has an app command
-	if manifest.App == "" {
-		log.Error("manifest file does not contain an 'app' command")
-		log.Error("Rebuild the manifest with 'shuttl build'")
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter: traceExporter,
+		Endpoint: traceEndpoint,
+		File:     traceFile,
+		Version:  Version,
+	})
+	if err != nil {
+		log.Error("Error setting up tracing: %v", err)
 		os.Exit(1)
 	}
 
@@ -145,18 +221,31 @@ func runServe(cmd *cobra.Command, args []string) {
 	}
 
 	client := ipc.NewClient(command)
+	if restart {
+		policy := ipc.DefaultRestartPolicy()
+		policy.MaxRestarts = maxRestarts
+		client.SetRestartPolicy(policy)
+	}
 	if err := client.Start(); err != nil {
 		log.Error("Error starting app: %v", err)
 		os.Exit(1)
 	}
-
-	// Give the app time to initialize
-	time.Sleep(500 * time.Millisecond)
+	go forwardAppLogs(client)
+	// Replies reach the handlers through their requests; drain what is left
+	// so the IPC queue metrics reflect real traffic
+	go client.Drain(func(output ipc.OutputLine) {
+		log.Debug("App notification: %s", output.Content)
+	}, func(err error) {
+		log.Warn("App error: %v", err)
+	})
 
 	// Create the trigger server
 	ts := &triggerServer{
 		client:   client,
 		manifest: manifest,
+		auth:     authPolicy,
+		metrics:  metrics.New(client),
+		awaiting: make(map[string]awaitingInput),
 	}
 
 	// Filter triggers based on agent and trigger flags
@@ -298,12 +387,21 @@ func runServe(cmd *cobra.Command, args []string) {
 	// Build agent-to-triggers mapping
 	agentTriggers := make(map[string][]TriggerEndpoint)
 	for _, trigger := range manifest.Triggers {
+		// Routes were checked when the manifest was validated
+		route, err := manifestpkg.RouteOf(trigger)
+		if err != nil {
+			log.Error("Invalid route for trigger %s/%s: %v", trigger.AgentName, trigger.Name, err)
+			os.Exit(1)
+		}
 		endpoint := TriggerEndpoint{
-			Path:        fmt.Sprintf("/%s/%s", trigger.AgentName, trigger.Name),
+			Path:        route.Route,
+			Methods:     route.Methods,
+			PathParams:  route.Params,
 			AgentName:   trigger.AgentName,
 			TriggerName: trigger.Name,
 			TriggerType: trigger.TriggerType,
 			Description: trigger.Description,
+			Auth:        authPolicy.Describe(trigger.AgentName, trigger.Name),
 		}
 		agentTriggers[trigger.AgentName] = append(agentTriggers[trigger.AgentName], endpoint)
 		ts.endpoints = append(ts.endpoints, endpoint)
@@ -312,30 +410,18 @@ func runServe(cmd *cobra.Command, args []string) {
 	// Create HTTP mux and register handlers
 	mux := http.NewServeMux()
 
-	for _, triggers := range agentTriggers {
-		for _, endpoint := range triggers {
-			// Create a closure to capture the endpoint
-			ep := endpoint
-			mux.HandleFunc(ep.Path, ts.createTriggerHandler(ep))
-		}
-	}
-
 	// Add a health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "ok",
-			"time":   time.Now().UTC().Format(time.RFC3339),
-		})
-	})
+	mux.HandleFunc(manifestpkg.HealthRoute, ts.handleHealth)
 
-	// Add a list endpoints endpoint
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
-		}
+	// Add the Prometheus metrics endpoint
+	mux.Handle(manifestpkg.MetricsRoute, ts.metrics.Handler())
+
+	// Add the endpoint for replies to agents waiting for input
+	mux.HandleFunc(RespondRoute, ts.handleRespond)
+
+	// Add a list endpoints endpoint. It only matches "/" itself so that
+	// requests for trigger routes with the wrong method get 405, not 404.
+	mux.HandleFunc(manifestpkg.EndpointsRoute, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"endpoints": ts.endpoints,
@@ -346,6 +432,23 @@ func runServe(cmd *cobra.Command, args []string) {
 		})
 	})
 
+	for _, triggers := range agentTriggers {
+		for _, endpoint := range triggers {
+			// Create a closure to capture the endpoint
+			ep := endpoint
+			// Count every request, including those rejected by auth
+			var handler http.Handler = ts.createTriggerHandler(ep)
+			handler = ts.auth.Wrap(ep.AgentName, ep.TriggerName, handler)
+			handler = ts.metrics.Wrap(ep.AgentName, ep.TriggerName, handler)
+			for _, method := range ep.Methods {
+				if err := handleRoute(mux, method+" "+ep.Path, handler); err != nil {
+					log.Error("Cannot serve trigger %s/%s: %v", ep.AgentName, ep.TriggerName, err)
+					os.Exit(1)
+				}
+			}
+		}
+	}
+
 	// Print startup information
 	log.Info("")
 	log.Info("🚀 Shuttl Trigger Server")
@@ -365,14 +468,24 @@ func runServe(cmd *cobra.Command, args []string) {
 			if desc == "" {
 				desc = fmt.Sprintf("(%s trigger)", ep.TriggerType)
 			}
-			log.Info("   POST %s - %s", ep.Path, desc)
+			log.Info("   %s %s - %s [auth: %s]", strings.Join(ep.Methods, ","), ep.Path, desc, ep.Auth)
 		}
 	}
 	log.Info("")
 	log.Info("   GET  /health - Health check endpoint")
+	log.Info("   GET  /metrics - Prometheus metrics")
+	log.Info("   POST /threads/{threadId}/respond - Reply to an agent waiting for input")
 	log.Info("   GET  / - List all endpoints")
 	log.Info("")
 
+	// Start the scheduler for rate triggers
+	schedulerCtx, stopScheduler := context.WithCancel(client.Context())
+	defer stopScheduler()
+	var sched *scheduler.Scheduler
+	if !noSchedule {
+		sched = ts.startScheduler(schedulerCtx)
+	}
+
 	// Create server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -384,6 +497,7 @@ func runServe(cmd *cobra.Command, args []string) {
 
 	// Setup graceful shutdown
 	shutdownChan := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
 	signal.Notify(shutdownChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
@@ -399,19 +513,34 @@ func runServe(cmd *cobra.Command, args []string) {
 			log.Error("Error shutting down HTTP server: %v", err)
 		}
 
+		// Stop the scheduler and wait for in-flight ticks
+		stopScheduler()
+		if sched != nil {
+			log.Info("   Stopping scheduler...")
+			sched.Wait()
+		}
+
 		// Stop the IPC client
 		log.Info("   Stopping app...")
 		if err := client.Close(); err != nil {
 			log.Error("Error stopping app: %v", err)
 		}
 
+		// Flush the spans of the last requests
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Error flushing traces: %v", err)
+		}
+
 		log.Info("✅ Server stopped")
-		os.Exit(0)
+		close(stopped)
 	}()
 
 	if insecure {
 		// HTTP/2 cleartext (h2c) mode
 		log.Warn("⚠️  Starting HTTP/2 server in insecure mode (h2c)")
+		if authPolicy.Enabled() {
+			log.Warn("⚠️  Credentials for authenticated endpoints will be sent in cleartext")
+		}
 		log.Info("🌐 Listening on http://localhost:%d", port)
 		log.Info("")
 		log.Info("Press Ctrl+C to stop the server")
@@ -477,28 +606,146 @@ func runServe(cmd *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 	}
+
+	// The server stops accepting requests as soon as shutdown begins; wait
+	// for the app to stop and the traces to be flushed
+	<-stopped
 }
 
-// Thread ID header and query parameter names
+// startScheduler registers every rate and cron trigger in the manifest with a
+// scheduler and starts it. Triggers with invalid schedules are logged and
+// skipped.
+func (ts *triggerServer) startScheduler(ctx context.Context) *scheduler.Scheduler {
+	sched := scheduler.New(ts.invokeScheduledTrigger, 5*time.Minute)
+	for _, trigger := range ts.manifest.Triggers {
+		if !scheduler.IsScheduled(trigger.TriggerType) {
+			continue
+		}
+		if _, err := sched.Add(trigger); err != nil {
+			log.Error("⚠️  Not scheduling %s/%s: %v", trigger.AgentName, trigger.Name, err)
+		}
+	}
+
+	if len(sched.Entries()) == 0 {
+		return sched
+	}
+
+	log.Info("⏰ Scheduled triggers:")
+	for _, entry := range sched.Entries() {
+		log.Info("   %s - %s", entry.Name(), entry.Schedule)
+	}
+	log.Info("")
+
+	sched.Start(ctx)
+	return sched
+}
+
+// invokeScheduledTrigger invokes a rate trigger for a scheduler tick
+func (ts *triggerServer) invokeScheduledTrigger(ctx context.Context, trigger ipc.TriggerInfo, scheduledAt time.Time) (*ipc.TriggerResponse, error) {
+	ctx, span := serveTracer.Start(ctx, "scheduled "+trigger.AgentName+"/"+trigger.Name, trace.WithAttributes(
+		attribute.String("shuttl.agent", trigger.AgentName),
+		attribute.String("shuttl.trigger", trigger.Name),
+	))
+	defer span.End()
+
+	headers := map[string][]string{ScheduledAtHeader: {scheduledAt.UTC().Format(time.RFC3339)}}
+	tracing.Inject(ctx, headers)
+
+	response, err := ts.client.InvokeTrigger(ctx, ipc.TriggerRequest{
+		AgentName:   trigger.AgentName,
+		TriggerName: trigger.Name,
+		TriggerType: trigger.TriggerType,
+		HTTPRequest: &ipc.SerializedHTTPRequest{
+			Method:      "POST",
+			Path:        fmt.Sprintf("/%s/%s", trigger.AgentName, trigger.Name),
+			Headers:     headers,
+			Query:       make(map[string][]string),
+			ContentType: "application/json",
+			RemoteAddr:  "scheduler",
+			Host:        "localhost",
+			Proto:       "SCHEDULER/1.0",
+			Timestamp:   time.Now(),
+		},
+	})
+	ts.metrics.ObserveResponse(trigger.AgentName, trigger.Name, response, err)
+	recordTriggerResult(span, response, err)
+	return response, err
+}
+
+// forwardAppLogs writes the app's log events and stderr output to the
+// server log until the client stops
+func forwardAppLogs(client *ipc.Client) {
+	for event := range client.Logs() {
+		fields := log.Fields{"source": event.Source}
+		if event.AgentID != "" {
+			fields[log.FieldAgent] = event.AgentID
+		}
+		appLog := log.With(fields)
+		switch event.Level {
+		case ipc.LogLevelError:
+			appLog.Error("[app] %s", event.Message)
+		case ipc.LogLevelWarn:
+			appLog.Warn("[app] %s", event.Message)
+		case ipc.LogLevelDebug:
+			appLog.Debug("[app] %s", event.Message)
+		default:
+			appLog.Info("[app] %s", event.Message)
+		}
+	}
+}
+
+// recordTriggerResult marks span as failed unless the trigger succeeded
+func recordTriggerResult(span trace.Span, response *ipc.TriggerResponse, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if !response.Success {
+		span.SetStatus(codes.Error, response.Error)
+	}
+}
+
+// Header and query parameter names used by trigger requests
 const (
 	ThreadIDHeader     = "X-Shuttl-Thread-ID"
 	ThreadIDQueryParam = "thread_id"
 	StreamQueryParam   = "stream"
+	RequestIDHeader    = "X-Request-ID"
+	ScheduledAtHeader  = "X-Shuttl-Scheduled-At"
+
+	// InputRequestedEvent is the SSE event sent when the agent waits for a
+	// reply, which is posted to RespondRoute
+	InputRequestedEvent = "input_requested"
+	RespondRoute        = manifestpkg.RespondRoute
 )
 
 // createTriggerHandler creates an HTTP handler for a trigger endpoint
 func (ts *triggerServer) createTriggerHandler(endpoint TriggerEndpoint) http.HandlerFunc {
+	// The mux only routes the endpoint's methods here and answers others
+	// with 405 Method Not Allowed
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Only allow POST requests
 		log.Info("createTriggerHandler: %s", endpoint.Path)
-		if r.Method != http.MethodPost {
-			w.Header().Set("Allow", "POST")
-			http.Error(w, "Method not allowed. Use POST.", http.StatusMethodNotAllowed)
-			return
-		}
 
-		// Read request body
+		// Trace the request, continuing the caller's trace if it sent one
+		ctx, span := serveTracer.Start(tracing.Extract(r.Context(), r.Header), r.Method+" "+endpoint.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", endpoint.Path),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("shuttl.agent", endpoint.AgentName),
+				attribute.String("shuttl.trigger", endpoint.TriggerName),
+			),
+		)
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		// Read request body, which auth may have limited in size
 		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
 			return
@@ -510,6 +757,22 @@ func (ts *triggerServer) createTriggerHandler(endpoint TriggerEndpoint) http.Han
 
 		// Check if streaming is requested (via query param or Accept header)
 		wantsStreaming := shouldStream(r)
+		span.SetAttributes(attribute.String("shuttl.thread_id", threadID), attribute.Bool("shuttl.streaming", wantsStreaming))
+
+		// Tag the request's log entries so they can be correlated, and hand
+		// the ID back to the caller
+		requestID := requestIDFor(r, span)
+		w.Header().Set(RequestIDHeader, requestID)
+		fields := log.Fields{
+			log.FieldAgent:     endpoint.AgentName,
+			log.FieldTrigger:   endpoint.TriggerName,
+			log.FieldRequestID: requestID,
+		}
+		if threadID != "" {
+			fields[log.FieldThreadID] = threadID
+		}
+		reqLog := log.With(fields)
+		ctx = log.NewContext(ctx, reqLog)
 
 		// Log the trigger invocation
 		streamStr := ""
@@ -517,13 +780,15 @@ func (ts *triggerServer) createTriggerHandler(endpoint TriggerEndpoint) http.Han
 			streamStr = ", streaming"
 		}
 		if threadID != "" {
-			log.Info("POST %s - Trigger invoked (thread: %s%s)", endpoint.Path, threadID, streamStr)
+			reqLog.Info("%s %s - Trigger invoked (thread: %s%s)", r.Method, r.URL.Path, threadID, streamStr)
 		} else {
-			log.Info("POST %s - Trigger invoked (new thread%s)", endpoint.Path, streamStr)
+			reqLog.Info("%s %s - Trigger invoked (new thread%s)", r.Method, r.URL.Path, streamStr)
 		}
 
-		// Serialize the HTTP request to JSON
-		serializedReq := ts.serializeHTTPRequest(r, body)
+		// Serialize the HTTP request to JSON, passing the trace context on
+		// so that the app's spans join the trace
+		serializedReq := ts.serializeHTTPRequest(r, body, endpoint.PathParams)
+		tracing.Inject(ctx, serializedReq.Headers)
 
 		// Create the trigger request for IPC
 		triggerReq := ipc.TriggerRequest{
@@ -535,7 +800,7 @@ func (ts *triggerServer) createTriggerHandler(endpoint TriggerEndpoint) http.Han
 		}
 
 		// Create a context with timeout for the IPC call
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 		defer cancel()
 
 		if wantsStreaming {
@@ -560,13 +825,43 @@ func shouldStream(r *http.Request) bool {
 	return strings.Contains(accept, "text/event-stream")
 }
 
+// handleHealth reports whether the app is up, along with its restart history
+func (ts *triggerServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	app := ts.client.Status()
+
+	status, code := "ok", http.StatusOK
+	switch ts.client.State() {
+	case ipc.StateRunning:
+	case ipc.StateRestarting:
+		status, code = "restarting", http.StatusServiceUnavailable
+	default:
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"time":   time.Now().UTC().Format(time.RFC3339),
+		"app":    app,
+	})
+}
+
 // handleNonStreamingTrigger handles a trigger request without streaming
 func (ts *triggerServer) handleNonStreamingTrigger(w http.ResponseWriter, ctx context.Context, triggerReq ipc.TriggerRequest) {
 	response, err := ts.client.InvokeTrigger(ctx, triggerReq)
+	ts.metrics.ObserveResponse(triggerReq.AgentName, triggerReq.TriggerName, response, err)
+	recordTriggerResult(trace.SpanFromContext(ctx), response, err)
+	reqLog := log.FromContext(ctx)
 	if err != nil {
-		log.Error("   Error invoking trigger: %v", err)
+		reqLog.Error("   Error invoking trigger: %v", err)
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
+		if ipc.IsAppUnavailable(err) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success":   false,
 			"error":     fmt.Sprintf("Failed to invoke trigger: %v", err),
@@ -577,11 +872,14 @@ func (ts *triggerServer) handleNonStreamingTrigger(w http.ResponseWriter, ctx co
 
 	// Return the response from the trigger
 	w.Header().Set("Content-Type", "application/json")
+	if response.ThreadID != "" {
+		reqLog = reqLog.With(log.Fields{log.FieldThreadID: response.ThreadID})
+	}
 	if response.Success {
-		log.Info("   ✅ Trigger completed successfully")
+		reqLog.Info("   ✅ Trigger completed successfully")
 		w.WriteHeader(http.StatusOK)
 	} else {
-		log.Warn("   ⚠️ Trigger returned error: %s", response.Error)
+		reqLog.Warn("   ⚠️ Trigger returned error: %s", response.Error)
 		w.WriteHeader(http.StatusBadRequest)
 	}
 	json.NewEncoder(w).Encode(response)
@@ -613,18 +911,27 @@ func (ts *triggerServer) handleStreamingTrigger(w http.ResponseWriter, r *http.R
 
 	// Start streaming trigger invocation
 	eventCh, errCh := ts.client.InvokeTriggerStreaming(ctx, triggerReq)
+	reqLog := log.FromContext(ctx)
+
+	// Stop accepting replies once the stream ends
+	var awaitingThread string
+	defer func() {
+		if awaitingThread != "" {
+			ts.stopAwaiting(awaitingThread)
+		}
+	}()
 
 	// Process events
 	for {
 		select {
 		case <-r.Context().Done():
 			// Client disconnected
-			log.Warn("   ⚠️ Client disconnected")
+			reqLog.Warn("   ⚠️ Client disconnected")
 			return
 
 		case err := <-errCh:
 			if err != nil {
-				log.Error("   Error in trigger stream: %v", err)
+				reqLog.Error("   Error in trigger stream: %v", err)
 				ts.sendSSEEvent(w, flusher, "error", map[string]interface{}{
 					"error":     err.Error(),
 					"timestamp": time.Now().UTC().Format(time.RFC3339),
@@ -634,9 +941,13 @@ func (ts *triggerServer) handleStreamingTrigger(w http.ResponseWriter, r *http.R
 		case event, ok := <-eventCh:
 			if !ok {
 				// Channel closed, stream complete
-				log.Info("   ✅ Trigger stream completed")
+				reqLog.Info("   ✅ Trigger stream completed")
 				return
 			}
+			ts.metrics.ObserveStreamEvent(endpoint.AgentName, endpoint.TriggerName, event)
+			if event.Error != "" {
+				trace.SpanFromContext(ctx).SetStatus(codes.Error, event.Error)
+			}
 
 			// Send the event
 			eventData := map[string]interface{}{
@@ -649,6 +960,10 @@ func (ts *triggerServer) handleStreamingTrigger(w http.ResponseWriter, r *http.R
 			}
 			if event.ThreadID != "" {
 				eventData["threadId"] = event.ThreadID
+				if triggerReq.ThreadID == "" {
+					reqLog = reqLog.With(log.Fields{log.FieldThreadID: event.ThreadID})
+					triggerReq.ThreadID = event.ThreadID
+				}
 			}
 			if event.Error != "" {
 				eventData["error"] = event.Error
@@ -657,16 +972,194 @@ func (ts *triggerServer) handleStreamingTrigger(w http.ResponseWriter, r *http.R
 				eventData["completed"] = true
 			}
 
+			if request := inputRequestOf(event); request != nil {
+				if request.ThreadID == "" {
+					request.ThreadID = triggerReq.ThreadID
+				}
+				if request.ThreadID == "" {
+					reqLog.Warn("   ⚠️ Agent asked for input without a thread ID; replies cannot be routed")
+				} else {
+					awaitingThread = request.ThreadID
+					ts.awaitInput(endpoint, request)
+					reqLog.Info("   🙋 Agent is waiting for input (thread: %s)", request.ThreadID)
+				}
+				// The caller may take a while to reply
+				http.NewResponseController(w).SetWriteDeadline(time.Time{})
+				inputData := map[string]interface{}{
+					"threadId":   request.ThreadID,
+					"requestId":  request.RequestID,
+					"prompt":     request.Prompt,
+					"kind":       request.Kind,
+					"respondUrl": "/threads/" + url.PathEscape(request.ThreadID) + "/respond",
+					"timestamp":  eventData["timestamp"],
+				}
+				if len(request.Options) > 0 {
+					inputData["options"] = request.Options
+				}
+				ts.sendSSEEvent(w, flusher, InputRequestedEvent, inputData)
+				continue
+			}
+
 			ts.sendSSEEvent(w, flusher, event.Type, eventData)
 
 			if event.Completed {
-				log.Info("   ✅ Trigger completed")
+				reqLog.Info("   ✅ Trigger completed")
 				return
 			}
 		}
 	}
 }
 
+// inputRequestOf returns the input request an event carries, if any
+func inputRequestOf(event *ipc.TriggerStreamEvent) *ipc.InputRequest {
+	if event.Type != "response.requested" {
+		return nil
+	}
+	return ipc.ParseInputRequest(event.Data)
+}
+
+// awaitInput records that the agent in request's thread waits for a reply
+func (ts *triggerServer) awaitInput(endpoint TriggerEndpoint, request *ipc.InputRequest) {
+	ts.inputMu.Lock()
+	defer ts.inputMu.Unlock()
+	ts.awaiting[request.ThreadID] = awaitingInput{endpoint: endpoint, request: request}
+}
+
+// stopAwaiting forgets the input request of a thread
+func (ts *triggerServer) stopAwaiting(threadID string) {
+	ts.inputMu.Lock()
+	defer ts.inputMu.Unlock()
+	delete(ts.awaiting, threadID)
+}
+
+// awaitingIn returns the input request the agent in a thread waits on
+func (ts *triggerServer) awaitingIn(threadID string) (awaitingInput, bool) {
+	ts.inputMu.Lock()
+	defer ts.inputMu.Unlock()
+	waiting, ok := ts.awaiting[threadID]
+	return waiting, ok
+}
+
+// claimInput removes an input request so that only one reply is sent to it,
+// reporting false if another reply already claimed it
+func (ts *triggerServer) claimInput(request *ipc.InputRequest) bool {
+	ts.inputMu.Lock()
+	defer ts.inputMu.Unlock()
+	if current, ok := ts.awaiting[request.ThreadID]; ok && current.request == request {
+		delete(ts.awaiting, request.ThreadID)
+		return true
+	}
+	return false
+}
+
+// unclaimInput puts back an input request whose reply could not be sent,
+// unless the agent has asked something else since
+func (ts *triggerServer) unclaimInput(waiting awaitingInput) {
+	ts.inputMu.Lock()
+	defer ts.inputMu.Unlock()
+	if _, ok := ts.awaiting[waiting.request.ThreadID]; !ok {
+		ts.awaiting[waiting.request.ThreadID] = waiting
+	}
+}
+
+// InputReply is the body of a reply posted to RespondRoute
+type InputReply struct {
+	RequestID string `json:"requestId,omitempty"`
+	Response  string `json:"response,omitempty"`
+	Approved  *bool  `json:"approved,omitempty"`
+}
+
+// handleRespond passes a reply on to the agent waiting for input in a thread.
+// The caller must pass the auth of the trigger the agent runs under. Threads
+// nobody waits on are only reported to callers verified by some provider, so
+// that others cannot find out which threads are waiting.
+func (ts *triggerServer) handleRespond(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("threadId")
+	waiting, ok := ts.awaitingIn(threadID)
+	if !ok {
+		ts.auth.WrapAny(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeNotAwaiting(w, threadID)
+		})).ServeHTTP(w, r)
+		return
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.sendInputReply(w, r, waiting)
+	})
+	ts.auth.Wrap(waiting.endpoint.AgentName, waiting.endpoint.TriggerName, handler).ServeHTTP(w, r)
+}
+
+func (ts *triggerServer) sendInputReply(w http.ResponseWriter, r *http.Request, waiting awaitingInput) {
+	var reply InputReply
+	if err := json.NewDecoder(r.Body).Decode(&reply); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid reply: %v", err))
+		return
+	}
+	request := waiting.request
+	if reply.RequestID != "" && request.RequestID != "" && reply.RequestID != request.RequestID {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("The agent is waiting on request %s, not %s", request.RequestID, reply.RequestID))
+		return
+	}
+	if request.IsApproval() && reply.Approved == nil {
+		writeJSONError(w, http.StatusBadRequest, `The agent asked for an approval; set "approved" to true or false`)
+		return
+	}
+
+	// Concurrent replies to the same question must not both reach the agent
+	if !ts.claimInput(request) {
+		writeNotAwaiting(w, request.ThreadID)
+		return
+	}
+
+	reqLog := log.With(log.Fields{
+		log.FieldAgent:    waiting.endpoint.AgentName,
+		log.FieldTrigger:  waiting.endpoint.TriggerName,
+		log.FieldThreadID: request.ThreadID,
+	})
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	err := ts.client.Respond(ctx, ipc.InputResponse{
+		ThreadID:  request.ThreadID,
+		RequestID: request.RequestID,
+		Response:  reply.Response,
+		Approved:  reply.Approved,
+	})
+	if err != nil {
+		ts.unclaimInput(waiting)
+		reqLog.Error("   Error sending reply: %v", err)
+		code := http.StatusInternalServerError
+		if ipc.IsAppUnavailable(err) {
+			w.Header().Set("Retry-After", "1")
+			code = http.StatusServiceUnavailable
+		}
+		writeJSONError(w, code, fmt.Sprintf("Failed to send reply: %v", err))
+		return
+	}
+
+	reqLog.Info("   ↩️  Reply sent to agent")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"threadId":  request.ThreadID,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// writeNotAwaiting answers a reply to a thread in which no agent waits
+func writeNotAwaiting(w http.ResponseWriter, threadID string) {
+	writeJSONError(w, http.StatusNotFound, fmt.Sprintf("No agent is waiting for input in thread %s", threadID))
+}
+
+// writeJSONError answers with an error in the same shape as failed triggers
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   false,
+		"error":     message,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
 // sendSSEEvent sends an SSE event to the client
 func (ts *triggerServer) sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, eventType string, data interface{}) {
 	jsonData, err := json.Marshal(data)
@@ -680,6 +1173,20 @@ func (ts *triggerServer) sendSSEEvent(w http.ResponseWriter, flusher http.Flushe
 	flusher.Flush()
 }
 
+// requestIDFor returns the caller's X-Request-ID, or else the trace ID of
+// span, or else a random ID
+func requestIDFor(r *http.Request, span trace.Span) string {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	if traceID := span.SpanContext().TraceID(); traceID.IsValid() {
+		return traceID.String()
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
 // extractThreadID extracts the thread ID from the request header or query parameter
 // Header takes precedence over query parameter
 func extractThreadID(r *http.Request) string {
@@ -700,8 +1207,9 @@ func extractThreadID(r *http.Request) string {
 	return threadID
 }
 
-// serializeHTTPRequest converts an HTTP request to a serialized JSON structure
-func (ts *triggerServer) serializeHTTPRequest(r *http.Request, body []byte) *ipc.SerializedHTTPRequest {
+// serializeHTTPRequest converts an HTTP request to a serialized JSON structure,
+// including the values of the endpoint's path parameters
+func (ts *triggerServer) serializeHTTPRequest(r *http.Request, body []byte, pathParams []string) *ipc.SerializedHTTPRequest {
 	// Convert headers to map
 	headers := make(map[string][]string)
 	for key, values := range r.Header {
@@ -714,6 +1222,26 @@ func (ts *triggerServer) serializeHTTPRequest(r *http.Request, body []byte) *ipc
 		query[key] = values
 	}
 
+	// Extract path parameters matched by the route template
+	var params map[string]string
+	if len(pathParams) > 0 {
+		params = make(map[string]string, len(pathParams))
+		for _, name := range pathParams {
+			params[name] = r.PathValue(name)
+		}
+	}
+
+	// Convert cookies to map, keeping the first value of repeated cookies
+	var cookies map[string]string
+	for _, cookie := range r.Cookies() {
+		if cookies == nil {
+			cookies = make(map[string]string)
+		}
+		if _, ok := cookies[cookie.Name]; !ok {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+
 	// Determine content type
 	contentType := r.Header.Get("Content-Type")
 
@@ -723,11 +1251,14 @@ func (ts *triggerServer) serializeHTTPRequest(r *http.Request, body []byte) *ipc
 		Path:        r.URL.Path,
 		Headers:     headers,
 		Query:       query,
+		PathParams:  params,
+		Cookies:     cookies,
 		ContentType: contentType,
 		RemoteAddr:  r.RemoteAddr,
 		Host:        r.Host,
 		Proto:       r.Proto,
 		Timestamp:   time.Now(),
+		Principal:   httpauth.PrincipalFrom(r.Context()),
 	}
 
 	// Add body if present
@@ -738,6 +1269,36 @@ func (ts *triggerServer) serializeHTTPRequest(r *http.Request, body []byte) *ipc
 	return serialized
 }
 
+// handleRoute registers a handler on mux, returning the panic raised for
+// patterns that conflict with existing ones as an error
+func handleRoute(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
+}
+
+// loadAuthPolicy reads the auth section of shuttl.json. Without --config a
+// missing shuttl.json leaves every endpoint public.
+func loadAuthPolicy(configPath string) (*httpauth.Policy, error) {
+	if configPath == "" {
+		found, err := config.FindConfigFile()
+		if err != nil {
+			return httpauth.NewPolicy(nil, "")
+		}
+		configPath = found
+	}
+
+	cfg, err := config.LoadConfigFromPath(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return httpauth.NewPolicy(cfg.Auth, config.GetConfigDir(configPath))
+}
+
 // generateSelfSignedCert generates a self-signed TLS certificate for development
 func generateSelfSignedCert() (tls.Certificate, error) {
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/shuttl-ai/cli/agenttest"
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/log"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [path...]",
+	Short: "Run agent test suites against the app",
+	Long: `Run declarative test suites against the app's agents and triggers.
+
+A suite is a YAML or JSON file of cases. Each case sends a prompt to an agent,
+or an event to one of its triggers, and checks the result: text the output
+contains or matches, tools called with given arguments, the number of tool
+calls and the time taken. Directories are searched for *.test.yaml,
+*.test.yml and *.test.json files; the default is the current directory.
+
+The app is started via IPC. If a shuttl.json file is found, the "app" field
+will be used by default. With --replay the app is not run: requests are
+answered from a recording made with --record here or with shuttl dev, so
+failures can be reproduced offline. With --attach the suites run against an
+app that is already running and listening on a Unix domain socket or TCP
+port.
+
+Exit status:
+  0  all cases passed
+  1  a case failed or could not run
+  2  the suites or the app could not be loaded
+
+Examples:
+  shuttl test
+  shuttl test tests/support.test.yaml
+  shuttl test --format junit --output report.xml
+  shuttl test --format tap --run 'refunds/.*'
+  shuttl test --record session.jsonl
+  shuttl test --replay session.jsonl
+  shuttl test --attach tcp://localhost:7000`,
+	Run: runTest,
+}
+
+func init() {
+	testCmd.Flags().String("app", "", "App command to run (defaults to the \"app\" field of shuttl.json)")
+	testCmd.Flags().String("config", "", "Path to shuttl.json (defaults to searching current and parent directories)")
+	testCmd.Flags().String("format", agenttest.FormatText, "Report format: text, tap or junit")
+	testCmd.Flags().StringP("output", "o", "", "Write the report to a file instead of stdout; a text summary is still printed")
+	testCmd.Flags().String("run", "", "Only run cases whose \"suite/case\" name matches this regular expression")
+	testCmd.Flags().Duration("timeout", 0, "Timeout for every case, overriding the suites (default 2m)")
+	testCmd.Flags().String("record", "", "Record the requests and messages exchanged with the app to this file")
+	testCmd.Flags().String("replay", "", "Answer requests from a recording instead of running the app")
+	testCmd.Flags().String("attach", "", "Connect to an app that is already running at unix:///path/to/app.sock or tcp://host:port")
+	testCmd.MarkFlagsMutuallyExclusive("attach", "replay", "app")
+	rootCmd.AddCommand(testCmd)
+}
+
+func runTest(cmd *cobra.Command, args []string) {
+	appPath, _ := cmd.Flags().GetString("app")
+	configPath, _ := cmd.Flags().GetString("config")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	filter, _ := cmd.Flags().GetString("run")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	recordPath, _ := cmd.Flags().GetString("record")
+	replayPath, _ := cmd.Flags().GetString("replay")
+	attach, _ := cmd.Flags().GetString("attach")
+
+	// Keep stdout for the report
+	log.Default.SetConsole(os.Stderr)
+
+	switch format {
+	case agenttest.FormatText, agenttest.FormatTAP, agenttest.FormatJUnit:
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Error: unknown format %q; use text, tap or junit\n", format)
+		os.Exit(2)
+	}
+
+	runner := &agenttest.Runner{Timeout: timeout}
+	if filter != "" {
+		pattern, err := regexp.Compile(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: invalid --run pattern: %v\n", err)
+			os.Exit(2)
+		}
+		runner.Filter = func(suite, name string) bool {
+			return pattern.MatchString(suite + "/" + name)
+		}
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files, err := agenttest.Find(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(2)
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "❌ Error: no test suites found; name them *.test.yaml, *.test.yml or *.test.json\n")
+		os.Exit(2)
+	}
+	var suites []*agenttest.Suite
+	for _, file := range files {
+		suite, err := agenttest.Load(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(2)
+		}
+		suites = append(suites, suite)
+	}
+
+	client, err := testClient(appPath, configPath, replayPath, attach)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	var recorder *ipc.Recorder
+	if recordPath != "" {
+		if recorder, err = startRecording(client, recordPath); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if err := client.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error starting app: %v\n", err)
+		os.Exit(2)
+	}
+	runner.Client = client
+	report := runner.Run(client.Context(), suites)
+	client.Close()
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: %v\n", err)
+		}
+	}
+
+	if err := writeTestReport(report, format, output); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(2)
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
+
+// testClient returns a client for the app to test: the app running at the
+// --attach address, the replay of a recording, the --app flag or the "app"
+// field of shuttl.json
+func testClient(appPath, configPath, replayPath, attach string) (*ipc.Client, error) {
+	if attach != "" {
+		transport, err := ipc.ParseTransport(attach)
+		if err != nil {
+			return nil, err
+		}
+		return ipc.NewClientWithTransport(transport), nil
+	}
+	if replayPath != "" {
+		command, err := replayAppCommand(replayPath)
+		if err != nil {
+			return nil, err
+		}
+		return ipc.NewClient(command), nil
+	}
+	if appPath == "" {
+		var err error
+		if appPath, err = loadAppFromConfig(configPath); err != nil {
+			return nil, err
+		}
+	}
+	if appPath == "" {
+		return nil, fmt.Errorf("no app specified; use --app or create a shuttl.json config file")
+	}
+	command := ipc.ParseCommand(appPath)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty app command")
+	}
+	return ipc.NewClient(command), nil
+}
+
+// writeTestReport writes the report to stdout, or to output with a text
+// summary on stdout
+func writeTestReport(report *agenttest.Report, format, output string) error {
+	if output == "" {
+		return report.Write(os.Stdout, format)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := report.Write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Printf("📄 Wrote %s report to %s\n", format, output)
+	return nil
+}
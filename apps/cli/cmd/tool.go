@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/spf13/cobra"
+)
+
+var toolCmd = &cobra.Command{
+	Use:   "tool",
+	Short: "Work with the tools exposed by the app",
+	Long:  `Inspect and invoke the tools exposed by the app's toolkits.`,
+}
+
+var toolInvokeCmd = &cobra.Command{
+	Use:   "invoke <toolkit>/<tool>",
+	Short: "Invoke a single tool directly",
+	Long: `Invoke a single tool in a toolkit without running an agent conversation.
+
+The app is started via IPC, the tool's argument schema is fetched and the
+arguments are validated against it before the tool is invoked. The tool's
+result is printed as JSON.
+
+If a shuttl.json file is found, the "app" field will be used by default.
+
+Examples:
+  shuttl tool invoke UtilityToolkit/add --args '{"a": 1, "b": 2}'
+  shuttl tool invoke WeatherToolkit/forecast --args '{"city": "Paris"}' --app "node ./dist/main.js"`,
+	Args: cobra.ExactArgs(1),
+	Run:  runToolInvoke,
+}
+
+func init() {
+	toolInvokeCmd.Flags().String("args", "{}", "Tool arguments as a JSON object")
+	toolInvokeCmd.Flags().String("app", "", "App command to run (defaults to the \"app\" field of shuttl.json)")
+	toolInvokeCmd.Flags().String("config", "", "Path to shuttl.json (defaults to searching current and parent directories)")
+	toolInvokeCmd.Flags().Bool("skip-validation", false, "Send the arguments without validating them against the tool schema")
+	toolInvokeCmd.Flags().Duration("timeout", 2*time.Minute, "Maximum time to wait for the tool result")
+	toolCmd.AddCommand(toolInvokeCmd)
+	rootCmd.AddCommand(toolCmd)
+}
+
+func runToolInvoke(cmd *cobra.Command, args []string) {
+	rawArgs, _ := cmd.Flags().GetString("args")
+	appPath, _ := cmd.Flags().GetString("app")
+	configPath, _ := cmd.Flags().GetString("config")
+	skipValidation, _ := cmd.Flags().GetBool("skip-validation")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	toolkitName, toolName, ok := strings.Cut(args[0], "/")
+	if !ok || toolkitName == "" || toolName == "" {
+		fmt.Fprintf(os.Stderr, "❌ Error: tool must be given as <toolkit>/<tool>, got %q\n", args[0])
+		os.Exit(1)
+	}
+
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(rawArgs), &toolArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: --args must be a JSON object: %v\n", err)
+		os.Exit(1)
+	}
+
+	if appPath == "" {
+		var err error
+		appPath, err = loadAppFromConfig(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if appPath == "" {
+		fmt.Fprintf(os.Stderr, "❌ Error: no app specified. Use --app or create a shuttl.json config file.\n")
+		os.Exit(1)
+	}
+
+	command := ipc.ParseCommand(appPath)
+	if len(command) == 0 {
+		fmt.Fprintf(os.Stderr, "❌ Error: empty app command\n")
+		os.Exit(1)
+	}
+
+	client := ipc.NewClient(command)
+	if err := client.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error starting app: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(client.Context(), timeout)
+	result, err := invokeTool(ctx, client, toolkitName, toolName, toolArgs, skipValidation)
+	cancel()
+	client.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, result, "", "  "); err == nil {
+		fmt.Println(pretty.String())
+	} else {
+		fmt.Println(string(result))
+	}
+}
+
+// invokeTool looks the tool up, validates the arguments and invokes it
+func invokeTool(ctx context.Context, client *ipc.Client, toolkitName, toolName string, toolArgs map[string]any, skipValidation bool) (json.RawMessage, error) {
+	if !skipValidation {
+		tools, err := client.GetTools(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch tools: %w", err)
+		}
+
+		var tool *ipc.SingleToolInfo
+		for i := range tools {
+			if tools[i].ToolkitName == toolkitName && tools[i].Name == toolName {
+				tool = &tools[i]
+				break
+			}
+		}
+		if tool == nil {
+			return nil, fmt.Errorf("tool %s/%s not found in app", toolkitName, toolName)
+		}
+
+		if err := ipc.ValidateToolArgs(*tool, toolArgs); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := client.InvokeTool(ctx, toolkitName, toolName, toolArgs)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s/%s failed: %w", toolkitName, toolName, err)
+	}
+	return result, nil
+}
+
+// loadAppFromConfig returns the "app" field of shuttl.json. An explicit config
+// path must exist; otherwise a missing config is not an error.
+func loadAppFromConfig(configPath string) (string, error) {
+	var cfg *config.Config
+	if configPath != "" {
+		var err error
+		cfg, err = config.LoadConfigFromPath(configPath)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		cfg, _ = config.LoadConfig()
+	}
+
+	if cfg == nil {
+		return "", nil
+	}
+	return cfg.App, nil
+}
@@ -11,8 +11,64 @@ const ConfigFileName = "shuttl.json"
 
 // Config represents the structure of shuttl.json
 type Config struct {
-	App            string `json:"app"`
-	OrganizationID *int   `json:"organization_id"`
+	App            string       `json:"app"`
+	OrganizationID *int         `json:"organization_id"`
+	Watch          *WatchConfig `json:"watch,omitempty"`
+	Auth           *AuthConfig  `json:"auth,omitempty"`
+}
+
+// WatchConfig selects the files that make `shuttl dev` reload the app.
+// Patterns are relative to the directory containing shuttl.json and "**"
+// matches any number of directories.
+type WatchConfig struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// AuthConfig protects the endpoints of `shuttl serve`. Each endpoint accepts
+// a request if any of its providers verifies it. Providers are chosen by the
+// most specific entry: the trigger, then the agent, then Default. An empty
+// provider list makes an endpoint public.
+type AuthConfig struct {
+	Providers map[string]AuthProviderConfig `json:"providers"`
+	Default   []string                      `json:"default,omitempty"`
+	Agents    map[string]AgentAuthConfig    `json:"agents,omitempty"`
+	// MaxBodyBytes caps the size of request bodies on protected endpoints;
+	// zero uses the default of 10 MiB
+	MaxBodyBytes int64 `json:"maxBodyBytes,omitempty"`
+}
+
+// AgentAuthConfig selects the auth providers for an agent's triggers
+type AgentAuthConfig struct {
+	Providers []string            `json:"providers"`
+	Triggers  map[string][]string `json:"triggers,omitempty"`
+}
+
+// AuthProviderConfig configures one way of verifying requests. Which fields
+// apply depends on Type: "api_key", "hmac" or "jwt". Relative file paths are
+// resolved against the directory containing shuttl.json.
+type AuthProviderConfig struct {
+	Type string `json:"type"`
+
+	// api_key: keys are read from a JSON object of name to key, from an
+	// environment variable of comma-separated name=key pairs, or both
+	KeysFile string `json:"keysFile,omitempty"`
+	KeysEnv  string `json:"keysEnv,omitempty"`
+
+	// hmac: the shared secret is read from a file or environment variable
+	SecretFile string `json:"secretFile,omitempty"`
+	SecretEnv  string `json:"secretEnv,omitempty"`
+	// Window is how far the request timestamp may be from the server's
+	// clock, as a duration such as "5m"
+	Window string `json:"window,omitempty"`
+
+	// jwt: tokens are verified against the keys in a local JWKS file
+	JWKSFile string   `json:"jwksFile,omitempty"`
+	Issuer   string   `json:"issuer,omitempty"`
+	Audience []string `json:"audience,omitempty"`
+
+	// Header overrides the header carrying the API key or HMAC signature
+	Header string `json:"header,omitempty"`
 }
 
 // LoadConfig looks for shuttl.json in the current directory and parent directories
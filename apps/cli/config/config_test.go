@@ -54,6 +54,61 @@ func TestLoadConfigFromPath(t *testing.T) {
 		}
 	})
 
+	t.Run("config with watch patterns", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "watch_shuttl.json")
+		content := `{"app": "my-app", "watch": {"include": ["src/**/*.ts"], "exclude": ["dist/**"]}}`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		config, err := LoadConfigFromPath(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfigFromPath failed: %v", err)
+		}
+
+		if config.Watch == nil {
+			t.Fatal("Expected Watch to be set")
+		}
+		if len(config.Watch.Include) != 1 || config.Watch.Include[0] != "src/**/*.ts" {
+			t.Errorf("Expected Include to be [src/**/*.ts], got %v", config.Watch.Include)
+		}
+		if len(config.Watch.Exclude) != 1 || config.Watch.Exclude[0] != "dist/**" {
+			t.Errorf("Expected Exclude to be [dist/**], got %v", config.Watch.Exclude)
+		}
+	})
+
+	t.Run("config with auth", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "auth_shuttl.json")
+		content := `{"app": "my-app", "auth": {
+			"providers": {"partners": {"type": "api_key", "keysFile": "keys.json"}},
+			"default": ["partners"],
+			"agents": {"Bot": {"triggers": {"status": []}}}
+		}}`
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		config, err := LoadConfigFromPath(configPath)
+		if err != nil {
+			t.Fatalf("LoadConfigFromPath failed: %v", err)
+		}
+
+		if config.Auth == nil {
+			t.Fatal("Expected Auth to be set")
+		}
+		if provider := config.Auth.Providers["partners"]; provider.Type != "api_key" || provider.KeysFile != "keys.json" {
+			t.Errorf("Unexpected provider: %+v", provider)
+		}
+		bot := config.Auth.Agents["Bot"]
+		// An absent list inherits the default, an empty one makes the trigger public
+		if bot.Providers != nil {
+			t.Errorf("Expected agent providers to be unset, got %v", bot.Providers)
+		}
+		if status, ok := bot.Triggers["status"]; !ok || status == nil || len(status) != 0 {
+			t.Errorf("Expected an empty provider list for status, got %v", status)
+		}
+	})
+
 	t.Run("non-existent file", func(t *testing.T) {
 		_, err := LoadConfigFromPath(filepath.Join(tmpDir, "nonexistent.json"))
 		if err == nil {
@@ -0,0 +1,114 @@
+package httpauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// DefaultAPIKeyHeader carries the API key unless a provider sets Header
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// apiKeyAuthenticator accepts requests carrying one of a set of named keys
+type apiKeyAuthenticator struct {
+	header string
+	// keys maps the SHA-256 of each key to its name, so that lookups
+	// compare fixed-length digests
+	keys map[[sha256.Size]byte]string
+}
+
+func newAPIKeyAuthenticator(cfg config.AuthProviderConfig, baseDir string) (*apiKeyAuthenticator, error) {
+	if cfg.KeysFile == "" && cfg.KeysEnv == "" {
+		return nil, errors.New("api_key providers need keysFile or keysEnv")
+	}
+
+	keys := make(map[string]string)
+	if cfg.KeysFile != "" {
+		path := resolvePath(baseDir, cfg.KeysFile)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keys file: %w", err)
+		}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse keys file %s: expected an object of key names to keys: %w", path, err)
+		}
+	}
+	if cfg.KeysEnv != "" {
+		value := os.Getenv(cfg.KeysEnv)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", cfg.KeysEnv)
+		}
+		envKeys, err := parseKeyList(value)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", cfg.KeysEnv, err)
+		}
+		for keyName, key := range envKeys {
+			keys[keyName] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no API keys configured")
+	}
+
+	a := &apiKeyAuthenticator{
+		header: cfg.Header,
+		keys:   make(map[[sha256.Size]byte]string, len(keys)),
+	}
+	if a.header == "" {
+		a.header = DefaultAPIKeyHeader
+	}
+	for keyName, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("API key %q is empty", keyName)
+		}
+		a.keys[sha256.Sum256([]byte(key))] = keyName
+	}
+	return a, nil
+}
+
+// parseKeyList parses comma-separated name=key pairs. A key without a name
+// is named after its position.
+func parseKeyList(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, key = fmt.Sprintf("key%d", i+1), entry
+		}
+		if name == "" || key == "" {
+			return nil, fmt.Errorf("invalid entry %q; use name=key", entry)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+// Authenticate checks the API key header. The header is removed from the
+// request once verified so that the key is not passed on to the app.
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request, body []byte) (*ipc.Principal, error) {
+	key := r.Header.Get(a.header)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	for candidate, name := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], candidate[:]) == 1 {
+			r.Header.Del(a.header)
+			return &ipc.Principal{Subject: name}, nil
+		}
+	}
+	return nil, errors.New("invalid API key")
+}
@@ -0,0 +1,294 @@
+package httpauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// Provider types accepted in shuttl.json
+const (
+	TypeAPIKey = "api_key"
+	TypeHMAC   = "hmac"
+	TypeJWT    = "jwt"
+)
+
+// DefaultMaxBodyBytes caps request bodies on protected endpoints unless the
+// auth config sets maxBodyBytes
+const DefaultMaxBodyBytes = 10 << 20
+
+// ErrNoCredentials is returned by an Authenticator when the request carries
+// no credentials for it, so that the next provider can be tried
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator verifies the credentials of a request. body is the request
+// body if one of the endpoint's providers is a bodyVerifier, and nil
+// otherwise.
+type Authenticator interface {
+	Authenticate(r *http.Request, body []byte) (*ipc.Principal, error)
+}
+
+// bodyVerifier is implemented by Authenticators that need the request body,
+// which is then read before authenticating
+type bodyVerifier interface {
+	verifiesBody()
+}
+
+// Provider is a named Authenticator from shuttl.json
+type Provider struct {
+	Name string
+	Type string
+	Authenticator
+}
+
+// Policy decides which providers protect each agent and trigger
+type Policy struct {
+	providers map[string]*Provider
+	config    *config.AuthConfig
+}
+
+// NewPolicy builds the providers in cfg, reading keys, secrets and JWKS
+// files relative to baseDir. A nil cfg gives a policy that protects nothing.
+func NewPolicy(cfg *config.AuthConfig, baseDir string) (*Policy, error) {
+	p := &Policy{providers: make(map[string]*Provider), config: cfg}
+	if cfg == nil {
+		return p, nil
+	}
+
+	for name, providerCfg := range cfg.Providers {
+		provider, err := newProvider(name, providerCfg, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("auth provider %q: %w", name, err)
+		}
+		p.providers[name] = provider
+	}
+
+	if err := p.checkReferences(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newProvider(name string, cfg config.AuthProviderConfig, baseDir string) (*Provider, error) {
+	var auth Authenticator
+	var err error
+	switch cfg.Type {
+	case TypeAPIKey:
+		auth, err = newAPIKeyAuthenticator(cfg, baseDir)
+	case TypeHMAC:
+		auth, err = newHMACAuthenticator(name, cfg, baseDir)
+	case TypeJWT:
+		auth, err = newJWTAuthenticator(cfg, baseDir)
+	case "":
+		err = fmt.Errorf("missing type; use %s, %s or %s", TypeAPIKey, TypeHMAC, TypeJWT)
+	default:
+		err = fmt.Errorf("unknown type %q; use %s, %s or %s", cfg.Type, TypeAPIKey, TypeHMAC, TypeJWT)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{Name: name, Type: cfg.Type, Authenticator: auth}, nil
+}
+
+// checkReferences makes sure every provider named by the policy exists
+func (p *Policy) checkReferences() error {
+	check := func(where string, names []string) error {
+		for _, name := range names {
+			if _, ok := p.providers[name]; !ok {
+				return fmt.Errorf("%s refers to unknown auth provider %q", where, name)
+			}
+		}
+		return nil
+	}
+
+	if err := check("auth.default", p.config.Default); err != nil {
+		return err
+	}
+	for agent, agentCfg := range p.config.Agents {
+		if err := check(fmt.Sprintf("auth.agents.%s", agent), agentCfg.Providers); err != nil {
+			return err
+		}
+		for trigger, names := range agentCfg.Triggers {
+			if err := check(fmt.Sprintf("auth.agents.%s.triggers.%s", agent, trigger), names); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ProvidersFor returns the names of the providers that protect a trigger,
+// taken from the most specific level that sets them. An empty result means
+// the trigger is public.
+func (p *Policy) ProvidersFor(agent, trigger string) []string {
+	if p.config == nil {
+		return nil
+	}
+	if agentCfg, ok := p.config.Agents[agent]; ok {
+		if names, ok := agentCfg.Triggers[trigger]; ok {
+			return names
+		}
+		if agentCfg.Providers != nil {
+			return agentCfg.Providers
+		}
+	}
+	return p.config.Default
+}
+
+// Wrap returns a handler that only calls next for requests verified by one
+// of the trigger's providers. The verified principal is available to next
+// through PrincipalFrom. Requests that fail are answered with 401.
+func (p *Policy) Wrap(agent, trigger string, next http.Handler) http.Handler {
+	return p.wrap(p.ProvidersFor(agent, trigger), next)
+}
+
+// WrapAny is like Wrap for endpoints that belong to no trigger, accepting
+// requests verified by any configured provider
+func (p *Policy) WrapAny(next http.Handler) http.Handler {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return p.wrap(names, next)
+}
+
+// wrap protects next with the named providers
+func (p *Policy) wrap(names []string, next http.Handler) http.Handler {
+	if len(names) == 0 {
+		return next
+	}
+	providers := make([]*Provider, len(names))
+	readBody := false
+	for i, name := range names {
+		providers[i] = p.providers[name]
+		if _, ok := providers[i].Authenticator.(bodyVerifier); ok {
+			readBody = true
+		}
+	}
+	maxBody := p.maxBodyBytes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The limit holds for next too, whether or not the body is read here
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+		// Only buffer the body before authenticating if a provider signs it
+		var body []byte
+		if readBody {
+			var err error
+			body, err = io.ReadAll(r.Body)
+			r.Body.Close()
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error reading request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		principal, err := authenticate(providers, r, body)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// maxBodyBytes returns the body size limit of protected endpoints
+func (p *Policy) maxBodyBytes() int64 {
+	if p.config != nil && p.config.MaxBodyBytes > 0 {
+		return p.config.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+// authenticate tries each provider in turn. The error of the first provider
+// that found credentials is returned if none accepts the request.
+func authenticate(providers []*Provider, r *http.Request, body []byte) (*ipc.Principal, error) {
+	var firstErr error
+	for _, provider := range providers {
+		principal, err := provider.Authenticate(r, body)
+		if err == nil {
+			principal.Provider = provider.Name
+			principal.Type = provider.Type
+			return principal, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, errors.New("missing credentials")
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   false,
+		"error":     fmt.Sprintf("Unauthorized: %v", err),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Describe returns a short description of the providers protecting a
+// trigger, e.g. "api_key:partners, jwt:sso", or "public"
+func (p *Policy) Describe(agent, trigger string) string {
+	names := p.ProvidersFor(agent, trigger)
+	if len(names) == 0 {
+		return "public"
+	}
+	desc := ""
+	for i, name := range names {
+		if i > 0 {
+			desc += ", "
+		}
+		desc += p.providers[name].Type + ":" + name
+	}
+	return desc
+}
+
+// Enabled reports whether any provider is configured
+func (p *Policy) Enabled() bool {
+	return len(p.providers) > 0
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal
+func WithPrincipal(ctx context.Context, principal *ipc.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal verified for a request, or nil for
+// public endpoints
+func PrincipalFrom(ctx context.Context) *ipc.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*ipc.Principal)
+	return principal
+}
+
+// resolvePath makes relative paths relative to baseDir
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
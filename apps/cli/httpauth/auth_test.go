@@ -0,0 +1,357 @@
+package httpauth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestProvidersFor(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys.json", `{"partner": "k1"}`)
+
+	cfg := &config.AuthConfig{
+		Providers: map[string]config.AuthProviderConfig{
+			"keys":  {Type: TypeAPIKey, KeysFile: "keys.json"},
+			"other": {Type: TypeAPIKey, KeysFile: "keys.json"},
+		},
+		Default: []string{"keys"},
+		Agents: map[string]config.AgentAuthConfig{
+			"Support": {
+				Providers: []string{"other"},
+				Triggers:  map[string][]string{"webhook": {}, "signed": {"keys", "other"}},
+			},
+			"Reporter": {Triggers: map[string][]string{"daily": {"other"}}},
+		},
+	}
+	policy, err := NewPolicy(cfg, dir)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	testCases := []struct {
+		agent, trigger string
+		expected       []string
+	}{
+		{"Support", "webhook", []string{}},
+		{"Support", "signed", []string{"keys", "other"}},
+		{"Support", "chat", []string{"other"}},
+		{"Reporter", "daily", []string{"other"}},
+		{"Reporter", "weekly", []string{"keys"}},
+		{"Unknown", "api", []string{"keys"}},
+	}
+	for _, tc := range testCases {
+		got := policy.ProvidersFor(tc.agent, tc.trigger)
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("ProvidersFor(%s, %s) = %v, expected %v", tc.agent, tc.trigger, got, tc.expected)
+		}
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys.json", `{"partner": "k1"}`)
+
+	testCases := []struct {
+		name   string
+		cfg    config.AuthConfig
+		substr string
+	}{
+		{
+			name: "unknown provider",
+			cfg: config.AuthConfig{
+				Providers: map[string]config.AuthProviderConfig{"keys": {Type: TypeAPIKey, KeysFile: "keys.json"}},
+				Agents:    map[string]config.AgentAuthConfig{"A": {Providers: []string{"sso"}}},
+			},
+			substr: `auth.agents.A refers to unknown auth provider "sso"`,
+		},
+		{
+			name:   "unknown type",
+			cfg:    config.AuthConfig{Providers: map[string]config.AuthProviderConfig{"x": {Type: "basic"}}},
+			substr: `unknown type "basic"`,
+		},
+		{
+			name:   "missing keys file",
+			cfg:    config.AuthConfig{Providers: map[string]config.AuthProviderConfig{"x": {Type: TypeAPIKey, KeysFile: "missing.json"}}},
+			substr: "failed to read keys file",
+		},
+		{
+			name:   "hmac without secret",
+			cfg:    config.AuthConfig{Providers: map[string]config.AuthProviderConfig{"x": {Type: TypeHMAC}}},
+			substr: "need secretFile or secretEnv",
+		},
+		{
+			name:   "jwt without jwks",
+			cfg:    config.AuthConfig{Providers: map[string]config.AuthProviderConfig{"x": {Type: TypeJWT}}},
+			substr: "need jwksFile",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPolicy(&tc.cfg, dir)
+			if err == nil || !strings.Contains(err.Error(), tc.substr) {
+				t.Errorf("Expected error containing %q, got %v", tc.substr, err)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys.json", `{"partner": "k1"}`)
+	policy, err := NewPolicy(&config.AuthConfig{
+		Providers: map[string]config.AuthProviderConfig{"keys": {Type: TypeAPIKey, KeysFile: "keys.json"}},
+		Default:   []string{"keys"},
+		Agents:    map[string]config.AgentAuthConfig{"Public": {Providers: []string{}}},
+	}, dir)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	var gotPrincipal *ipc.Principal
+	var gotBody string
+	var gotKey string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrincipal = PrincipalFrom(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotKey = r.Header.Get(DefaultAPIKeyHeader)
+	})
+
+	t.Run("verified", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/A/api", strings.NewReader(`{"hello":1}`))
+		req.Header.Set(DefaultAPIKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		policy.Wrap("A", "api", next).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expected := &ipc.Principal{Provider: "keys", Type: TypeAPIKey, Subject: "partner"}
+		if !reflect.DeepEqual(gotPrincipal, expected) {
+			t.Errorf("Expected principal %+v, got %+v", expected, gotPrincipal)
+		}
+		if gotBody != `{"hello":1}` {
+			t.Errorf("Expected the body to reach the handler, got %q", gotBody)
+		}
+		if gotKey != "" {
+			t.Errorf("Expected the API key header to be removed, got %q", gotKey)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		for _, key := range []string{"", "wrong"} {
+			req := httptest.NewRequest("POST", "/A/api", nil)
+			if key != "" {
+				req.Header.Set(DefaultAPIKeyHeader, key)
+			}
+			rec := httptest.NewRecorder()
+			policy.Wrap("A", "api", next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected 401 for key %q, got %d", key, rec.Code)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["success"] != false {
+				t.Errorf("Expected a JSON error body, got %s", rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("public", func(t *testing.T) {
+		gotPrincipal = nil
+		rec := httptest.NewRecorder()
+		policy.Wrap("Public", "api", next).ServeHTTP(rec, httptest.NewRequest("POST", "/Public/api", nil))
+		if rec.Code != http.StatusOK || gotPrincipal != nil {
+			t.Errorf("Expected a public endpoint, got %d with principal %+v", rec.Code, gotPrincipal)
+		}
+	})
+}
+
+func TestWrapAny(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys.json", `{"partner": "k1"}`)
+	writeFile(t, dir, "other.json", `{"ops": "k2"}`)
+	policy, err := NewPolicy(&config.AuthConfig{
+		Providers: map[string]config.AuthProviderConfig{
+			"keys":  {Type: TypeAPIKey, KeysFile: "keys.json"},
+			"other": {Type: TypeAPIKey, KeysFile: "other.json", Header: "X-Ops-Key"},
+		},
+		Agents: map[string]config.AgentAuthConfig{"A": {Providers: []string{"keys"}}},
+	}, dir)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	var gotPrincipal *ipc.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrincipal = PrincipalFrom(r.Context())
+	})
+
+	testCases := []struct {
+		name    string
+		header  string
+		key     string
+		code    int
+		subject string
+	}{
+		{"first provider", DefaultAPIKeyHeader, "k1", http.StatusOK, "partner"},
+		{"provider no trigger uses", "X-Ops-Key", "k2", http.StatusOK, "ops"},
+		{"wrong key", DefaultAPIKeyHeader, "k2", http.StatusUnauthorized, ""},
+		{"no credentials", "", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotPrincipal = nil
+			req := httptest.NewRequest("POST", "/threads/t1/respond", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.key)
+			}
+			rec := httptest.NewRecorder()
+			policy.WrapAny(next).ServeHTTP(rec, req)
+
+			if rec.Code != tc.code {
+				t.Fatalf("Expected %d, got %d: %s", tc.code, rec.Code, rec.Body.String())
+			}
+			if tc.subject != "" && (gotPrincipal == nil || gotPrincipal.Subject != tc.subject) {
+				t.Errorf("Expected subject %q, got %+v", tc.subject, gotPrincipal)
+			}
+		})
+	}
+
+	t.Run("no providers", func(t *testing.T) {
+		open, err := NewPolicy(nil, "")
+		if err != nil {
+			t.Fatalf("NewPolicy failed: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		open.WrapAny(next).ServeHTTP(rec, httptest.NewRequest("POST", "/threads/t1/respond", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 without providers, got %d", rec.Code)
+		}
+	})
+}
+
+// readRecorder is a request body that records whether it was read
+type readRecorder struct {
+	io.Reader
+	read bool
+}
+
+func (r *readRecorder) Read(p []byte) (int, error) {
+	r.read = true
+	return r.Reader.Read(p)
+}
+
+func TestWrapBody(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "keys.json", `{"partner": "k1"}`)
+	t.Setenv("TEST_SHUTTL_SECRET", "s3cret")
+	policy, err := NewPolicy(&config.AuthConfig{
+		Providers: map[string]config.AuthProviderConfig{
+			"keys":  {Type: TypeAPIKey, KeysFile: "keys.json"},
+			"hooks": {Type: TypeHMAC, SecretEnv: "TEST_SHUTTL_SECRET"},
+		},
+		Default:      []string{"keys"},
+		Agents:       map[string]config.AgentAuthConfig{"Hooks": {Providers: []string{"hooks"}}},
+		MaxBodyBytes: 16,
+	}, dir)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	var gotBody string
+	var readErr error
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		gotBody, readErr = string(body), err
+	})
+	signed := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/Hooks/api", strings.NewReader(body))
+		timestamp := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(DefaultSignatureHeader, Sign([]byte("s3cret"), timestamp, "POST", "/Hooks/api", []byte(body)))
+		return req
+	}
+
+	t.Run("not read before rejecting", func(t *testing.T) {
+		body := &readRecorder{Reader: strings.NewReader(`{"hello":1}`)}
+		rec := httptest.NewRecorder()
+		policy.Wrap("A", "api", next).ServeHTTP(rec, httptest.NewRequest("POST", "/A/api", body))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401, got %d", rec.Code)
+		}
+		if body.read {
+			t.Error("Expected the body not to be read for API key auth")
+		}
+	})
+
+	t.Run("limited for the handler", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/A/api", strings.NewReader(`{"hello":"world!!"}`))
+		req.Header.Set(DefaultAPIKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		policy.Wrap("A", "api", next).ServeHTTP(rec, req)
+
+		var tooLarge *http.MaxBytesError
+		if !errors.As(readErr, &tooLarge) || tooLarge.Limit != 16 {
+			t.Errorf("Expected the handler to hit the 16 byte limit, got %v", readErr)
+		}
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		policy.Wrap("Hooks", "api", next).ServeHTTP(rec, signed(`{"hello":1}`))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if gotBody != `{"hello":1}` || readErr != nil {
+			t.Errorf("Expected the verified body to reach the handler, got %q, %v", gotBody, readErr)
+		}
+	})
+
+	t.Run("signed too large", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		policy.Wrap("Hooks", "api", next).ServeHTTP(rec, signed(`{"hello":"world!!"}`))
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestAPIKeysFromEnv(t *testing.T) {
+	t.Setenv("TEST_SHUTTL_KEYS", "ci=abc, deploy=def")
+	auth, err := newAPIKeyAuthenticator(config.AuthProviderConfig{Type: TypeAPIKey, KeysEnv: "TEST_SHUTTL_KEYS", Header: "Authorization-Key"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization-Key", "def")
+	principal, err := auth.Authenticate(req, nil)
+	if err != nil || principal.Subject != "deploy" {
+		t.Errorf("Expected subject deploy, got %+v, %v", principal, err)
+	}
+}
@@ -0,0 +1,62 @@
+package httpauth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuttl-ai/cli/config"
+)
+
+func TestHMACAuthenticate(t *testing.T) {
+	t.Setenv("TEST_SHUTTL_SECRET", "s3cret")
+	auth, err := newHMACAuthenticator("partners", config.AuthProviderConfig{Type: TypeHMAC, SecretEnv: "TEST_SHUTTL_SECRET", Window: "1m"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	auth.now = func() time.Time { return now }
+
+	body := []byte(`{"order":42}`)
+	signed := func(timestamp int64, signature string) error {
+		req := httptest.NewRequest("POST", "/orders/42?dry=1", nil)
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(DefaultSignatureHeader, signature)
+		_, err := auth.Authenticate(req, body)
+		return err
+	}
+	valid := Sign([]byte("s3cret"), now.Unix(), "POST", "/orders/42?dry=1", body)
+
+	if err := signed(now.Unix(), valid); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+	if err := signed(now.Unix(), "sha256="+valid); err != nil {
+		t.Errorf("Expected the sha256= prefix to be accepted, got %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		timestamp int64
+		signature string
+		substr    string
+	}{
+		{"wrong secret", now.Unix(), Sign([]byte("other"), now.Unix(), "POST", "/orders/42?dry=1", body), "invalid signature"},
+		{"other path", now.Unix(), Sign([]byte("s3cret"), now.Unix(), "POST", "/orders/43", body), "invalid signature"},
+		{"too old", now.Add(-2 * time.Minute).Unix(), Sign([]byte("s3cret"), now.Add(-2*time.Minute).Unix(), "POST", "/orders/42?dry=1", body), "outside the 1m0s window"},
+		{"not hex", now.Unix(), "xyz", "expected hex"},
+	}
+	for _, tc := range testCases {
+		err := signed(tc.timestamp, tc.signature)
+		if err == nil || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.substr, err)
+		}
+	}
+
+	unsigned := httptest.NewRequest("POST", "/orders/42", nil)
+	if _, err := auth.Authenticate(unsigned, body); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials for an unsigned request, got %v", err)
+	}
+}
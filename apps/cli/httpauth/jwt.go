@@ -0,0 +1,193 @@
+package httpauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shuttl-ai/cli/config"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// JWTLeeway is the clock skew allowed when checking exp and nbf
+const JWTLeeway = 30 * time.Second
+
+// jwtMethods are the signing algorithms accepted for tokens. Only public key
+// algorithms make sense for keys read from a JWKS.
+var jwtMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// jwtAuthenticator accepts bearer tokens signed by a key in a local JWKS
+type jwtAuthenticator struct {
+	keys    map[string]any
+	options []jwt.ParserOption
+}
+
+func newJWTAuthenticator(cfg config.AuthProviderConfig, baseDir string) (*jwtAuthenticator, error) {
+	if cfg.JWKSFile == "" {
+		return nil, errors.New("jwt providers need jwksFile")
+	}
+	keys, err := loadJWKS(resolvePath(baseDir, cfg.JWKSFile))
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(JWTLeeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audience) > 0 {
+		options = append(options, jwt.WithAudience(cfg.Audience...))
+	}
+	return &jwtAuthenticator{keys: keys, options: options}, nil
+}
+
+// Authenticate verifies the bearer token in the Authorization header. The
+// header is removed from the request once verified so that the token is not
+// passed on to the app.
+func (a *jwtAuthenticator) Authenticate(r *http.Request, body []byte) (*ipc.Principal, error) {
+	scheme, tokenString, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(strings.TrimSpace(tokenString), claims, a.keyFunc, a.options...); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	r.Header.Del("Authorization")
+	subject, _ := claims.GetSubject()
+	return &ipc.Principal{Subject: subject, Claims: claims}, nil
+}
+
+// keyFunc picks the verification key by the token's "kid". Tokens without a
+// kid are checked against every key.
+func (a *jwtAuthenticator) keyFunc(token *jwt.Token) (any, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	}
+
+	set := jwt.VerificationKeySet{}
+	for _, key := range a.keys {
+		set.Keys = append(set.Keys, key)
+	}
+	return set, nil
+}
+
+// jwk is a JSON Web Key as found in a JWKS file
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the public keys in a JWKS file, indexed by key ID. Keys
+// without an ID are indexed by their position.
+func loadJWKS(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", path, err)
+	}
+
+	keys := make(map[string]any)
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS file %s: key %d: %w", path, i, err)
+		}
+		id := k.Kid
+		if id == "" {
+			id = fmt.Sprintf("#%d", i)
+		}
+		keys[id] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no signing keys", path)
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := decodeBase64URL(k.N)
+		e, errE := decodeBase64URL(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return nil, errors.New("invalid RSA key: n and e must be base64url")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, errX := decodeBase64URL(k.X)
+		y, errY := decodeBase64URL(k.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("invalid EC key: x and y must be base64url")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC key: point is not on the curve")
+		}
+		return key, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Crv)
+		}
+		x, err := decodeBase64URL(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,116 @@
+package httpauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shuttl-ai/cli/config"
+)
+
+func TestJWTAuthenticate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	dir := t.TempDir()
+	writeFile(t, dir, "jwks.json", fmt.Sprintf(`{"keys": [{"kty": "EC", "kid": "k1", "crv": "P-256", "x": %q, "y": %q}]}`,
+		encode(key.X.FillBytes(make([]byte, 32))), encode(key.Y.FillBytes(make([]byte, 32)))))
+
+	auth, err := newJWTAuthenticator(config.AuthProviderConfig{
+		Type:     TypeJWT,
+		JWKSFile: "jwks.json",
+		Issuer:   "https://issuer.example",
+		Audience: []string{"shuttl"},
+	}, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sign := func(signer *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(signer)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signed
+	}
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "user-1",
+			"iss": "https://issuer.example",
+			"aud": "shuttl",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	authenticate := func(token string) error {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		principal, err := auth.Authenticate(req, nil)
+		if err == nil && principal.Subject != "user-1" {
+			t.Errorf("Expected subject user-1, got %q", principal.Subject)
+		}
+		if err == nil && req.Header.Get("Authorization") != "" {
+			t.Error("Expected the Authorization header to be removed")
+		}
+		return err
+	}
+
+	if err := authenticate(sign(key, claims(nil))); err != nil {
+		t.Errorf("Expected a valid token, got %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		token  string
+		substr string
+	}{
+		{"wrong key", sign(other, claims(nil)), "signature is invalid"},
+		{"expired", sign(key, claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })), "expired"},
+		{"no expiry", sign(key, claims(func(c jwt.MapClaims) { delete(c, "exp") })), "exp claim is required"},
+		{"wrong issuer", sign(key, claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example" })), "invalid issuer"},
+		{"wrong audience", sign(key, claims(func(c jwt.MapClaims) { c["aud"] = "other" })), "invalid audience"},
+	}
+	for _, tc := range testCases {
+		err := authenticate(tc.token)
+		if err == nil || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.substr, err)
+		}
+	}
+}
+
+func TestLoadJWKSErrors(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		content string
+		substr  string
+	}{
+		{`{"keys": []}`, "no signing keys"},
+		{`{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`, `unsupported key type "oct"`},
+		{`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`, "not on the curve"},
+		{`not json`, "failed to parse JWKS file"},
+	}
+	for i, tc := range testCases {
+		name := fmt.Sprintf("jwks%d.json", i)
+		writeFile(t, dir, name, tc.content)
+		_, err := loadJWKS(dir + "/" + name)
+		if err == nil || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("loadJWKS(%s): expected error containing %q, got %v", tc.content, tc.substr, err)
+		}
+	}
+}
@@ -30,10 +30,36 @@ type ToolCallResult struct {
 }
 
 // ToolCallCompletedResult (type: "tool_calls_completed")
-type ToolCallCompletedResult []struct {
-	Output string `json:"output"`
-	Type   string `json:"type"`
-	CallID string `json:"call_id"`
+type ToolCallCompletedResult []ToolCallOutput
+
+// ToolCallOutput is the result of one tool call. Apps can set is_error to
+// report a failed call.
+type ToolCallOutput struct {
+	Output  string `json:"output"`
+	Type    string `json:"type"`
+	CallID  string `json:"call_id"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Failed reports whether the tool call failed: either the app said so, or
+// the output is an object with an "error" field, which is what tools that
+// catch their own errors usually return
+func (o ToolCallOutput) Failed() bool {
+	if o.IsError {
+		return true
+	}
+	output := json.RawMessage(o.Output)
+	// Outputs are often JSON encoded twice
+	var encoded string
+	if err := json.Unmarshal(output, &encoded); err == nil {
+		output = json.RawMessage(encoded)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(output, &fields); err != nil {
+		return false
+	}
+	value, ok := fields["error"]
+	return ok && string(value) != "null" && string(value) != "false" && string(value) != `""`
 }
 
 // TextDeltaResult (type: "output_text_delta")
@@ -67,6 +93,7 @@ type ChatParsedResult struct {
 	FinalOutput        *FinalOutputResult
 	Status             *StatusResult
 	ResponseRequested  *json.RawMessage
+	InputRequest       *InputRequest // Set when response.requested asks the user for input
 }
 
 func (c ChatParsedResult) String() string {
@@ -149,6 +176,7 @@ func parseResult(msgType MessageType, raw json.RawMessage) (*ChatParsedResult, e
 		result.FinalOutput = &output
 	case "response.requested":
 		result.ResponseRequested = &raw
+		result.InputRequest = ParseInputRequest(raw)
 	default:
 		// Try to parse as status
 		var status StatusResult
@@ -164,44 +192,83 @@ func (c *Client) StartChat(ctx context.Context, agentID string, message string)
 }
 
 func (c *Client) StartChatWithAttachments(ctx context.Context, agentID string, message string, attachments []FileAttachment) (chan *ChatParsedResult, chan error) {
+	return c.StartChatInThread(ctx, agentID, "", message, attachments)
+}
+
+// StartChatInThread sends a prompt to an agent, continuing the thread with
+// threadID. An empty threadID starts a new thread, whose ID is reported in a
+// status result. Attachments with a path are read when the prompt is sent,
+// or passed by path to apps that read them themselves.
+func (c *Client) StartChatInThread(ctx context.Context, agentID string, threadID string, message string, attachments []FileAttachment) (chan *ChatParsedResult, chan error) {
 	id := getID(RequestChat)
+	parsedResultCh := make(chan *ChatParsedResult, 10)
+	errOut := make(chan error, 1)
+	attachments, err := c.prepareAttachments(attachments)
+	if err != nil {
+		close(parsedResultCh)
+		errOut <- err
+		return parsedResultCh, errOut
+	}
+	body := ChatRequest{Agent: agentID, Prompt: message, Attachments: attachments}
+	if threadID != "" {
+		body.ThreadID = &threadID
+	}
 	req := Request{
 		ID:     id,
 		Method: "invokeAgent",
-		Body:   ChatRequest{Agent: agentID, Prompt: message, Attachments: attachments},
+		Body:   body,
 	}
-	log.Error("Starting chat with attachments: %v prompt: %s", attachments, message)
-	parsedResultCh := make(chan *ChatParsedResult, 10)
+	log.Debug("Starting chat with %d attachments, prompt: %s", len(attachments), message)
 	errCh, resultCh := c.SendAsyncWithResult(ctx, req)
 	go func() {
 		defer close(parsedResultCh)
-		defer c.CloseSpecialChannel(id)
+		defer c.ReleaseRequest(id)
 		for {
 			select {
 			case <-ctx.Done():
-				errCh <- ctx.Err()
+				errOut <- ctx.Err()
+				return
+			case err, ok := <-errCh:
+				if !ok {
+					errCh = nil
+					continue
+				}
+				errOut <- err
 				return
 			case result, ok := <-resultCh:
 				if !ok {
-					errCh <- fmt.Errorf("result channel closed")
+					if err := requestError(errCh); err != nil {
+						errOut <- err
+						return
+					}
+					errOut <- fmt.Errorf("result channel closed")
+					return
+				}
+				if !result.Message.Success && result.Message.ErrorObj != nil {
+					errOut <- result.Message.ErrorObj
 					return
 				}
 
 				parsed, err := parseResult(result.Message.Type, result.Message.Result)
 				if err != nil {
 					log.Error("Failed to parse result: %v", err)
-					errCh <- err
+					errOut <- err
 					return
 				}
 
-				parsedResultCh <- parsed
+				select {
+				case parsedResultCh <- parsed:
+				case <-ctx.Done():
+					errOut <- ctx.Err()
+					return
+				}
 				if parsed.Status != nil && parsed.Status.Status == "completed" {
 					return
 				}
 			}
 		}
 	}()
-	return parsedResultCh, nil
+	return parsedResultCh, errOut
 }
 
 func (c *Client) SendMessage(ctx context.Context, agentID string, threadID string, message string) (string, error) {
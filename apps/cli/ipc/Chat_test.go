@@ -0,0 +1,27 @@
+package ipc
+
+import "testing"
+
+func TestToolCallOutputFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		output ToolCallOutput
+		want   bool
+	}{
+		{"plain output", ToolCallOutput{Output: `"42 results"`}, false},
+		{"object output", ToolCallOutput{Output: `{"results":[1,2]}`}, false},
+		{"not json", ToolCallOutput{Output: `done`}, false},
+		{"is_error", ToolCallOutput{Output: `"timed out"`, IsError: true}, true},
+		{"error field", ToolCallOutput{Output: `{"error":"not found"}`}, true},
+		{"error field encoded twice", ToolCallOutput{Output: `"{\"error\":{\"code\":404}}"`}, true},
+		{"null error field", ToolCallOutput{Output: `{"error":null,"ok":true}`}, false},
+		{"false error field", ToolCallOutput{Output: `{"error":false}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.output.Failed(); got != tt.want {
+				t.Errorf("Failed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package ipc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CapabilityAttachmentPaths is listed by apps that read attachments sent with
+// a path and no content themselves
+const CapabilityAttachmentPaths = "attachment_paths"
+
+// prepareAttachments decides how attachments travel to the app. An app the
+// client started shares its filesystem, so if it can read attachments by path
+// it is sent only their absolute paths. Others get the content inline, read
+// from the path if the attachment has none yet.
+func (c *Client) prepareAttachments(attachments []FileAttachment) ([]FileAttachment, error) {
+	_, local := c.transport.(*StdioTransport)
+	byPath := local && c.AppSupports(CapabilityAttachmentPaths)
+
+	prepared := make([]FileAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		switch {
+		case attachment.Path == "":
+		case byPath:
+			path, err := filepath.Abs(attachment.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve attachment %s: %w", attachment.Name, err)
+			}
+			attachment.Path = path
+			attachment.Content = ""
+		case attachment.Content == "":
+			data, err := os.ReadFile(attachment.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment %s: %w", attachment.Name, err)
+			}
+			attachment.Content = base64.StdEncoding.EncodeToString(data)
+		}
+		prepared = append(prepared, attachment)
+	}
+	return prepared, nil
+}
@@ -0,0 +1,54 @@
+package ipc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareAttachments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.7"), 0o644); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+	attachments := []FileAttachment{
+		{Name: "report.pdf", Path: path, MimeType: "application/pdf"},
+		{Name: "inline.txt", Content: "aGk="},
+	}
+
+	testCases := []struct {
+		name         string
+		client       *Client
+		capabilities []string
+		content      string
+	}{
+		{"app without path support", NewClient([]string{"app"}), nil, "JVBERi0xLjc="},
+		{"app reading paths", NewClient([]string{"app"}), []string{CapabilityAttachmentPaths}, ""},
+		{"attached app reading paths", NewClientWithTransport(&SocketTransport{Network: "tcp", Address: "localhost:7000"}), []string{CapabilityAttachmentPaths}, "JVBERi0xLjc="},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.client.capabilities = tc.capabilities
+			prepared, err := tc.client.prepareAttachments(attachments)
+			if err != nil {
+				t.Fatalf("prepareAttachments failed: %v", err)
+			}
+			if prepared[0].Content != tc.content || prepared[0].Path != path {
+				t.Errorf("Unexpected attachment %+v", prepared[0])
+			}
+			if prepared[1] != attachments[1] {
+				t.Errorf("Inline attachment changed to %+v", prepared[1])
+			}
+		})
+	}
+
+	if attachments[0].Content != "" {
+		t.Error("prepareAttachments should not modify its argument")
+	}
+	missing := []FileAttachment{{Name: "gone.png", Path: filepath.Join(dir, "gone.png")}}
+	if _, err := NewClient([]string{"app"}).prepareAttachments(missing); err == nil {
+		t.Error("Expected an error for a missing attachment")
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/shuttl-ai/cli/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MessageType represents the type of IPC message
@@ -72,24 +73,23 @@ const (
 	StateRunning
 	StateStopping
 	StateStopped
+	StateRestarting
+	StateCrashed
 )
 
 // Client manages IPC communication with a Shuttl application
-type SpecialChannel struct {
-	outputChan chan OutputLine
-	errChan    chan error
-}
-
 type Client struct {
-	command []string
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	stdout  io.ReadCloser
-	stderr  io.ReadCloser
+	command   []string
+	transport Transport
+	conn      Conn
+	procMu    sync.RWMutex
+	// Why the output of conn could not be read; see readOutput
+	readErr error
 
 	// Channels for output
 	outputChan chan OutputLine
 	errChan    chan error
+	logChan    chan LogEvent
 
 	// State management
 	state   ClientState
@@ -103,32 +103,108 @@ type Client struct {
 	wg sync.WaitGroup
 
 	// Mutex for sending messages
-	sendMu            sync.Mutex
-	specialChannels   map[string]*SpecialChannel
-	specialChannelsMu sync.RWMutex
+	sendMu sync.Mutex
+
+	// Requests waiting for replies, keyed by request ID
+	pending        *pendingTable
+	requestTimeout time.Duration
+
+	// Ready handshake; see handshake.go
+	handshakeTimeout time.Duration
+	protocolVersion  string
+	capabilities     []string
+
+	// Restarts the app after crashes; nil unless a restart policy is set
+	supervisor *supervisor
+
+	// Reload state; see reload.go
+	holdOnCrash bool
+	reloadDone  chan error
+	reloadCh    chan chan error
 
 	// Stderr buffer for capturing subprocess stderr output
 	stderrBuffer   []string
 	stderrBufferMu sync.Mutex
+
+	// Messages dropped by reason; see stats.go
+	dropped dropCounters
+
+	// Records the traffic with the app; nil unless set, see record.go
+	recorder *Recorder
+
+	// Creates the IPC spans; see trace.go
+	tracer trace.Tracer
 }
 
 // NewClient creates a new IPC client for the given command and arguments
 func NewClient(command []string) *Client {
+	client := NewClientWithTransport(&StdioTransport{Command: command})
+	client.command = command
+	return client
+}
+
+// NewClientWithTransport creates a new IPC client talking to the app over a
+// transport, such as a socket of an app that is already running
+func NewClientWithTransport(transport Transport) *Client {
 	ctx, cancel := context.WithCancelCause(context.Background())
 
 	return &Client{
-		command:         command,
-		outputChan:      make(chan OutputLine, 100),
-		errChan:         make(chan error, 10),
-		state:           StateIdle,
-		ctx:             ctx,
-		cancel:          cancel,
-		specialChannels: make(map[string]*SpecialChannel),
-		stderrBuffer:    make([]string, 0),
+		transport:      transport,
+		outputChan:     make(chan OutputLine, 100),
+		errChan:        make(chan error, 10),
+		logChan:        make(chan LogEvent, 100),
+		state:          StateIdle,
+		ctx:            ctx,
+		cancel:         cancel,
+		pending:        newPendingTable(),
+		reloadCh:       make(chan chan error, 1),
+		requestTimeout: DefaultRequestTimeout,
+		stderrBuffer:   make([]string, 0),
+
+		handshakeTimeout: DefaultHandshakeTimeout,
+		tracer:           otel.Tracer(tracerName),
 	}
 }
 
-// Start starts the Shuttl application subprocess
+// SetRequestTimeout sets how long a request may wait without receiving a
+// message before it fails. Zero disables the timeout; a deadline on the
+// request's context still applies.
+func (c *Client) SetRequestTimeout(timeout time.Duration) {
+	c.requestTimeout = timeout
+}
+
+// SetRestartPolicy enables supervision: when the app exits unexpectedly it is
+// restarted according to the policy instead of stopping the client. Must be
+// called before Start.
+func (c *Client) SetRestartPolicy(policy RestartPolicy) {
+	c.supervisor = newSupervisor(policy)
+}
+
+// Status returns the state of the app process and its restart history
+func (c *Client) Status() ProcessStatus {
+	status := ProcessStatus{
+		State:           c.State().String(),
+		PID:             c.ProcessID(),
+		ProtocolVersion: c.AppProtocolVersion(),
+	}
+	if c.supervisor != nil {
+		c.supervisor.fill(&status)
+	}
+	return status
+}
+
+// Restarts returns how many times the supervisor has restarted the app
+func (c *Client) Restarts() int {
+	return c.Status().Restarts
+}
+
+// PendingRequests returns the number of requests waiting for replies
+func (c *Client) PendingRequests() int {
+	return c.pending.len()
+}
+
+// Start starts the Shuttl application subprocess and waits for it to complete
+// the ready handshake (see SetHandshakeTimeout)
 func (c *Client) Start() error {
 	c.stateMu.Lock()
 	if c.state != StateIdle && c.state != StateStopped {
@@ -138,179 +214,429 @@ func (c *Client) Start() error {
 	c.state = StateRunning
 	c.stateMu.Unlock()
 
-	if len(c.command) == 0 {
+	conn, readers, err := c.spawn()
+	if err != nil {
 		c.setState(StateStopped)
-		return fmt.Errorf("no command specified")
+		return err
+	}
+
+	// Start process monitor goroutine
+	c.wg.Add(1)
+	go c.monitorProcess(conn, readers)
+
+	// Wait until the app answers before letting callers send requests
+	if c.handshakeTimeout > 0 {
+		ctx, cancel := context.WithTimeout(c.ctx, c.handshakeTimeout)
+		defer cancel()
+		if _, err := c.Handshake(ctx); err != nil {
+			c.Kill()
+			return fmt.Errorf("app handshake failed: %w", err)
+		}
 	}
 
-	// Create the command with arguments
-	c.cmd = exec.CommandContext(c.ctx, c.command[0], c.command[1:]...)
-	c.cmd.Env = append(os.Environ(), "_SHUTTL_CONTROL=true")
+	return nil
+}
 
-	// Get pipes
-	var err error
-	c.stdin, err = c.cmd.StdinPipe()
+// spawn starts or connects to the app and the goroutines reading its output
+func (c *Client) spawn() (Conn, *sync.WaitGroup, error) {
+	conn, err := c.transport.Open(c.ctx)
 	if err != nil {
-		c.setState(StateStopped)
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
+		return nil, nil, err
 	}
 
-	c.stdout, err = c.cmd.StdoutPipe()
-	if err != nil {
-		c.setState(StateStopped)
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	c.stderrBufferMu.Lock()
+	c.stderrBuffer = c.stderrBuffer[:0]
+	c.stderrBufferMu.Unlock()
+
+	c.sendMu.Lock()
+	c.procMu.Lock()
+	c.conn = conn
+	c.readErr = nil
+	c.procMu.Unlock()
+	c.sendMu.Unlock()
+
+	// What the previous app reported in its handshake does not carry over
+	c.stateMu.Lock()
+	c.protocolVersion = ""
+	c.capabilities = nil
+	c.stateMu.Unlock()
+
+	// Start reading goroutines
+	readers := &sync.WaitGroup{}
+	readers.Add(1)
+	go c.readOutput(readers, conn)
+	if stderr := conn.Stderr(); stderr != nil {
+		readers.Add(1)
+		go c.readStderr(readers, stderr)
 	}
 
-	c.stderr, err = c.cmd.StderrPipe()
-	if err != nil {
-		c.setState(StateStopped)
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
+	return conn, readers, nil
+}
+
+// connect spawns a new app for a restart or reload and completes the ready
+// handshake before the connection goes live. The client stays in
+// StateRestarting meanwhile, so only the handshake pings reach the app.
+// monitorProcess is not yet watching the connection, so the app exiting
+// during the handshake is detected here and fails it at once.
+func (c *Client) connect() (Conn, *sync.WaitGroup, error) {
+	conn, readers, err := c.spawn()
+	if err != nil || c.handshakeTimeout <= 0 {
+		return conn, readers, err
 	}
 
-	// Start the process
-	if err := c.cmd.Start(); err != nil {
-		c.setState(StateStopped)
-		return fmt.Errorf("failed to start process: %w", err)
+	ctx, cancel := context.WithTimeout(c.ctx, c.handshakeTimeout)
+	defer cancel()
+	exited := make(chan struct{})
+	go func() {
+		readers.Wait()
+		close(exited)
+		cancel()
+	}()
+
+	_, err = c.handshake(ctx, c.write)
+	if err == nil {
+		return conn, readers, nil
 	}
 
-	// Start reading goroutines
-	c.wg.Add(2)
-	go c.readOutput(c.stdout, "stdout")
-	go c.readOutput(c.stderr, "stderr")
+	appExited := false
+	select {
+	case <-exited:
+		appExited = true
+	default:
+	}
+	conn.Kill()
+	readers.Wait()
+	waitErr := conn.Wait()
+	if readErr := c.takeReadError(); readErr != nil {
+		waitErr = readErr
+	}
+	if appExited {
+		c.logStderr()
+		err = &ProcessCrashedError{PID: conn.PID(), Err: waitErr}
+	}
+	return nil, nil, fmt.Errorf("app handshake failed: %w", err)
+}
 
-	// Start process monitor goroutine
-	c.wg.Add(1)
-	go c.monitorProcess()
+// readOutput reads the messages the app writes and routes them. A message
+// that cannot be framed ends the connection, since the rest of the output
+// cannot be read; the error is reported as the cause of the crash.
+func (c *Client) readOutput(readers *sync.WaitGroup, conn Conn) {
+	defer readers.Done()
 
-	return nil
+	messages := NewMessageReader(conn.Output())
+	for {
+		data, err := messages.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && c.ctx.Err() == nil {
+				err = fmt.Errorf("failed to read message from app: %w", err)
+				c.reportError(err)
+				c.setReadError(conn, err)
+				conn.Kill()
+			}
+			return
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.handleMessage(data)
+	}
 }
 
-// readOutput reads lines from a pipe and sends them to the output channel
-func (c *Client) readOutput(pipe io.ReadCloser, source string) {
-	defer c.wg.Done()
+// handleMessage parses a message from the app and delivers it
+func (c *Client) handleMessage(data []byte) {
+	line := string(data)
+	log.DebugWithPrefix("IPC", "Received message: %s", line)
 
-	scanner := bufio.NewScanner(pipe)
-	// Increase buffer size for large messages
-	buf := make([]byte, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	output := OutputLine{
+		Source:    "stdout",
+		Content:   line,
+		Timestamp: time.Now(),
+	}
 
-	for scanner.Scan() {
-		select {
-		case <-c.ctx.Done():
+	// Try to parse as JSON message
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		output.Err = err
+		c.reportError(err)
+		c.emit(output)
+		return
+	}
+	output.Message = &msg
+	if c.recorder != nil {
+		c.recorder.recordMessage(line, output.Timestamp)
+	}
+	if isLogEvent(&msg) {
+		event, err := parseLogEvent(&msg, output.Timestamp)
+		if err != nil {
+			c.reportError(fmt.Errorf("invalid log event: %w", err))
 			return
-		default:
-			line := scanner.Text()
-			log.DebugWithPrefix("IPC", "Received line from %s: %s", source, line)
+		}
+		c.emitLog(event)
+		return
+	}
+	c.route(output)
+}
+
+// readStderr reads the app's stderr line by line as log output
+func (c *Client) readStderr(readers *sync.WaitGroup, pipe io.Reader) {
+	defer readers.Done()
+
+	reader := bufio.NewReader(pipe)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" && c.ctx.Err() == nil {
+			line = strings.TrimRight(line, "\r\n")
+			log.DebugWithPrefix("IPC", "Received line from stderr: %s", line)
 
 			// Collect stderr lines into the buffer for later display if process exits early
-			if source == "stderr" {
-				c.stderrBufferMu.Lock()
-				c.stderrBuffer = append(c.stderrBuffer, line)
-				c.stderrBufferMu.Unlock()
-			}
+			c.stderrBufferMu.Lock()
+			c.stderrBuffer = append(c.stderrBuffer, line)
+			c.stderrBufferMu.Unlock()
 
-			output := OutputLine{
-				Source:    source,
-				Content:   line,
-				Timestamp: time.Now(),
-			}
+			c.emitLog(stderrLogEvent(line, time.Now()))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
 
-			// Try to parse as JSON message
-			var msg Message
-			err := json.Unmarshal([]byte(line), &msg)
-			if err == nil {
-				output.Message = &msg
-			}
+// setReadError records why the app's output could not be read, if conn is
+// still the current connection
+func (c *Client) setReadError(conn Conn, err error) {
+	c.procMu.Lock()
+	defer c.procMu.Unlock()
+	if c.conn == conn {
+		c.readErr = err
+	}
+}
 
-			c.specialChannelsMu.RLock()
-			channel, ok := c.specialChannels[msg.ID]
-			c.specialChannelsMu.RUnlock()
+// takeReadError returns and clears the error recorded by setReadError
+func (c *Client) takeReadError() error {
+	c.procMu.Lock()
+	defer c.procMu.Unlock()
+	err := c.readErr
+	c.readErr = nil
+	return err
+}
 
-			outputChan := c.outputChan
-			errChan := c.errChan
+// route delivers a parsed message to the request it answers. Notifications
+// go to the shared output channel; replies to requests that are not pending
+// are reported as errors.
+func (c *Client) route(output OutputLine) {
+	id := output.Message.ID
+	if isNotificationID(id) {
+		c.emit(output)
+		return
+	}
 
-			if ok {
-				outputChan = channel.outputChan
-				errChan = channel.errChan
-			}
-			if err != nil {
-				errChan <- err
-			}
+	request, late := c.pending.lookup(id)
+	if request == nil {
+		log.Warn("Dropping reply for request %s: request is not pending", id)
+		c.dropped.orphaned.Add(1)
+		c.reportError(&OrphanedResponseError{ID: id, Late: late, Output: output})
+		return
+	}
 
-			if source == "stdout" {
-				select {
-				case outputChan <- output:
-				default:
-					// Channel full, drop oldest
-					select {
-					case <-outputChan:
-					default:
-					}
-					outputChan <- output
-
-					if err := scanner.Err(); err != nil && c.ctx.Err() == nil {
-						errChan <- fmt.Errorf("%s scanner error: %w", source, err)
-					}
-				}
-			} else {
-				select {
-				case errChan <- fmt.Errorf("%s scanner error: %s", source, line):
-				default:
-				}
-			}
+	if !request.deliver(output) {
+		c.dropped.orphaned.Add(1)
+		c.reportError(&OrphanedResponseError{ID: id, Late: true, Output: output})
+	}
+}
+
+// emit sends an output line to the shared output channel, dropping the
+// oldest line if nobody is reading
+func (c *Client) emit(output OutputLine) {
+	select {
+	case c.outputChan <- output:
+	default:
+		select {
+		case <-c.outputChan:
+			c.dropped.outputFull.Add(1)
+		default:
+		}
+		select {
+		case c.outputChan <- output:
+		default:
+			c.dropped.outputFull.Add(1)
 		}
 	}
+}
 
+// reportError sends an error to the shared error channel without blocking
+func (c *Client) reportError(err error) {
+	select {
+	case c.errChan <- err:
+	default:
+		c.dropped.errorFull.Add(1)
+		log.Debug("Error channel full, dropping error: %v", err)
+	}
 }
 
-// monitorProcess monitors the subprocess and handles cleanup
-func (c *Client) monitorProcess() {
+// monitorProcess waits for the app to exit or disconnect, restarts it if the
+// client is supervised and closes the output channels once the client stops
+func (c *Client) monitorProcess(conn Conn, readers *sync.WaitGroup) {
 	defer c.wg.Done()
 	defer func() {
-		c.wg.Wait()
 		log.Info("Closing output and error channels")
+		c.pending.failAll(fmt.Errorf("client stopped"))
 		close(c.outputChan)
 		close(c.errChan)
-		for key := range c.specialChannels {
-			c.CloseSpecialChannel(key)
-		}
+		close(c.logChan)
 	}()
 
-	err := c.cmd.Wait()
-	c.setState(StateStopped)
+	for {
+		// Drain the pipes before Wait closes them
+		readers.Wait()
+		err := conn.Wait()
+		if readErr := c.takeReadError(); readErr != nil {
+			err = readErr
+		}
+
+		if c.stopRequested() {
+			c.setState(StateStopped)
+			return
+		}
+
+		var ok bool
+
+		// Reload asked the process to exit, so start the new one right away
+		if done := c.takeReload(); done != nil {
+			c.pending.failAll(ErrAppReloaded)
+			conn, readers, ok = c.respawn(done)
+			if !ok {
+				return
+			}
+			continue
+		}
 
-	if err != nil && c.ctx.Err() == nil {
 		// Output the error and stderr to the user
 		log.Error("Subprocess exited unexpectedly: %v", err)
+		c.logStderr()
+
+		crash := &ProcessCrashedError{PID: conn.PID(), Err: err, Restarting: c.supervisor != nil}
+		conn, readers, ok = c.recover(crash)
+		if !ok {
+			return
+		}
+	}
+}
+
+// recover handles an app process that is gone: supervised clients restart it,
+// clients holding on crash wait for Reload and all others stop
+func (c *Client) recover(crash error) (Conn, *sync.WaitGroup, bool) {
+	switch {
+	case c.supervisor != nil:
+		c.setState(StateRestarting)
+		c.pending.failAll(crash)
+		return c.restart(crash)
+	case c.holdOnCrash:
+		c.setState(StateCrashed)
+		c.pending.failAll(crash)
+		return c.awaitReload()
+	default:
+		c.setState(StateStopped)
+		c.pending.failAll(crash)
+		c.cancel(fmt.Errorf("subprocess exited unexpectedly: %w", crash))
+		return nil, nil, false
+	}
+}
+
+// restart waits out the backoff and starts a new app process, retrying until
+// a process starts, the restart policy gives up or the client is stopped
+func (c *Client) restart(crash error) (Conn, *sync.WaitGroup, bool) {
+	for {
+		delay, err := c.supervisor.crashed(crash, time.Now())
+		if err != nil {
+			log.Error("Not restarting app: %v", err)
+			c.setState(StateStopped)
+			c.cancel(err)
+			return nil, nil, false
+		}
+
+		log.Warn("Restarting app in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			c.setState(StateStopped)
+			return nil, nil, false
+		}
+
+		// Stop or Kill may have been called while waiting
+		if c.State() != StateRestarting {
+			c.setState(StateStopped)
+			return nil, nil, false
+		}
 
-		// Print the stderr output if we collected any
-		c.stderrBufferMu.Lock()
-		if len(c.stderrBuffer) > 0 {
-			log.Error("Subprocess stderr output:\n")
-			log.Error("----------------------------------------\n")
-			for _, line := range c.stderrBuffer {
-				log.Error("%s\n", line)
+		conn, readers, err := c.connect()
+		if err == nil {
+			// Stop may have been called during the handshake; monitorProcess
+			// then sees the connection end
+			c.stateMu.Lock()
+			if c.state == StateRestarting {
+				c.state = StateRunning
 			}
-			log.Error("----------------------------------------\n")
+			c.stateMu.Unlock()
+
+			c.supervisor.restarted(time.Now())
+			log.Info("Restarted app (%s, restart %d)", c.describe(conn), c.Restarts())
+			c.emit(OutputLine{
+				Source:    "supervisor",
+				Content:   "app restarted",
+				Message:   &Message{ID: RestartedNotificationID, Timestamp: time.Now()},
+				Timestamp: time.Now(),
+			})
+			return conn, readers, true
 		}
-		c.stderrBufferMu.Unlock()
-		c.cancel(fmt.Errorf("subprocess exited unexpectedly: %w", err))
-		return
+
+		if c.stopRequested() {
+			c.setState(StateStopped)
+			return nil, nil, false
+		}
+		log.Error("Failed to restart app: %v", err)
+		crash = err
 	}
+}
 
-	// Close the output channel after process exits
+// stopRequested reports whether the process exited because of Stop or Kill
+func (c *Client) stopRequested() bool {
+	return c.ctx.Err() != nil || c.State() == StateStopping
+}
 
+// logStderr prints the stderr collected from the exited process
+func (c *Client) logStderr() {
+	c.stderrBufferMu.Lock()
+	defer c.stderrBufferMu.Unlock()
+	if len(c.stderrBuffer) == 0 {
+		return
+	}
+	log.Error("Subprocess stderr output:\n")
+	log.Error("----------------------------------------\n")
+	for _, line := range c.stderrBuffer {
+		log.Error("%s\n", line)
+	}
+	log.Error("----------------------------------------\n")
 }
 
 // Send sends a message to the Shuttl application (blocking)
 func (c *Client) Send(req Request) error {
 	c.stateMu.RLock()
-	if c.state != StateRunning {
-		c.stateMu.RUnlock()
+	state := c.state
+	c.stateMu.RUnlock()
+	if state == StateRestarting {
+		return ErrAppRestarting
+	}
+	if state == StateCrashed {
+		return ErrAppCrashed
+	}
+	if state != StateRunning {
 		return fmt.Errorf("client is not running")
 	}
-	c.stateMu.RUnlock()
+	return c.write(req)
+}
 
+// write sends a message to the app regardless of the client state
+func (c *Client) write(req Request) error {
 	c.sendMu.Lock()
 	defer c.sendMu.Unlock()
 
@@ -321,9 +647,11 @@ func (c *Client) Send(req Request) error {
 	}
 
 	// Write message with newline
-	_, err = c.stdin.Write(append(data, '\n'))
-	if err != nil {
-		return fmt.Errorf("failed to write to stdin: %w", err)
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if c.recorder != nil {
+		c.recorder.recordRequest(req, data)
 	}
 
 	return nil
@@ -355,37 +683,61 @@ func (c *Client) SendAsync(req Request) <-chan error {
 	return errCh
 }
 
+// SendAsyncWithResult registers the request as pending and sends it. Replies
+// are delivered on the returned output channel and failures (timeouts, send
+// errors, shutdown) on the error channel. The caller must call ReleaseRequest
+// with the request ID once it no longer needs replies.
 func (c *Client) SendAsyncWithResult(ctx context.Context, req Request) (chan error, chan OutputLine) {
-	errCh := make(chan error, 1)
-	outputChan := make(chan OutputLine, 10)
+	return c.sendWithResult(ctx, req, c.Send)
+}
 
-	specialChannels := &SpecialChannel{
-		outputChan: outputChan,
-		errChan:    errCh,
+// sendWithResult is SendAsyncWithResult with the function that writes the
+// request
+func (c *Client) sendWithResult(ctx context.Context, req Request, send func(Request) error) (chan error, chan OutputLine) {
+	timeout := c.requestTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	request, err := c.pending.register(req, timeout, 10)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		return errCh, make(chan OutputLine)
 	}
-	c.specialChannelsMu.Lock()
-	c.specialChannels[req.ID] = specialChannels
-	c.specialChannelsMu.Unlock()
+	request.startTrace(ctx, c.tracer, req)
 
-	c.Send(req)
+	if err := send(req); err != nil {
+		request.fail(err)
+	}
 
-	return errCh, outputChan
+	return request.errChan, request.outputChan
 }
 
-func (c *Client) CloseSpecialChannel(id string) {
-	c.specialChannelsMu.Lock()
-	channel, ok := c.specialChannels[id]
-	if ok {
-		close(channel.outputChan)
-		close(channel.errChan)
-		delete(c.specialChannels, id)
+// requestError returns the error a request failed with before its channels
+// were closed, such as the app crashing, or nil. Check it when the output
+// channel closes, since select may see the close before the error.
+func requestError(errCh chan error) error {
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
 	}
-	c.specialChannelsMu.Unlock()
 }
 
+// ReleaseRequest stops waiting for replies to a request and closes its
+// channels. Replies that arrive afterwards are reported as late.
+func (c *Client) ReleaseRequest(id string) {
+	c.pending.release(id)
+}
+
+// SendAndWaitForResponse sends a request and waits for its first reply
 func (c *Client) SendAndWaitForResponse(ctx context.Context, req Request) (OutputLine, error) {
 	errCh, outputChan := c.SendAsyncWithResult(ctx, req)
-	defer c.CloseSpecialChannel(req.ID)
+	defer c.ReleaseRequest(req.ID)
 
 	log.Debug("Waiting for response: %+v", req)
 	for {
@@ -394,24 +746,23 @@ func (c *Client) SendAndWaitForResponse(ctx context.Context, req Request) (Outpu
 			return OutputLine{}, ctx.Err()
 		case err, ok := <-errCh:
 			if !ok {
-				return OutputLine{}, nil
+				return OutputLine{}, fmt.Errorf("request %s closed without a response", req.ID)
 			}
 			if err != nil {
 				return OutputLine{}, err
 			}
 		case output, ok := <-outputChan:
 			if !ok {
-				return OutputLine{}, nil
+				if err := requestError(errCh); err != nil {
+					return OutputLine{}, err
+				}
+				return OutputLine{}, fmt.Errorf("request %s closed without a response", req.ID)
 			}
 			return output, nil
 		}
 	}
 }
 
-func getID(messageType MessageType) string {
-	return fmt.Sprintf("%s:%s", messageType, time.Now().Format("20060102150405"))
-}
-
 // Receive receives the next output line (blocking)
 func (c *Client) Receive() (OutputLine, error) {
 	select {
@@ -486,16 +837,25 @@ func (c *Client) Errors() <-chan error {
 // Stop gracefully stops the subprocess by closing stdin first
 func (c *Client) Stop() error {
 	c.stateMu.Lock()
-	if c.state != StateRunning {
+	state := c.state
+	if state != StateRunning && state != StateRestarting && state != StateCrashed {
 		c.stateMu.Unlock()
 		return nil
 	}
 	c.state = StateStopping
 	c.stateMu.Unlock()
 
-	// Close stdin to signal the subprocess to exit gracefully
-	if c.stdin != nil {
-		c.stdin.Close()
+	if state != StateRunning {
+		// No process to stop; wake the supervisor so it exits
+		c.cancel(nil)
+	} else {
+		// Close stdin, or the write half of a socket, to ask the app to
+		// finish gracefully
+		c.sendMu.Lock()
+		if c.conn != nil {
+			c.conn.CloseWrite()
+		}
+		c.sendMu.Unlock()
 	}
 
 	// Wait for subprocess to exit with timeout
@@ -530,10 +890,8 @@ func (c *Client) Kill() error {
 	c.cancel(nil)
 
 	// Kill the process
-	if c.cmd != nil && c.cmd.Process != nil {
-		if err := c.cmd.Process.Kill(); err != nil {
-			return fmt.Errorf("failed to kill process: %w", err)
-		}
+	if err := c.killProcess(); err != nil {
+		return err
 	}
 
 	// Wait for goroutines to finish
@@ -543,6 +901,18 @@ func (c *Client) Kill() error {
 	return nil
 }
 
+// killProcess kills the current app process, or drops the connection to an
+// app the client attached to, without stopping the client
+func (c *Client) killProcess() error {
+	c.procMu.RLock()
+	conn := c.conn
+	c.procMu.RUnlock()
+	if conn != nil {
+		return conn.Kill()
+	}
+	return nil
+}
+
 // Close stops the subprocess and cleans up resources
 func (c *Client) Close() error {
 	if err := c.Stop(); err != nil {
@@ -598,19 +968,36 @@ func (c *Client) Context() context.Context {
 	return c.ctx
 }
 
-// ProcessID returns the PID of the subprocess, or 0 if not running
+// ProcessID returns the PID of the subprocess, or 0 if not running or
+// attached over a socket
 func (c *Client) ProcessID() int {
-	if c.cmd != nil && c.cmd.Process != nil {
-		return c.cmd.Process.Pid
+	c.procMu.RLock()
+	defer c.procMu.RUnlock()
+	if c.conn != nil {
+		return c.conn.PID()
 	}
 	return 0
 }
 
-// Command returns the command array
+// Command returns the command array, or nil if the client does not start
+// the app
 func (c *Client) Command() []string {
 	return c.command
 }
 
+// Transport returns how the client reaches the app
+func (c *Client) Transport() Transport {
+	return c.transport
+}
+
+// describe names an app connection for logs
+func (c *Client) describe(conn Conn) string {
+	if pid := conn.PID(); pid != 0 {
+		return fmt.Sprintf("PID %d", pid)
+	}
+	return c.transport.String()
+}
+
 // String returns the client state as a string
 func (s ClientState) String() string {
 	switch s {
@@ -622,6 +1009,10 @@ func (s ClientState) String() string {
 		return "stopping"
 	case StateStopped:
 		return "stopped"
+	case StateRestarting:
+		return "restarting"
+	case StateCrashed:
+		return "crashed"
 	default:
 		return "unknown"
 	}
@@ -398,6 +398,8 @@ func TestClientWithRealCommand(t *testing.T) {
 	}
 
 	client := NewClient([]string{"echo", "hello"})
+	// echo cannot answer the handshake ping
+	client.SetHandshakeTimeout(0)
 
 	err := client.Start()
 	if err != nil {
@@ -0,0 +1,157 @@
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Framings of the messages exchanged with the app. Newline-delimited JSON is
+// the default. With content-length framing every message is preceded by a
+// "Content-Length: <bytes>" header and a blank line, as in the Language
+// Server Protocol, so large messages are read without scanning for
+// newlines. The CLI offers content-length framing in the handshake ping and
+// reads both framings, even mixed, from every app.
+const (
+	FramingNDJSON        = "ndjson"
+	FramingContentLength = "content-length"
+)
+
+// SupportedFramings are the framings this CLI reads, offered to the app in
+// the handshake ping
+var SupportedFramings = []string{FramingContentLength, FramingNDJSON}
+
+const contentLengthHeader = "Content-Length:"
+
+// MaxMessageSize is the largest message a MessageReader accepts. It leaves
+// room for attachments sent inline but stops a bad length header from
+// allocating without bound.
+const MaxMessageSize = 256 * 1024 * 1024
+
+// ErrMessageTooLarge is returned by MessageReader.Next for a message, or
+// header line, longer than MaxMessageSize
+var ErrMessageTooLarge = errors.New("message too large")
+
+// MessageReader reads JSON messages framed either as lines or with a
+// Content-Length header, up to MaxMessageSize bytes each
+type MessageReader struct {
+	r       *bufio.Reader
+	maxSize int
+}
+
+// NewMessageReader returns a reader of the messages written to r
+func NewMessageReader(r io.Reader) *MessageReader {
+	return &MessageReader{r: bufio.NewReaderSize(r, 64*1024), maxSize: MaxMessageSize}
+}
+
+// Next returns the next message, skipping blank lines. It returns io.EOF
+// after the last message. Any other error leaves the reader out of step with
+// the stream, so reading must stop.
+func (m *MessageReader) Next() ([]byte, error) {
+	for {
+		line, err := m.readLine()
+		if errors.Is(err, ErrMessageTooLarge) {
+			return nil, err
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !bytes.HasPrefix(trimmed, []byte(contentLengthHeader)) {
+			// A final line without a newline is still a message; the next
+			// call reports the EOF
+			return trimmed, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("message header %q ended early: %w", trimmed, io.ErrUnexpectedEOF)
+		}
+		return m.readFrame(trimmed)
+	}
+}
+
+// readLine reads up to and including the next newline, like
+// bufio.Reader.ReadBytes, but fails with ErrMessageTooLarge once the line
+// grows past the size limit
+func (m *MessageReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := m.r.ReadSlice('\n')
+		if len(line)+len(chunk) > m.maxSize {
+			return nil, fmt.Errorf("line longer than %d bytes: %w", m.maxSize, ErrMessageTooLarge)
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// readFrame reads the rest of the headers after the Content-Length header
+// and the message body
+func (m *MessageReader) readFrame(header []byte) ([]byte, error) {
+	value := bytes.TrimSpace(header[len(contentLengthHeader):])
+	length, err := parseContentLength(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message header %q: %w", header, err)
+	}
+	if length > int64(m.maxSize) {
+		return nil, fmt.Errorf("message of %d bytes is longer than %d: %w", length, m.maxSize, ErrMessageTooLarge)
+	}
+
+	// Other headers, such as Content-Type, are ignored up to the blank line
+	for {
+		line, err := m.readLine()
+		if errors.Is(err, ErrMessageTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("message headers ended early: %w", io.ErrUnexpectedEOF)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(m.r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("message of %d bytes ended early: %w", length, err)
+	}
+	return body, nil
+}
+
+// parseContentLength parses the value of a Content-Length header, which must
+// be plain decimal digits. Signs and lengths that overflow int64 are rejected.
+func parseContentLength(value []byte) (int64, error) {
+	if len(value) == 0 {
+		return 0, errors.New("missing length")
+	}
+	for _, b := range value {
+		if b < '0' || b > '9' {
+			return 0, fmt.Errorf("length is not a decimal number")
+		}
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+// WriteMessage writes one JSON message to w with the given framing
+func WriteMessage(w io.Writer, framing string, data []byte) error {
+	var frame []byte
+	switch framing {
+	case FramingContentLength:
+		frame = fmt.Appendf(nil, "%s %d\r\n\r\n", contentLengthHeader, len(data))
+		frame = append(frame, data...)
+	default:
+		frame = append(append(frame, data...), '\n')
+	}
+	_, err := w.Write(frame)
+	return err
+}
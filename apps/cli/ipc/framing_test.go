@@ -0,0 +1,177 @@
+package ipc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageReader(t *testing.T) {
+	large := `{"id":"big","result":"` + strings.Repeat("a", 3*1024*1024) + `"}`
+	var input bytes.Buffer
+	input.WriteString(`{"id":"1"}` + "\n\n")
+	WriteMessage(&input, FramingContentLength, []byte(`{"id":"2"}`))
+	input.WriteString("Content-Length: 10\r\nContent-Type: application/json\r\n\r\n" + `{"id":"3"}`)
+	WriteMessage(&input, FramingNDJSON, []byte(large))
+	WriteMessage(&input, FramingContentLength, []byte(large))
+	input.WriteString(`{"id":"last"}`)
+
+	reader := NewMessageReader(&input)
+	expected := []string{`{"id":"1"}`, `{"id":"2"}`, `{"id":"3"}`, large, large, `{"id":"last"}`}
+	for i, want := range expected {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Message %d: unexpected error %v", i, err)
+		}
+		if string(got) != want {
+			t.Errorf("Message %d: expected %.40q, got %.40q", i, want, got)
+		}
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF after the last message, got %v", err)
+	}
+}
+
+func TestMessageReaderSizeLimit(t *testing.T) {
+	t.Run("oversized frame", func(t *testing.T) {
+		// Fails on the header alone, without waiting for or allocating the body
+		input := fmt.Sprintf("Content-Length: %d\r\n\r\n{}", MaxMessageSize+1)
+		_, err := NewMessageReader(strings.NewReader(input)).Next()
+		if !errors.Is(err, ErrMessageTooLarge) {
+			t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+		}
+	})
+
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"oversized frame", "Content-Length: 26\r\n\r\n" + `{"id":"abcdefghijklmnopq"}`},
+		{"oversized line", `{"id":"abcdefghijklmnopq"}` + "\n"},
+		{"oversized header", "Content-Length: 2\r\nX-Padding: abcdefghijklmnop\r\n\r\n{}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("limit/"+tc.name, func(t *testing.T) {
+			reader := NewMessageReader(strings.NewReader(`{"id":"1"}` + "\n" + tc.input))
+			reader.maxSize = 24
+			if got, err := reader.Next(); err != nil || string(got) != `{"id":"1"}` {
+				t.Fatalf("Expected a message within the limit, got %q, %v", got, err)
+			}
+			if _, err := reader.Next(); !errors.Is(err, ErrMessageTooLarge) {
+				t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMessageReaderErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"invalid length", "Content-Length: lots\r\n\r\n{}"},
+		{"empty length", "Content-Length:\r\n\r\n{}"},
+		{"negative length", "Content-Length: -1\r\n\r\n{}"},
+		{"signed length", "Content-Length: +2\r\n\r\n{}"},
+		{"hex length", "Content-Length: 0x2\r\n\r\n{}"},
+		{"two lengths", "Content-Length: 1 2\r\n\r\n{}"},
+		{"overflowing length", "Content-Length: 99999999999999999999\r\n\r\n{}"},
+		{"missing blank line", "Content-Length: 2\r\n"},
+		{"short body", "Content-Length: 20\r\n\r\n{}"},
+		{"header without newline", "Content-Length: 2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewMessageReader(strings.NewReader(tc.input)).Next()
+			if err == nil || errors.Is(err, io.EOF) {
+				t.Errorf("Expected a framing error, got %v", err)
+			}
+		})
+	}
+}
+
+// serveLarge answers every request on the listener's connections with a
+// result of size bytes, framed as the request's method says. A request with
+// method "garble" gets a malformed frame.
+func serveLarge(t *testing.T, listener net.Listener, size int) {
+	t.Helper()
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				requests := NewMessageReader(conn)
+				for {
+					data, err := requests.Next()
+					if err != nil {
+						return
+					}
+					var req Request
+					if json.Unmarshal(data, &req) != nil {
+						continue
+					}
+					if req.Method == "garble" {
+						io.WriteString(conn, "Content-Length: 100\r\n\r\n{")
+						return
+					}
+					reply := fmt.Appendf(nil, `{"id":%q,"success":true,"result":"%s"}`, req.ID, strings.Repeat("x", size))
+					WriteMessage(conn, req.Method, reply)
+				}
+			}()
+		}
+	}()
+}
+
+func TestClientLargeMessages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "app.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	size := 5 * 1024 * 1024
+	serveLarge(t, listener, size)
+
+	client := NewClientWithTransport(&SocketTransport{Network: "unix", Address: listener.Addr().String()})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to attach: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, framing := range []string{FramingNDJSON, FramingContentLength} {
+		output, err := client.SendAndWaitForResponse(ctx, Request{ID: "req-" + framing, Method: framing})
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", framing, err)
+		}
+		if len(output.Message.Result) != size+2 {
+			t.Errorf("%s: expected a result of %d bytes, got %d", framing, size+2, len(output.Message.Result))
+		}
+	}
+
+	// A malformed frame ends the connection and fails the request with
+	// the reason
+	_, err = client.SendAndWaitForResponse(ctx, Request{ID: "req-garble", Method: "garble"})
+	var crash *ProcessCrashedError
+	if !errors.As(err, &crash) || !strings.Contains(err.Error(), "failed to read message from app") {
+		t.Errorf("Expected a crash caused by the malformed frame, got %v", err)
+	}
+}
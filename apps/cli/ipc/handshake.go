@@ -0,0 +1,223 @@
+package ipc
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shuttl-ai/cli/log"
+)
+
+// ProtocolVersion is the IPC protocol version spoken by this CLI. Apps with
+// the same major version are compatible.
+const ProtocolVersion = "1.0"
+
+// DefaultHandshakeTimeout is how long Start waits for the app to answer a ping
+const DefaultHandshakeTimeout = 30 * time.Second
+
+// Bounds for the delay between handshake pings
+const (
+	handshakeInitialRetry = 100 * time.Millisecond
+	handshakeMaxRetry     = time.Second
+)
+
+// PingRequest is the body of a ping
+type PingRequest struct {
+	// Framing lists the framings the CLI reads, in order of preference
+	Framing []string `json:"framing"`
+}
+
+// PingResult is the app's answer to a ping
+type PingResult struct {
+	Pong            bool   `json:"pong"`
+	Timestamp       int64  `json:"timestamp"`
+	ProtocolVersion string `json:"protocol_version"`
+	// Framing is the framing the app writes messages with from now on;
+	// apps that do not say use newline-delimited JSON
+	Framing string `json:"framing,omitempty"`
+	// Capabilities lists optional features of the protocol the app supports,
+	// such as CapabilityAttachmentPaths
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// HandshakeTimeoutError is returned by Start when the app never answers a ping
+type HandshakeTimeoutError struct {
+	Timeout  time.Duration
+	Attempts int
+}
+
+func (e *HandshakeTimeoutError) Error() string {
+	return fmt.Sprintf("app did not answer %d pings within %s; is it using the shuttl SDK?", e.Attempts, e.Timeout)
+}
+
+// IncompatibleProtocolError is returned by Start when the app speaks a
+// protocol version this CLI cannot talk to
+type IncompatibleProtocolError struct {
+	AppVersion string
+	CLIVersion string
+}
+
+func (e *IncompatibleProtocolError) Error() string {
+	return fmt.Sprintf("app speaks IPC protocol %s but this CLI speaks %s; upgrade the shuttl CLI or the app's shuttl SDK so their major versions match",
+		e.AppVersion, e.CLIVersion)
+}
+
+// SetHandshakeTimeout sets how long Start waits for the app to become ready.
+// Zero skips the handshake. Must be called before Start.
+func (c *Client) SetHandshakeTimeout(timeout time.Duration) {
+	c.handshakeTimeout = timeout
+}
+
+// AppProtocolVersion returns the protocol version reported by the app during
+// the handshake, or "" if none was reported
+func (c *Client) AppProtocolVersion() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.protocolVersion
+}
+
+// AppSupports reports whether the app listed a capability in its answer to
+// the handshake ping
+func (c *Client) AppSupports(capability string) bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return slices.Contains(c.capabilities, capability)
+}
+
+// Handshake pings the app until it answers or ctx is done, then checks that
+// the reported protocol version is compatible. Pings are resent with backoff
+// because apps may drop input until they have finished starting.
+func (c *Client) Handshake(ctx context.Context) (*PingResult, error) {
+	return c.handshake(ctx, c.Send)
+}
+
+// handshake runs the handshake, writing pings with send
+func (c *Client) handshake(ctx context.Context, send func(Request) error) (*PingResult, error) {
+	replies := make(chan OutputLine, 1)
+	failures := make(chan error, 1)
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			c.ReleaseRequest(id)
+		}
+	}()
+
+	start := time.Now()
+	retry := handshakeInitialRetry
+	for {
+		// Any reply will do, so earlier pings stay pending
+		id := getID(MessageType("ping"))
+		ids = append(ids, id)
+		errCh, outputCh := c.sendWithResult(ctx, Request{ID: id, Method: "ping", Body: PingRequest{Framing: SupportedFramings}}, send)
+		go forwardFirst(outputCh, errCh, replies, failures)
+
+		select {
+		case output := <-replies:
+			return c.checkPing(output)
+		case err := <-failures:
+			// The app is gone; waiting longer will not help
+			if IsAppUnavailable(err) {
+				return nil, err
+			}
+			log.Debug("Handshake ping failed: %v", err)
+		case <-ctx.Done():
+			return nil, &HandshakeTimeoutError{Timeout: time.Since(start).Round(time.Millisecond), Attempts: len(ids)}
+		case <-time.After(retry):
+		}
+
+		retry *= 2
+		if retry > handshakeMaxRetry {
+			retry = handshakeMaxRetry
+		}
+	}
+}
+
+// forwardFirst passes on the first reply or error of a pending request
+func forwardFirst(outputCh chan OutputLine, errCh chan error, replies chan<- OutputLine, failures chan<- error) {
+	select {
+	case output, ok := <-outputCh:
+		if ok {
+			select {
+			case replies <- output:
+			default:
+			}
+		}
+	case err, ok := <-errCh:
+		if ok && err != nil {
+			select {
+			case failures <- err:
+			default:
+			}
+		}
+	}
+}
+
+// checkPing records the app's protocol version and rejects apps that fail the
+// ping or are incompatible
+func (c *Client) checkPing(output OutputLine) (*PingResult, error) {
+	if output.Message == nil || !output.Message.Success {
+		if output.Message != nil && output.Message.ErrorObj != nil {
+			return nil, output.Message.ErrorObj
+		}
+		return nil, fmt.Errorf("app answered ping without success: %s", output.Content)
+	}
+
+	var result PingResult
+	if output.Message.Result != nil {
+		if err := json.Unmarshal(output.Message.Result, &result); err != nil {
+			log.Warn("App answered ping with an unexpected result: %s", output.Content)
+		}
+	}
+
+	c.stateMu.Lock()
+	c.protocolVersion = result.ProtocolVersion
+	c.capabilities = result.Capabilities
+	c.stateMu.Unlock()
+
+	if result.ProtocolVersion == "" {
+		log.Warn("App did not report an IPC protocol version; assuming %s", ProtocolVersion)
+		return &result, nil
+	}
+
+	compatible, newer := compareProtocolVersions(result.ProtocolVersion, ProtocolVersion)
+	if !compatible {
+		return &result, &IncompatibleProtocolError{AppVersion: result.ProtocolVersion, CLIVersion: ProtocolVersion}
+	}
+	if newer {
+		log.Warn("App speaks IPC protocol %s, newer than this CLI's %s; some features may not work until you upgrade the shuttl CLI",
+			result.ProtocolVersion, ProtocolVersion)
+	}
+	log.Debug("Handshake complete, app protocol version %s, framing %s, capabilities %v",
+		result.ProtocolVersion, cmp.Or(result.Framing, FramingNDJSON), result.Capabilities)
+	return &result, nil
+}
+
+// compareProtocolVersions reports whether the app's "major.minor" version is
+// compatible with the CLI's, and whether it is a newer minor version
+func compareProtocolVersions(app, cli string) (compatible bool, newer bool) {
+	appMajor, appMinor, ok := parseProtocolVersion(app)
+	if !ok {
+		return false, false
+	}
+	cliMajor, cliMinor, _ := parseProtocolVersion(cli)
+	return appMajor == cliMajor, appMajor == cliMajor && appMinor > cliMinor
+}
+
+func parseProtocolVersion(version string) (major int, minor int, ok bool) {
+	majorStr, minorStr, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	if minorStr != "" {
+		if minor, err = strconv.Atoi(minorStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return major, minor, true
+}
@@ -0,0 +1,270 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareProtocolVersions(t *testing.T) {
+	testCases := []struct {
+		app        string
+		compatible bool
+		newer      bool
+	}{
+		{"1.0", true, false},
+		{"1", true, false},
+		{"1.3", true, true},
+		{"0.9", false, false},
+		{"2.0", false, false},
+		{"one", false, false},
+		{"1.x", false, false},
+	}
+
+	for _, tc := range testCases {
+		compatible, newer := compareProtocolVersions(tc.app, "1.0")
+		if compatible != tc.compatible || newer != tc.newer {
+			t.Errorf("compareProtocolVersions(%q, \"1.0\") = (%v, %v), expected (%v, %v)",
+				tc.app, compatible, newer, tc.compatible, tc.newer)
+		}
+	}
+}
+
+// pingApp returns a command for an app that ignores its first `ignore` lines
+// and then answers every request as a ping reporting version
+func pingApp(version string, ignore int) []string {
+	script := fmt.Sprintf(`
+i=0
+while read -r line; do
+  i=$((i+1))
+  [ "$i" -le %d ] && continue
+  id=$(printf '%%s' "$line" | sed 's/.*"id":"\([^"]*\)".*/\1/')
+  printf '{"id":"%%s","success":true,"result":{"pong":true,"timestamp":1,"protocol_version":"%s"}}\n' "$id"
+done`, ignore, version)
+	return []string{"sh", "-c", script}
+}
+
+func TestClientHandshake(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	t.Run("compatible app", func(t *testing.T) {
+		client := NewClient(pingApp("1.0", 0))
+		if err := client.Start(); err != nil {
+			t.Fatalf("Failed to start client: %v", err)
+		}
+		defer client.Close()
+
+		if got := client.AppProtocolVersion(); got != "1.0" {
+			t.Errorf("Expected protocol version 1.0, got %q", got)
+		}
+		if got := client.Status().ProtocolVersion; got != "1.0" {
+			t.Errorf("Expected status to report protocol version 1.0, got %q", got)
+		}
+	})
+
+	t.Run("app that drops early pings", func(t *testing.T) {
+		client := NewClient(pingApp("1.2", 2))
+		if err := client.Start(); err != nil {
+			t.Fatalf("Failed to start client: %v", err)
+		}
+		defer client.Close()
+
+		if got := client.AppProtocolVersion(); got != "1.2" {
+			t.Errorf("Expected protocol version 1.2, got %q", got)
+		}
+	})
+
+	t.Run("incompatible app", func(t *testing.T) {
+		client := NewClient(pingApp("2.0", 0))
+		err := client.Start()
+		defer client.Close()
+
+		var protoErr *IncompatibleProtocolError
+		if !errors.As(err, &protoErr) {
+			t.Fatalf("Expected IncompatibleProtocolError, got %v", err)
+		}
+		if protoErr.AppVersion != "2.0" || protoErr.CLIVersion != ProtocolVersion {
+			t.Errorf("Unexpected error: %+v", protoErr)
+		}
+		waitFor(t, func() bool { return !client.IsRunning() })
+	})
+
+	t.Run("app that fails the ping", func(t *testing.T) {
+		script := `read -r line
+id=$(printf '%s' "$line" | sed 's/.*"id":"\([^"]*\)".*/\1/')
+printf '{"id":"%s","success":false,"errorObj":{"code":"NOT_READY","message":"still loading agents"}}\n' "$id"
+sleep 10`
+		client := NewClient([]string{"sh", "-c", script})
+		err := client.Start()
+		defer client.Close()
+
+		var errObj *ErrorObject
+		if !errors.As(err, &errObj) {
+			t.Fatalf("Expected the app's ErrorObject, got %v", err)
+		}
+		if errObj.Code != "NOT_READY" {
+			t.Errorf("Unexpected error: %+v", errObj)
+		}
+		waitFor(t, func() bool { return !client.IsRunning() })
+	})
+
+	t.Run("app that echoes the ping", func(t *testing.T) {
+		client := NewClient([]string{"cat"})
+		err := client.Start()
+		defer client.Close()
+
+		if err == nil || !strings.Contains(err.Error(), "without success") {
+			t.Fatalf("Expected the handshake to fail, got %v", err)
+		}
+	})
+
+	t.Run("app that never answers", func(t *testing.T) {
+		client := NewClient([]string{"sleep", "10"})
+		client.SetHandshakeTimeout(300 * time.Millisecond)
+
+		err := client.Start()
+		defer client.Close()
+
+		var timeoutErr *HandshakeTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("Expected HandshakeTimeoutError, got %v", err)
+		}
+		if timeoutErr.Attempts < 2 {
+			t.Errorf("Expected the ping to be retried, got %d attempts", timeoutErr.Attempts)
+		}
+		if !strings.Contains(err.Error(), "handshake") {
+			t.Errorf("Expected error to mention the handshake, got %q", err)
+		}
+	})
+
+	t.Run("app that exits", func(t *testing.T) {
+		client := NewClient([]string{"true"})
+		client.SetHandshakeTimeout(5 * time.Second)
+
+		start := time.Now()
+		err := client.Start()
+		defer client.Close()
+
+		if err == nil {
+			t.Fatal("Expected an error for an app that exits")
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("Expected the handshake to give up once the app exited, took %s", time.Since(start))
+		}
+	})
+}
+
+// upgradingApp returns a command for an app that answers pings with the given
+// results in turn, one per start, and exits on any other request
+func upgradingApp(t *testing.T, results ...string) []string {
+	starts := filepath.Join(t.TempDir(), "starts")
+	var cases strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&cases, "  %d) result='%s' ;;\n", i+1, result)
+	}
+	script := fmt.Sprintf(`
+n=$(( $(cat %q 2>/dev/null || echo 0) + 1 ))
+echo "$n" > %q
+case "$n" in
+%s  *) result='%s' ;;
+esac
+while read -r line; do
+  case "$line" in
+    *'"method":"ping"'*) ;;
+    *) exit 3 ;;
+  esac
+  id=$(printf '%%s' "$line" | sed 's/.*"id":"\([^"]*\)".*/\1/')
+  printf '{"id":"%%s","success":true,"result":%%s}\n' "$id" "$result"
+done`, starts, starts, cases.String(), results[len(results)-1])
+	return []string{"sh", "-c", script}
+}
+
+func TestClientHandshakeAfterRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	oldApp := `{"pong":true,"protocol_version":"1.0"}`
+	newApp := `{"pong":true,"protocol_version":"1.1","capabilities":["attachment_paths"]}`
+	incompatibleApp := `{"pong":true,"protocol_version":"2.0"}`
+	policy := RestartPolicy{
+		InitialBackoff:     10 * time.Millisecond,
+		CrashLoopThreshold: 3,
+		CrashLoopWindow:    time.Minute,
+	}
+
+	start := func(t *testing.T, client *Client) {
+		t.Helper()
+		if err := client.Start(); err != nil {
+			t.Fatalf("Failed to start client: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		if client.AppSupports(CapabilityAttachmentPaths) {
+			t.Fatal("Expected the first app not to read attachment paths")
+		}
+	}
+	crash := func(client *Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"})
+	}
+	expectUpgraded := func(t *testing.T, client *Client) {
+		t.Helper()
+		if got := client.AppProtocolVersion(); got != "1.1" {
+			t.Errorf("Expected the new app's protocol version 1.1, got %q", got)
+		}
+		if !client.AppSupports(CapabilityAttachmentPaths) {
+			t.Error("Expected the new app's capabilities")
+		}
+	}
+
+	t.Run("restart into an upgraded app", func(t *testing.T) {
+		client := NewClient(upgradingApp(t, oldApp, newApp))
+		client.SetRestartPolicy(policy)
+		start(t, client)
+
+		crash(client)
+		waitFor(t, func() bool { return client.IsRunning() && client.Restarts() == 1 })
+		expectUpgraded(t, client)
+	})
+
+	t.Run("reload into an upgraded app", func(t *testing.T) {
+		client := NewClient(upgradingApp(t, oldApp, newApp))
+		start(t, client)
+
+		if err := client.Reload(); err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+		expectUpgraded(t, client)
+	})
+
+	t.Run("restart into an incompatible app", func(t *testing.T) {
+		client := NewClient(upgradingApp(t, oldApp, incompatibleApp))
+		client.SetRestartPolicy(policy)
+		start(t, client)
+
+		crash(client)
+		waitFor(t, func() bool { return client.State() == StateStopped })
+		if status := client.Status(); status.Restarts != 0 || status.GaveUp == "" {
+			t.Errorf("Expected every restart to fail its handshake, got %+v", status)
+		}
+	})
+
+	t.Run("reload into an incompatible app", func(t *testing.T) {
+		client := NewClient(upgradingApp(t, oldApp, incompatibleApp))
+		start(t, client)
+
+		err := client.Reload()
+		var protoErr *IncompatibleProtocolError
+		if !errors.As(err, &protoErr) {
+			t.Fatalf("Expected IncompatibleProtocolError, got %v", err)
+		}
+		waitFor(t, func() bool { return client.State() == StateStopped })
+	})
+}
@@ -0,0 +1,207 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shuttl-ai/cli/log"
+)
+
+// ToolInvokeRequest represents the request payload for invoking a tool directly
+type ToolInvokeRequest struct {
+	Toolkit string         `json:"toolkit"`
+	Tool    string         `json:"tool"`
+	Args    map[string]any `json:"args"`
+}
+
+// InvokeTool invokes a single tool in a toolkit via IPC and returns its raw result
+func (c *Client) InvokeTool(ctx context.Context, toolkit string, tool string, args map[string]any) (json.RawMessage, error) {
+	c.wg.Add(1)
+	defer c.wg.Done()
+	if args == nil {
+		args = map[string]any{}
+	}
+	id := getID("invoke_tool")
+	req := Request{
+		ID:     id,
+		Method: "invokeTool",
+		Body:   ToolInvokeRequest{Toolkit: toolkit, Tool: tool, Args: args},
+	}
+	log.Debug("Invoking tool: %s/%s", toolkit, tool)
+	output, err := c.SendAndWaitForResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if output.Message == nil {
+		return nil, fmt.Errorf("no response received for tool %s/%s", toolkit, tool)
+	}
+	if !output.Message.Success {
+		if output.Message.ErrorObj != nil {
+			return nil, output.Message.ErrorObj
+		}
+		return nil, fmt.Errorf("tool %s/%s failed", toolkit, tool)
+	}
+	return output.Message.Result, nil
+}
+
+// ToolArgsError describes every way a set of arguments violates a tool's schema
+type ToolArgsError struct {
+	Tool     string
+	Problems []string
+}
+
+func (e *ToolArgsError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool %s:\n  - %s", e.Tool, strings.Join(e.Problems, "\n  - "))
+}
+
+// ValidateToolArgs checks args against the property schemas reported in
+// SingleToolInfo.Args. Only the properties map is available from the app,
+// so unknown arguments, JSON types and enums are checked but required
+// properties are not.
+func ValidateToolArgs(tool SingleToolInfo, args map[string]any) error {
+	var problems []string
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw, ok := tool.Args[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown argument %q", name))
+			continue
+		}
+		schema, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		problems = append(problems, validateValue(name, schema, args[name])...)
+	}
+
+	if len(problems) > 0 {
+		return &ToolArgsError{Tool: fmt.Sprintf("%s/%s", tool.ToolkitName, tool.Name), Problems: problems}
+	}
+	return nil
+}
+
+// validateValue validates a single decoded JSON value against a JSON schema fragment
+func validateValue(path string, schema map[string]any, value any) []string {
+	var problems []string
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		found := false
+		for _, option := range enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+		}
+	}
+
+	types := schemaTypes(schema["type"])
+	if len(types) == 0 {
+		return problems
+	}
+	matched := false
+	for _, t := range types {
+		if jsonTypeMatches(t, value) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return append(problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeOf(value)))
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateValue(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+			}
+		}
+	case map[string]any:
+		if props, ok := schema["properties"].(map[string]any); ok {
+			for key, item := range v {
+				propSchema, ok := props[key].(map[string]any)
+				if !ok {
+					continue
+				}
+				problems = append(problems, validateValue(path+"."+key, propSchema, item)...)
+			}
+		}
+	}
+	return problems
+}
+
+func schemaTypes(raw any) []string {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func jsonTypeMatches(schemaType string, value any) bool {
+	switch schemaType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "null":
+		return value == nil
+	}
+	// Unknown schema types are not enforced
+	return true
+}
+
+func jsonTypeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,138 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolInvokeRequest(t *testing.T) {
+	req := ToolInvokeRequest{
+		Toolkit: "UtilityToolkit",
+		Tool:    "add",
+		Args:    map[string]any{"a": 1, "b": 2},
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var unmarshaled map[string]any
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if unmarshaled["toolkit"] != "UtilityToolkit" {
+		t.Errorf("Expected toolkit 'UtilityToolkit', got '%v'", unmarshaled["toolkit"])
+	}
+	if unmarshaled["tool"] != "add" {
+		t.Errorf("Expected tool 'add', got '%v'", unmarshaled["tool"])
+	}
+	if _, ok := unmarshaled["args"].(map[string]any); !ok {
+		t.Errorf("Expected args object, got %T", unmarshaled["args"])
+	}
+}
+
+func TestInvokeToolFailureWithoutError(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// cat echoes the request back, a reply with neither success nor an error
+	client := NewClient([]string{"cat"})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.InvokeTool(ctx, "UtilityToolkit", "add", nil)
+	if err == nil {
+		t.Fatal("Expected an error for a failed reply")
+	}
+	if err.Error() != "tool UtilityToolkit/add failed" {
+		t.Errorf("Unexpected error %q", err.Error())
+	}
+}
+
+func TestValidateToolArgs(t *testing.T) {
+	tool := SingleToolInfo{
+		Name:        "search",
+		ToolkitName: "SearchToolkit",
+		Args: map[string]any{
+			"query": map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+			"score": map[string]any{"type": "number"},
+			"mode":  map[string]any{"type": "string", "enum": []any{"fast", "exact"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"filter": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"active": map[string]any{"type": "boolean"}},
+			},
+			"anything": map[string]any{},
+		},
+	}
+
+	parse := func(t *testing.T, raw string) map[string]any {
+		var args map[string]any
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			t.Fatalf("Failed to parse args: %v", err)
+		}
+		return args
+	}
+
+	validCases := []struct {
+		name string
+		args string
+	}{
+		{"empty args", `{}`},
+		{"all valid", `{"query":"go","limit":10,"score":0.5,"mode":"fast","tags":["a","b"],"filter":{"active":true}}`},
+		{"untyped property", `{"anything":[1,"two",null]}`},
+	}
+
+	for _, tc := range validCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateToolArgs(tool, parse(t, tc.args)); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+
+	invalidCases := []struct {
+		name     string
+		args     string
+		contains string
+	}{
+		{"unknown argument", `{"qeury":"go"}`, `unknown argument "qeury"`},
+		{"wrong type", `{"query":42}`, "query: expected string, got integer"},
+		{"non-integer", `{"limit":1.5}`, "limit: expected integer, got number"},
+		{"enum mismatch", `{"mode":"slow"}`, "mode: slow is not one of"},
+		{"array item type", `{"tags":["a",2]}`, "tags[1]: expected string"},
+		{"nested property type", `{"filter":{"active":"yes"}}`, "filter.active: expected boolean"},
+	}
+
+	for _, tc := range invalidCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateToolArgs(tool, parse(t, tc.args))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			argsErr, ok := err.(*ToolArgsError)
+			if !ok {
+				t.Fatalf("Expected *ToolArgsError, got %T", err)
+			}
+			if argsErr.Tool != "SearchToolkit/search" {
+				t.Errorf("Expected tool 'SearchToolkit/search', got '%s'", argsErr.Tool)
+			}
+			if !strings.Contains(err.Error(), tc.contains) {
+				t.Errorf("Expected error to contain %q, got %q", tc.contains, err.Error())
+			}
+		})
+	}
+}
@@ -24,12 +24,31 @@ type SerializedHTTPRequest struct {
 	Path        string              `json:"path"`
 	Headers     map[string][]string `json:"headers"`
 	Query       map[string][]string `json:"query"`
+	PathParams  map[string]string   `json:"pathParams,omitempty"`
+	Cookies     map[string]string   `json:"cookies,omitempty"`
 	Body        json.RawMessage     `json:"body,omitempty"`
 	ContentType string              `json:"contentType"`
 	RemoteAddr  string              `json:"remoteAddr"`
 	Host        string              `json:"host"`
 	Proto       string              `json:"proto"`
 	Timestamp   time.Time           `json:"timestamp"`
+	// Principal is the caller verified by serve's auth providers, if the
+	// endpoint requires authentication
+	Principal *Principal `json:"principal,omitempty"`
+}
+
+// Principal identifies the verified caller of a trigger
+type Principal struct {
+	// Provider is the name of the auth provider in shuttl.json that verified
+	// the request
+	Provider string `json:"provider"`
+	// Type is the provider type: "api_key", "hmac" or "jwt"
+	Type string `json:"type"`
+	// Subject is the API key name, the HMAC provider name or the JWT "sub"
+	// claim
+	Subject string `json:"subject"`
+	// Claims holds the claims of a verified JWT
+	Claims map[string]any `json:"claims,omitempty"`
 }
 
 // TriggerResponse represents the response from invoking a trigger
@@ -40,6 +59,9 @@ type TriggerResponse struct {
 	Result    json.RawMessage   `json:"result,omitempty"`
 	Error     string            `json:"error,omitempty"`
 	Timestamp time.Time         `json:"timestamp"`
+	// ToolCalls names the tools the agent called, in order, taken from the
+	// tool_call events of the invocation
+	ToolCalls []string `json:"-"`
 }
 
 // TriggerStreamEvent represents a streaming event from a trigger invocation
@@ -51,13 +73,28 @@ type TriggerStreamEvent struct {
 	Error     string          `json:"error,omitempty"`
 }
 
+// ToolCallName returns the tool named by the result of a tool_call event,
+// or "" if data is not a tool call
+func ToolCallName(data json.RawMessage) string {
+	var toolCall ToolCallResult
+	if err := json.Unmarshal(data, &toolCall); err != nil {
+		return ""
+	}
+	return toolCall.ToolCall.Name
+}
+
+// triggerRequestID returns a unique request ID naming the agent and trigger
+func triggerRequestID(req TriggerRequest) string {
+	return getID(MessageType(fmt.Sprintf("invoke_trigger:%s:%s", req.AgentName, req.TriggerName)))
+}
+
 // InvokeTrigger invokes a trigger via IPC and waits for completion
 // It collects all events and returns them in the response
 func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*TriggerResponse, error) {
 	c.wg.Add(1)
 	defer c.wg.Done()
 
-	id := fmt.Sprintf("invoke_trigger:%s:%s:%d", req.AgentName, req.TriggerName, time.Now().UnixNano())
+	id := triggerRequestID(req)
 
 	ipcReq := Request{
 		ID:     id,
@@ -68,12 +105,29 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 	log.Debug("Invoking trigger: %s/%s", req.AgentName, req.TriggerName)
 
 	errCh, resultCh := c.SendAsyncWithResult(ctx, ipcReq)
-	defer c.CloseSpecialChannel(id)
+	defer c.ReleaseRequest(id)
 
 	var events []json.RawMessage
+	var toolCalls []string
 	var threadID string
 	var finalResult json.RawMessage
 
+	fail := func(err error) (*TriggerResponse, error) {
+		response := &TriggerResponse{
+			Success:   false,
+			Error:     err.Error(),
+			Events:    events,
+			ToolCalls: toolCalls,
+			ThreadID:  threadID,
+			Timestamp: time.Now(),
+		}
+		// Crashes are not the trigger's fault, so let callers tell them apart
+		if IsAppUnavailable(err) {
+			return response, err
+		}
+		return response, nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -81,28 +135,31 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 				Success:   false,
 				Error:     ctx.Err().Error(),
 				Events:    events,
+				ToolCalls: toolCalls,
 				ThreadID:  threadID,
 				Timestamp: time.Now(),
 			}, ctx.Err()
 
-		case err := <-errCh:
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
 			if err != nil {
-				return &TriggerResponse{
-					Success:   false,
-					Error:     err.Error(),
-					Events:    events,
-					ThreadID:  threadID,
-					Timestamp: time.Now(),
-				}, nil
+				return fail(err)
 			}
 
 		case result, ok := <-resultCh:
 			if !ok {
+				if err := requestError(errCh); err != nil {
+					return fail(err)
+				}
 				// Channel closed unexpectedly
 				return &TriggerResponse{
 					Success:   false,
 					Error:     "result channel closed unexpectedly",
 					Events:    events,
+					ToolCalls: toolCalls,
 					ThreadID:  threadID,
 					Timestamp: time.Now(),
 				}, nil
@@ -118,6 +175,7 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 					Success:   false,
 					Error:     errMsg,
 					Events:    events,
+					ToolCalls: toolCalls,
 					ThreadID:  threadID,
 					Timestamp: time.Now(),
 				}, nil
@@ -125,8 +183,13 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 
 			// Collect the event
 			if result.Message.Result != nil {
-				if result.Message.Type == "output_text" {
+				switch result.Message.Type {
+				case "output_text":
 					events = append(events, result.Message.Result)
+				case "tool_call":
+					if name := ToolCallName(result.Message.Result); name != "" {
+						toolCalls = append(toolCalls, name)
+					}
 				}
 			}
 
@@ -147,6 +210,7 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 							Success:   true,
 							ThreadID:  threadID,
 							Events:    events,
+							ToolCalls: toolCalls,
 							Result:    finalResult,
 							Timestamp: time.Now(),
 						}, nil
@@ -167,7 +231,7 @@ func (c *Client) InvokeTrigger(ctx context.Context, req TriggerRequest) (*Trigge
 
 // InvokeTriggerAsync invokes a trigger asynchronously and returns channels for results
 func (c *Client) InvokeTriggerAsync(ctx context.Context, req TriggerRequest) (chan *TriggerResponse, chan error) {
-	id := fmt.Sprintf("invoke_trigger:%s:%s:%d", req.AgentName, req.TriggerName, time.Now().UnixNano())
+	id := triggerRequestID(req)
 
 	ipcReq := Request{
 		ID:     id,
@@ -176,50 +240,65 @@ func (c *Client) InvokeTriggerAsync(ctx context.Context, req TriggerRequest) (ch
 	}
 
 	responseCh := make(chan *TriggerResponse, 1)
+	errOut := make(chan error, 1)
 	errCh, resultCh := c.SendAsyncWithResult(ctx, ipcReq)
 
 	go func() {
 		defer close(responseCh)
-		defer c.CloseSpecialChannel(id)
+		defer c.ReleaseRequest(id)
 
-		select {
-		case <-ctx.Done():
-			errCh <- ctx.Err()
-			return
-		case result, ok := <-resultCh:
-			if !ok {
-				errCh <- fmt.Errorf("result channel closed")
+		for {
+			select {
+			case <-ctx.Done():
+				errOut <- ctx.Err()
 				return
-			}
+			case err, ok := <-errCh:
+				if !ok {
+					errCh = nil
+					continue
+				}
+				errOut <- err
+				return
+			case result, ok := <-resultCh:
+				if !ok {
+					if err := requestError(errCh); err != nil {
+						errOut <- err
+						return
+					}
+					errOut <- fmt.Errorf("result channel closed")
+					return
+				}
 
-			if !result.Message.Success {
-				errMsg := "trigger invocation failed"
-				if result.Message.ErrorObj != nil {
-					errMsg = result.Message.ErrorObj.Error()
+				if !result.Message.Success {
+					errMsg := "trigger invocation failed"
+					if result.Message.ErrorObj != nil {
+						errMsg = result.Message.ErrorObj.Error()
+					}
+					responseCh <- &TriggerResponse{
+						Success:   false,
+						Error:     errMsg,
+						Timestamp: time.Now(),
+					}
+					return
 				}
+
 				responseCh <- &TriggerResponse{
-					Success:   false,
-					Error:     errMsg,
+					Success:   true,
+					Result:    result.Message.Result,
 					Timestamp: time.Now(),
 				}
 				return
 			}
-
-			responseCh <- &TriggerResponse{
-				Success:   true,
-				Result:    result.Message.Result,
-				Timestamp: time.Now(),
-			}
 		}
 	}()
 
-	return responseCh, errCh
+	return responseCh, errOut
 }
 
 // InvokeTriggerStreaming invokes a trigger and streams events back as they arrive
 // This is similar to StartChat but for triggers
 func (c *Client) InvokeTriggerStreaming(ctx context.Context, req TriggerRequest) (chan *TriggerStreamEvent, chan error) {
-	id := fmt.Sprintf("invoke_trigger:%s:%s:%d", req.AgentName, req.TriggerName, time.Now().UnixNano())
+	id := triggerRequestID(req)
 
 	ipcReq := Request{
 		ID:     id,
@@ -230,29 +309,55 @@ func (c *Client) InvokeTriggerStreaming(ctx context.Context, req TriggerRequest)
 	log.Debug("Invoking trigger (streaming): %s/%s", req.AgentName, req.TriggerName)
 
 	eventCh := make(chan *TriggerStreamEvent, 10)
+	errOut := make(chan error, 1)
 	errCh, resultCh := c.SendAsyncWithResult(ctx, ipcReq)
 
+	// send delivers an event unless the caller has gone away
+	send := func(event *TriggerStreamEvent) bool {
+		select {
+		case eventCh <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	go func() {
 		defer close(eventCh)
-		defer c.CloseSpecialChannel(id)
+		defer c.ReleaseRequest(id)
 
 		for {
 			select {
 			case <-ctx.Done():
-				eventCh <- &TriggerStreamEvent{
+				select {
+				case eventCh <- &TriggerStreamEvent{Type: "error", Error: ctx.Err().Error(), Completed: true}:
+				default:
+				}
+				return
+
+			case err, ok := <-errCh:
+				if !ok {
+					errCh = nil
+					continue
+				}
+				send(&TriggerStreamEvent{
 					Type:      "error",
-					Error:     ctx.Err().Error(),
+					Error:     err.Error(),
 					Completed: true,
-				}
+				})
 				return
 
 			case result, ok := <-resultCh:
 				if !ok {
-					eventCh <- &TriggerStreamEvent{
+					message := "result channel closed unexpectedly"
+					if err := requestError(errCh); err != nil {
+						message = err.Error()
+					}
+					send(&TriggerStreamEvent{
 						Type:      "error",
-						Error:     "result channel closed unexpectedly",
+						Error:     message,
 						Completed: true,
-					}
+					})
 					return
 				}
 
@@ -262,11 +367,11 @@ func (c *Client) InvokeTriggerStreaming(ctx context.Context, req TriggerRequest)
 					if result.Message.ErrorObj != nil {
 						errMsg = result.Message.ErrorObj.Error()
 					}
-					eventCh <- &TriggerStreamEvent{
+					send(&TriggerStreamEvent{
 						Type:      "error",
 						Error:     errMsg,
 						Completed: true,
-					}
+					})
 					return
 				}
 
@@ -287,16 +392,18 @@ func (c *Client) InvokeTriggerStreaming(ctx context.Context, req TriggerRequest)
 						event.ThreadID = status.ThreadID
 						if status.Status == "completed" || status.Status == "invoked" {
 							event.Completed = true
-							eventCh <- event
+							send(event)
 							return
 						}
 					}
 				}
 
-				eventCh <- event
+				if !send(event) {
+					return
+				}
 			}
 		}
 	}()
 
-	return eventCh, errCh
+	return eventCh, errOut
 }
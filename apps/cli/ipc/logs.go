@@ -0,0 +1,133 @@
+package ipc
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// LogNotificationID is the ID of log events sent by the app, e.g.
+// {"type":"event","id":"__log__","result":{"level":"info","message":"..."}}
+const LogNotificationID = "__" + EventLog + "__"
+
+// Sources of log events
+const (
+	LogSourceApp    = "app"
+	LogSourceStderr = "stderr"
+)
+
+// Log levels reported on LogEvent
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Logs returns the app's log events and stderr lines. Events are dropped,
+// oldest first, if nobody reads them. The channel is closed when the client
+// stops.
+func (c *Client) Logs() <-chan LogEvent {
+	return c.logChan
+}
+
+// isLogEvent reports whether msg is a log event rather than a reply or
+// another notification
+func isLogEvent(msg *Message) bool {
+	return msg.Type == MessageTypeEvent && msg.ID == LogNotificationID
+}
+
+// parseLogEvent decodes the log event carried by msg
+func parseLogEvent(msg *Message, received time.Time) (LogEvent, error) {
+	var event LogEvent
+	if err := json.Unmarshal(msg.Result, &event); err != nil {
+		return LogEvent{}, err
+	}
+	event.Level = normalizeLogLevel(event.Level)
+	event.Source = LogSourceApp
+	event.Timestamp = received
+	if !msg.Timestamp.IsZero() {
+		event.Timestamp = msg.Timestamp
+	}
+	return event, nil
+}
+
+// stderrLogEvent turns a line the app wrote to stderr into a log event. The
+// level is guessed from a leading level word such as "ERROR:" or "[warn]",
+// defaulting to info since apps print ordinary output to stderr too.
+func stderrLogEvent(line string, received time.Time) LogEvent {
+	return LogEvent{
+		Level:     stderrLevel(line),
+		Message:   line,
+		Source:    LogSourceStderr,
+		Timestamp: received,
+	}
+}
+
+func stderrLevel(line string) string {
+	word := strings.TrimLeft(line, " \t[")
+	if end := strings.IndexAny(word, " \t:]"); end >= 0 {
+		word = word[:end]
+	}
+	switch strings.ToLower(word) {
+	case "error", "err", "fatal", "panic", "traceback", "exception":
+		return LogLevelError
+	case "warn", "warning":
+		return LogLevelWarn
+	case "debug", "trace":
+		return LogLevelDebug
+	}
+	return LogLevelInfo
+}
+
+// normalizeLogLevel maps the level names apps commonly use onto debug, info,
+// warn and error
+func normalizeLogLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "debug", "trace", "verbose":
+		return LogLevelDebug
+	case "warn", "warning":
+		return LogLevelWarn
+	case "error", "fatal", "critical":
+		return LogLevelError
+	}
+	return LogLevelInfo
+}
+
+// emitLog tags event with the agent that is running, if it is not tagged
+// already, and sends it to the log channel, dropping the oldest event if
+// nobody is reading
+func (c *Client) emitLog(event LogEvent) {
+	if event.AgentID == "" {
+		event.AgentID = c.pending.activeAgent()
+	}
+	select {
+	case c.logChan <- event:
+	default:
+		select {
+		case <-c.logChan:
+			c.dropped.logFull.Add(1)
+		default:
+		}
+		select {
+		case c.logChan <- event:
+		default:
+			c.dropped.logFull.Add(1)
+		}
+	}
+}
+
+// requestAgent returns the agent a request body is addressed to
+func requestAgent(body any) string {
+	switch body := body.(type) {
+	case ChatRequest:
+		return body.Agent
+	case *ChatRequest:
+		return body.Agent
+	case TriggerRequest:
+		return body.AgentName
+	case *TriggerRequest:
+		return body.AgentName
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package ipc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStderrLevel(t *testing.T) {
+	testCases := map[string]string{
+		"ERROR: connection refused":          LogLevelError,
+		"[warn] slow response":               LogLevelWarn,
+		"Warning: deprecated option":         LogLevelWarn,
+		"  DEBUG loading config":             LogLevelDebug,
+		"Traceback (most recent call last):": LogLevelError,
+		"Binding request to Slack":           LogLevelInfo,
+		"errors are fine in the middle":      LogLevelInfo,
+		"":                                   LogLevelInfo,
+	}
+
+	for line, expected := range testCases {
+		if got := stderrLevel(line); got != expected {
+			t.Errorf("stderrLevel(%q) = %q, expected %q", line, got, expected)
+		}
+	}
+}
+
+func TestParseLogEvent(t *testing.T) {
+	received := time.Now()
+	sent := received.Add(-time.Second).UTC()
+	msg := &Message{
+		Type:      MessageTypeEvent,
+		ID:        LogNotificationID,
+		Timestamp: sent,
+		Result:    json.RawMessage(`{"level":"WARNING","message":"rate limited","agent_id":"Bot"}`),
+	}
+	if !isLogEvent(msg) {
+		t.Fatal("Expected message to be recognized as a log event")
+	}
+
+	event, err := parseLogEvent(msg, received)
+	if err != nil {
+		t.Fatalf("parseLogEvent failed: %v", err)
+	}
+	if event.Level != LogLevelWarn || event.Message != "rate limited" || event.AgentID != "Bot" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Source != LogSourceApp || !event.Timestamp.Equal(sent) {
+		t.Errorf("Expected the app's timestamp and source, got %+v", event)
+	}
+
+	if isLogEvent(&Message{Type: MessageTypeResponse, ID: LogNotificationID}) {
+		t.Error("Only event messages are log events")
+	}
+}
+
+func TestEmitLogTagsActiveAgent(t *testing.T) {
+	client := NewClient([]string{"echo"})
+
+	client.emitLog(stderrLogEvent("no request running", time.Now()))
+	if event := <-client.Logs(); event.AgentID != "" {
+		t.Errorf("Expected no agent without pending requests, got %q", event.AgentID)
+	}
+
+	client.pending.register(Request{ID: "chat-1", Body: ChatRequest{Agent: "Bot"}}, 0, 1)
+	client.pending.register(Request{ID: "ping-1", Method: "ping"}, 0, 1)
+	client.emitLog(stderrLogEvent("thinking", time.Now()))
+	if event := <-client.Logs(); event.AgentID != "Bot" {
+		t.Errorf("Expected the event to be tagged with Bot, got %q", event.AgentID)
+	}
+
+	client.pending.register(Request{ID: "trigger-1", Body: TriggerRequest{AgentName: "Other"}}, 0, 1)
+	client.emitLog(LogEvent{Level: LogLevelInfo, Message: "ambiguous"})
+	if event := <-client.Logs(); event.AgentID != "" {
+		t.Errorf("Expected no agent with requests for two agents, got %q", event.AgentID)
+	}
+
+	client.emitLog(LogEvent{Level: LogLevelInfo, Message: "tagged", AgentID: "Explicit"})
+	if event := <-client.Logs(); event.AgentID != "Explicit" {
+		t.Errorf("Expected the app's agent to be kept, got %q", event.AgentID)
+	}
+}
+
+func TestEmitLogDropsOldest(t *testing.T) {
+	client := NewClient([]string{"echo"})
+	for i := 0; i < cap(client.logChan)+2; i++ {
+		client.emitLog(LogEvent{Level: LogLevelInfo, Message: "line"})
+	}
+	if dropped := client.DroppedMessages()[DropLogFull]; dropped != 2 {
+		t.Errorf("Expected 2 dropped log events, got %d", dropped)
+	}
+}
+
+func TestClientLogs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	script := `echo 'ERROR: failed to load tool' >&2
+printf '{"type":"event","id":"__log__","result":{"level":"debug","message":"loaded","agent_id":"Bot"}}\n'
+` + pingApp("1.0", 0)[2]
+	client := NewClient([]string{"sh", "-c", script})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	got := map[string]LogEvent{}
+	timeout := time.After(2 * time.Second)
+	for len(got) < 2 {
+		select {
+		case event := <-client.Logs():
+			got[event.Source] = event
+		case <-timeout:
+			t.Fatalf("Timed out waiting for log events, got %+v", got)
+		}
+	}
+
+	if event := got[LogSourceStderr]; event.Level != LogLevelError || event.Message != "ERROR: failed to load tool" {
+		t.Errorf("Unexpected stderr event: %+v", event)
+	}
+	if event := got[LogSourceApp]; event.Level != LogLevelDebug || event.AgentID != "Bot" {
+		t.Errorf("Unexpected app event: %+v", event)
+	}
+
+	select {
+	case line := <-client.Output():
+		t.Errorf("Log events should not reach the output channel, got %+v", line)
+	default:
+	}
+}
@@ -0,0 +1,342 @@
+package ipc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultRequestTimeout is how long a pending request may go without
+// receiving a message before it is failed with a RequestTimeoutError
+const DefaultRequestTimeout = 5 * time.Minute
+
+// recentRequestsSize bounds how many finished request IDs are remembered to
+// tell late replies apart from replies to requests that never existed
+const recentRequestsSize = 256
+
+// idCounter makes IDs generated within the same nanosecond unique
+var idCounter atomic.Uint64
+
+// getID returns a request ID that is unique for the lifetime of the process
+func getID(messageType MessageType) string {
+	return fmt.Sprintf("%s:%d-%d", messageType, time.Now().UnixNano(), idCounter.Add(1))
+}
+
+// isNotificationID reports whether a message ID belongs to an unsolicited
+// app notification (such as "__ready__") instead of a reply to a request
+func isNotificationID(id string) bool {
+	return id == "" || strings.HasPrefix(id, "__")
+}
+
+// RequestTimeoutError is delivered to a pending request that received no
+// message within its timeout
+type RequestTimeoutError struct {
+	ID      string
+	Method  string
+	Timeout time.Duration
+}
+
+func (e *RequestTimeoutError) Error() string {
+	return fmt.Sprintf("request %s (%s) timed out after %s without a response", e.ID, e.Method, e.Timeout)
+}
+
+// DuplicateRequestIDError is returned when a request reuses the ID of a
+// request that is still pending
+type DuplicateRequestIDError struct {
+	ID string
+}
+
+func (e *DuplicateRequestIDError) Error() string {
+	return fmt.Sprintf("request id %s is already pending", e.ID)
+}
+
+// OrphanedResponseError is reported on Errors() when a reply arrives for a
+// request that is not pending. Late is true when the request existed but had
+// already finished, timed out or been released.
+type OrphanedResponseError struct {
+	ID     string
+	Late   bool
+	Output OutputLine
+}
+
+func (e *OrphanedResponseError) Error() string {
+	if e.Late {
+		return fmt.Sprintf("late response for finished request %s: %s", e.ID, e.Output.Content)
+	}
+	return fmt.Sprintf("orphaned response with unknown request id %s: %s", e.ID, e.Output.Content)
+}
+
+// pendingRequest is an in-flight request waiting for one or more replies
+type pendingRequest struct {
+	id         string
+	method     string
+	agent      string
+	sentAt     time.Time
+	timeout    time.Duration
+	outputChan chan OutputLine
+	errChan    chan error
+
+	timer *time.Timer
+	// awaiting is the thread whose agent is waiting for the user; the
+	// timeout is paused until Respond is called for it
+	awaiting string
+
+	// trace holds the request's span; see trace.go
+	trace *requestTrace
+
+	// done is closed before the channels so blocked deliveries can bail out
+	done      chan struct{}
+	closeOnce sync.Once
+	// expired is closed when the request times out, so a delivery blocked
+	// on a consumer that stopped reading gives up
+	expired    chan struct{}
+	expireOnce sync.Once
+	// sending counts deliveries blocked outside mu, which close waits for
+	// before closing the channels
+	sending sync.WaitGroup
+	mu      sync.Mutex
+	closed  bool
+}
+
+// deliver sends a reply to the request. If the consumer is behind it blocks
+// until the reply is consumed, the request is released or it times out. The
+// lock is not held meanwhile, so the timeout and other callers are not held
+// up by a slow consumer.
+func (p *pendingRequest) deliver(output OutputLine) bool {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return false
+	}
+	if p.timer != nil {
+		if thread, ok := inputRequestThread(output.Message); ok {
+			p.awaiting = thread
+			p.timer.Stop()
+		} else {
+			p.awaiting = ""
+			p.timer.Reset(p.timeout)
+		}
+	}
+	if p.trace != nil {
+		p.trace.observe(output.Message)
+	}
+	p.sending.Add(1)
+	p.mu.Unlock()
+	defer p.sending.Done()
+
+	select {
+	case p.outputChan <- output:
+		return true
+	default:
+	}
+	select {
+	case p.outputChan <- output:
+		return true
+	case <-p.done:
+		return false
+	case <-p.expired:
+		return false
+	}
+}
+
+// expire fails the request with a timeout and stops waiting for its consumer
+func (p *pendingRequest) expire() {
+	p.fail(&RequestTimeoutError{ID: p.id, Method: p.method, Timeout: p.timeout})
+	p.expireOnce.Do(func() { close(p.expired) })
+}
+
+// fail delivers an error without blocking; the error is dropped if the
+// request already has one queued
+func (p *pendingRequest) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	if p.trace != nil {
+		p.trace.fail(err)
+	}
+	select {
+	case p.errChan <- err:
+	default:
+	}
+}
+
+// startTrace starts the request's span as a child of the span in ctx
+func (p *pendingRequest) startTrace(ctx context.Context, tracer trace.Tracer, req Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.trace = startTrace(ctx, tracer, req)
+}
+
+// close releases the request and closes its channels
+func (p *pendingRequest) close() {
+	p.closeOnce.Do(func() {
+		// No delivery starts once closed is set; those under way bail out
+		// on done and are waited for before the channels close
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.done)
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		p.sending.Wait()
+
+		p.mu.Lock()
+		close(p.outputChan)
+		close(p.errChan)
+		if p.trace != nil {
+			p.trace.end()
+		}
+		p.mu.Unlock()
+	})
+}
+
+// pendingTable correlates replies with the requests that caused them
+type pendingTable struct {
+	mu       sync.Mutex
+	requests map[string]*pendingRequest
+	recent   []string
+	recentAt map[string]struct{}
+}
+
+func newPendingTable() *pendingTable {
+	return &pendingTable{
+		requests: make(map[string]*pendingRequest),
+		recentAt: make(map[string]struct{}),
+	}
+}
+
+// register adds a pending request. A positive timeout fails the request if
+// no message arrives within that duration; the timer restarts on every reply.
+func (t *pendingTable) register(req Request, timeout time.Duration, bufferSize int) (*pendingRequest, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.requests[req.ID]; exists {
+		return nil, &DuplicateRequestIDError{ID: req.ID}
+	}
+
+	p := &pendingRequest{
+		id:         req.ID,
+		method:     req.Method,
+		agent:      requestAgent(req.Body),
+		sentAt:     time.Now(),
+		timeout:    timeout,
+		outputChan: make(chan OutputLine, bufferSize),
+		errChan:    make(chan error, 1),
+		done:       make(chan struct{}),
+		expired:    make(chan struct{}),
+	}
+	if timeout > 0 {
+		p.timer = time.AfterFunc(timeout, p.expire)
+	}
+	t.requests[req.ID] = p
+	return p, nil
+}
+
+// lookup returns the pending request for an ID and whether the ID belongs to
+// a recently finished request
+func (t *pendingTable) lookup(id string) (*pendingRequest, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.requests[id]; ok {
+		return p, false
+	}
+	_, late := t.recentAt[id]
+	return nil, late
+}
+
+// release removes a pending request, closes its channels and remembers its ID
+// so later replies can be reported as late
+func (t *pendingTable) release(id string) {
+	t.mu.Lock()
+	p, ok := t.requests[id]
+	if ok {
+		delete(t.requests, id)
+		t.remember(id)
+	}
+	t.mu.Unlock()
+	if ok {
+		p.close()
+	}
+}
+
+// remember records a finished ID, evicting the oldest beyond the limit.
+// The caller must hold t.mu.
+func (t *pendingTable) remember(id string) {
+	t.recent = append(t.recent, id)
+	t.recentAt[id] = struct{}{}
+	if len(t.recent) > recentRequestsSize {
+		delete(t.recentAt, t.recent[0])
+		t.recent = t.recent[1:]
+	}
+}
+
+// resume restarts the timeout of requests waiting for the user in thread
+func (t *pendingTable) resume(thread string) {
+	t.mu.Lock()
+	requests := make([]*pendingRequest, 0, len(t.requests))
+	for _, p := range t.requests {
+		requests = append(requests, p)
+	}
+	t.mu.Unlock()
+
+	for _, p := range requests {
+		p.mu.Lock()
+		if !p.closed && p.timer != nil && p.awaiting != "" && p.awaiting == thread {
+			p.awaiting = ""
+			p.timer.Reset(p.timeout)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// failAll delivers err to every pending request and releases them
+func (t *pendingTable) failAll(err error) {
+	t.mu.Lock()
+	requests := make([]*pendingRequest, 0, len(t.requests))
+	for id, p := range t.requests {
+		requests = append(requests, p)
+		delete(t.requests, id)
+		t.remember(id)
+	}
+	t.mu.Unlock()
+
+	for _, p := range requests {
+		if err != nil {
+			p.fail(err)
+		}
+		p.close()
+	}
+}
+
+// activeAgent returns the agent all pending requests are addressed to, or ""
+// if there are none or they are for different agents
+func (t *pendingTable) activeAgent() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	agent := ""
+	for _, p := range t.requests {
+		if p.agent == "" {
+			continue
+		}
+		if agent != "" && agent != p.agent {
+			return ""
+		}
+		agent = p.agent
+	}
+	return agent
+}
+
+// len returns the number of pending requests
+func (t *pendingTable) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.requests)
+}
@@ -0,0 +1,278 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetIDIsUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id := getID(RequestListAgents)
+		if seen[id] {
+			t.Fatalf("Duplicate ID generated: %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestIsNotificationID(t *testing.T) {
+	testCases := map[string]bool{
+		"":                    true,
+		"__ready__":           true,
+		"__parse_error__":     true,
+		"list_agents:1-1":     false,
+		"invoke_trigger:a:b":  false,
+		"_single_underscore_": false,
+	}
+
+	for id, expected := range testCases {
+		if got := isNotificationID(id); got != expected {
+			t.Errorf("isNotificationID(%q) = %v, expected %v", id, got, expected)
+		}
+	}
+}
+
+func TestPendingTableRegister(t *testing.T) {
+	table := newPendingTable()
+
+	if _, err := table.register(Request{ID: "req-1", Method: "listAgents"}, 0, 1); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	_, err := table.register(Request{ID: "req-1", Method: "listAgents"}, 0, 1)
+	var dupErr *DuplicateRequestIDError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("Expected DuplicateRequestIDError, got %v", err)
+	}
+
+	if table.len() != 1 {
+		t.Errorf("Expected 1 pending request, got %d", table.len())
+	}
+
+	table.release("req-1")
+	if table.len() != 0 {
+		t.Errorf("Expected 0 pending requests after release, got %d", table.len())
+	}
+
+	if _, late := table.lookup("req-1"); !late {
+		t.Error("Expected released request to be remembered as late")
+	}
+	if _, late := table.lookup("never-sent"); late {
+		t.Error("Expected unknown request not to be reported as late")
+	}
+}
+
+func TestPendingRequestTimeout(t *testing.T) {
+	table := newPendingTable()
+	request, err := table.register(Request{ID: "slow", Method: "invokeAgent"}, 20*time.Millisecond, 1)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	defer table.release("slow")
+
+	select {
+	case err := <-request.errChan:
+		var timeoutErr *RequestTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("Expected RequestTimeoutError, got %v", err)
+		}
+		if timeoutErr.ID != "slow" || timeoutErr.Method != "invokeAgent" {
+			t.Errorf("Unexpected timeout error fields: %+v", timeoutErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected request to time out")
+	}
+}
+
+func TestPendingRequestTimeoutResetsOnReply(t *testing.T) {
+	table := newPendingTable()
+	request, err := table.register(Request{ID: "stream", Method: "invokeAgent"}, 60*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	defer table.release("stream")
+
+	// Keep the request alive past its timeout by delivering replies
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		request.deliver(OutputLine{Content: fmt.Sprintf("delta %d", i)})
+	}
+
+	select {
+	case err := <-request.errChan:
+		t.Fatalf("Expected no timeout while replies arrive, got %v", err)
+	default:
+	}
+}
+
+func TestPendingRequestDeliverAfterRelease(t *testing.T) {
+	table := newPendingTable()
+	request, err := table.register(Request{ID: "req", Method: "listAgents"}, 0, 0)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	delivered := make(chan bool)
+	go func() {
+		// Unbuffered and unread, so this blocks until the request is released
+		delivered <- request.deliver(OutputLine{Content: "reply"})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	table.release("req")
+
+	select {
+	case ok := <-delivered:
+		if ok {
+			t.Error("Expected delivery to a released request to fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Delivery blocked after the request was released")
+	}
+}
+
+func TestPendingRequestSlowConsumer(t *testing.T) {
+	table := newPendingTable()
+	request, err := table.register(Request{ID: "slow", Method: "invokeAgent"}, 100*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	defer table.release("slow")
+
+	delivered := make(chan bool)
+	go func() {
+		// Unbuffered and unread, so this blocks on the consumer
+		delivered <- request.deliver(OutputLine{Content: "reply"})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The blocked delivery must not hold up other users of the request
+	unblocked := make(chan struct{})
+	go func() {
+		table.resume("thread")
+		close(unblocked)
+	}()
+	select {
+	case <-unblocked:
+	case <-time.After(50 * time.Millisecond):
+		t.Fatal("Request was locked while a delivery was blocked")
+	}
+
+	// The timeout still fires and frees the reader
+	select {
+	case ok := <-delivered:
+		if ok {
+			t.Error("Expected the delivery to give up")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Delivery blocked past the request timeout")
+	}
+	var timeoutErr *RequestTimeoutError
+	if err := <-request.errChan; !errors.As(err, &timeoutErr) {
+		t.Errorf("Expected RequestTimeoutError, got %v", err)
+	}
+}
+
+func TestClientRoutesOrphanedResponses(t *testing.T) {
+	client := NewClient([]string{"echo"})
+
+	t.Run("unknown id is reported as orphaned", func(t *testing.T) {
+		client.route(OutputLine{Content: "{}", Message: &Message{ID: "unknown:1"}})
+
+		select {
+		case err := <-client.Errors():
+			var orphanErr *OrphanedResponseError
+			if !errors.As(err, &orphanErr) {
+				t.Fatalf("Expected OrphanedResponseError, got %v", err)
+			}
+			if orphanErr.Late {
+				t.Error("Expected unknown response not to be late")
+			}
+		default:
+			t.Fatal("Expected an orphaned response error")
+		}
+
+		select {
+		case output := <-client.Output():
+			t.Fatalf("Orphaned response leaked to the output channel: %+v", output)
+		default:
+		}
+	})
+
+	t.Run("released id is reported as late", func(t *testing.T) {
+		if _, err := client.pending.register(Request{ID: "done:1"}, 0, 1); err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+		client.ReleaseRequest("done:1")
+		client.route(OutputLine{Content: "{}", Message: &Message{ID: "done:1"}})
+
+		err := <-client.Errors()
+		var orphanErr *OrphanedResponseError
+		if !errors.As(err, &orphanErr) || !orphanErr.Late {
+			t.Fatalf("Expected late OrphanedResponseError, got %v", err)
+		}
+	})
+
+	t.Run("notifications go to the output channel", func(t *testing.T) {
+		client.route(OutputLine{Content: "{}", Message: &Message{ID: "__ready__"}})
+
+		select {
+		case output := <-client.Output():
+			if output.Message.ID != "__ready__" {
+				t.Errorf("Expected __ready__ notification, got %s", output.Message.ID)
+			}
+		default:
+			t.Fatal("Expected notification on the output channel")
+		}
+	})
+}
+
+func TestConcurrentRequestsAreCorrelated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// cat echoes every request back, so each reply carries its request's ID.
+	// An echoed ping is not a successful answer, so skip the handshake.
+	client := NewClient([]string{"cat"})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := getID(RequestListAgents)
+			output, err := client.SendAndWaitForResponse(ctx, Request{ID: id, Method: "listAgents"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if output.Message == nil || output.Message.ID != id {
+				errs <- fmt.Errorf("request %s received reply %+v", id, output.Message)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if client.PendingRequests() != 0 {
+		t.Errorf("Expected no pending requests, got %d", client.PendingRequests())
+	}
+}
@@ -0,0 +1,151 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Directions of recorded entries
+const (
+	RecordSent     = "sent"
+	RecordReceived = "received"
+)
+
+// RecordEntry is one line of a recording: a request sent to the app or a
+// message received from it
+type RecordEntry struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"`
+	Request   *RecordRequest  `json:"request,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+// RecordRequest is a recorded request. The body is kept as sent so it can
+// be compared with the requests of a replay.
+type RecordRequest struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Recorder writes the traffic between a client and its app to a recording,
+// one JSON entry per line
+type Recorder struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewRecorder returns a recorder writing to w
+func NewRecorder(w io.Writer) *Recorder {
+	r := &Recorder{w: bufio.NewWriter(w)}
+	if closer, ok := w.(io.Closer); ok {
+		r.closer = closer
+	}
+	return r
+}
+
+// CreateRecording creates a recording file, truncating any existing one
+func CreateRecording(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+	return NewRecorder(file), nil
+}
+
+// Close flushes the recording and closes the underlying writer if it is
+// closable. It returns the first error met while recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+// recordRequest records a request as it was written to the app
+func (r *Recorder) recordRequest(req Request, data []byte) {
+	var sent RecordRequest
+	if err := json.Unmarshal(data, &sent); err != nil {
+		sent = RecordRequest{ID: req.ID, Method: req.Method}
+	}
+	r.write(RecordEntry{Time: time.Now(), Direction: RecordSent, Request: &sent})
+}
+
+// recordMessage records a line read from the app
+func (r *Recorder) recordMessage(line string, received time.Time) {
+	r.write(RecordEntry{Time: received, Direction: RecordReceived, Message: json.RawMessage(line)})
+}
+
+func (r *Recorder) write(entry RecordEntry) {
+	data, err := json.Marshal(entry)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err != nil {
+		r.err = fmt.Errorf("failed to record message: %w", err)
+		return
+	}
+	data = append(data, '\n')
+	if _, err := r.w.Write(data); err != nil {
+		r.err = fmt.Errorf("failed to write recording: %w", err)
+		return
+	}
+	// Flush per entry so a recording survives a crash of the CLI
+	if err := r.w.Flush(); err != nil {
+		r.err = fmt.Errorf("failed to write recording: %w", err)
+	}
+}
+
+// SetRecorder records every request sent and message received by the
+// client. Must be called before Start; the caller closes the recorder after
+// the client stops.
+func (c *Client) SetRecorder(recorder *Recorder) {
+	c.recorder = recorder
+}
+
+// ReadRecording reads the entries of a recording
+func ReadRecording(path string) ([]RecordEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	var entries []RecordEntry
+	decoder := json.NewDecoder(file)
+	for {
+		var entry RecordEntry
+		err := decoder.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %w", path, err)
+		}
+		switch {
+		case entry.Direction == RecordSent && entry.Request != nil:
+		case entry.Direction == RecordReceived && len(entry.Message) > 0:
+		default:
+			return nil, fmt.Errorf("invalid recording %s: entry %d is neither a sent request nor a received message", path, len(entries)+1)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,242 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientRecording(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := CreateRecording(path)
+	if err != nil {
+		t.Fatalf("Failed to create recording: %v", err)
+	}
+
+	client := NewClient(pingApp("1.0", 0))
+	client.SetRecorder(recorder)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.SendAndWaitForResponse(ctx, Request{ID: "req-1", Method: "echo", Body: map[string]string{"text": "hi"}}); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	client.Close()
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Failed to close recording: %v", err)
+	}
+
+	entries, err := ReadRecording(path)
+	if err != nil {
+		t.Fatalf("Failed to read recording: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected a ping and an echo with their replies, got %d entries", len(entries))
+	}
+	sent, received := entries[2], entries[3]
+	if sent.Direction != RecordSent || sent.Request.Method != "echo" || !jsonEqual(sent.Request.Body, json.RawMessage(`{"text":"hi"}`)) {
+		t.Errorf("Unexpected sent entry: %+v", sent)
+	}
+	if received.Direction != RecordReceived || !strings.Contains(string(received.Message), `"id":"req-1"`) {
+		t.Errorf("Unexpected received entry: %+v", received)
+	}
+	if received.Time.Before(sent.Time) {
+		t.Error("Expected the reply to be recorded after the request")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	at := time.Now()
+	entry := func(direction string, offset int, data string) RecordEntry {
+		e := RecordEntry{Time: at.Add(time.Duration(offset) * time.Millisecond), Direction: direction}
+		if direction == RecordSent {
+			e.Request = &RecordRequest{}
+			json.Unmarshal([]byte(data), e.Request)
+		} else {
+			e.Message = json.RawMessage(data)
+		}
+		return e
+	}
+	replay := NewReplay([]RecordEntry{
+		entry(RecordReceived, 0, `{"type":"event","id":"__ready__"}`),
+		entry(RecordSent, 1, `{"id":"a","method":"invokeAgent","body":{"prompt":"hello"}}`),
+		entry(RecordReceived, 2, `{"type":"response","id":"a","success":true,"result":{"type":"response.requested","threadId":"t1"}}`),
+		entry(RecordSent, 3, `{"id":"b","method":"respond","body":{"thread_id":"t1","response":"yes"}}`),
+		entry(RecordReceived, 4, `{"type":"response","id":"b","success":true}`),
+		entry(RecordReceived, 5, `{"type":"response","id":"a","success":true,"result":{"type":"output_text"}}`),
+		entry(RecordSent, 6, `{"id":"c","method":"invokeAgent","body":{"prompt":"bye"}}`),
+		entry(RecordReceived, 7, `{"type":"response","id":"c","success":true,"result":{"type":"bye"}}`),
+	})
+	replay.SetSpeed(0)
+	if replay.Requests() != 3 {
+		t.Fatalf("Expected 3 recorded requests, got %d", replay.Requests())
+	}
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- replay.Serve(context.Background(), inReader, outWriter) }()
+	lines := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a replayed message")
+			return ""
+		}
+	}
+	noMore := func() {
+		t.Helper()
+		select {
+		case line := <-lines:
+			t.Fatalf("Unexpected message: %s", line)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	send := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(inWriter, line+"\n"); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+
+	if line := next(); !strings.Contains(line, "__ready__") {
+		t.Errorf("Expected the startup notification first, got %s", line)
+	}
+
+	// Matched by body, out of recorded order, under the new ID
+	send(`{"id":"new-c","method":"invokeAgent","body":{"prompt":"bye"}}`)
+	if line := next(); !strings.Contains(line, `"id":"new-c"`) || !strings.Contains(line, `"bye"`) {
+		t.Errorf("Expected the reply to the second prompt, got %s", line)
+	}
+
+	// The final output waits for the response to the input request
+	send(`{"id":"new-a","method":"invokeAgent","body":{"prompt":"hello"}}`)
+	if line := next(); !strings.Contains(line, `"id":"new-a"`) || !strings.Contains(line, "response.requested") {
+		t.Errorf("Expected the input request, got %s", line)
+	}
+	noMore()
+	send(`{"id":"new-b","method":"respond","body":{"thread_id":"t1","response":"no"}}`)
+	first, second := next(), next()
+	if !strings.Contains(first+second, `"id":"new-b"`) || !strings.Contains(first+second, "output_text") {
+		t.Errorf("Expected the response reply and the final output, got %s and %s", first, second)
+	}
+
+	// Unknown methods get an error
+	send(`{"id":"x","method":"listToolkits"}`)
+	var reply Message
+	if err := json.Unmarshal([]byte(next()), &reply); err != nil {
+		t.Fatalf("Invalid reply: %v", err)
+	}
+	if reply.ID != "x" || reply.Type != MessageTypeError || reply.ErrorObj == nil || reply.ErrorObj.Code != ReplayNoMatchCode {
+		t.Errorf("Expected a no-match error, got %+v", reply)
+	}
+
+	inWriter.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %v", err)
+	}
+}
+
+func TestReplayOverlappingExchanges(t *testing.T) {
+	at := time.Now()
+	sent := func(offset int, data string) RecordEntry {
+		request := &RecordRequest{}
+		json.Unmarshal([]byte(data), request)
+		return RecordEntry{Time: at.Add(time.Duration(offset) * time.Millisecond), Direction: RecordSent, Request: request}
+	}
+	received := func(offset int, data string) RecordEntry {
+		return RecordEntry{Time: at.Add(time.Duration(offset) * time.Millisecond), Direction: RecordReceived, Message: json.RawMessage(data)}
+	}
+	// Two chats overlap: the second starts while the first waits for input,
+	// and the first is answered after the second has finished
+	replay := NewReplay([]RecordEntry{
+		sent(1, `{"id":"a","method":"invokeAgent","body":{"prompt":"refund"}}`),
+		received(2, `{"type":"response","id":"a","success":true,"result":{"type":"response.requested","threadId":"t1","prompt":"Refund $30?"}}`),
+		sent(3, `{"id":"b","method":"invokeAgent","body":{"prompt":"status","threadId":"t2"}}`),
+		received(4, `{"type":"response","id":"a","success":true,"result":{"type":"output_text_delta","threadId":"t1"}}`),
+		received(5, `{"type":"response","id":"b","success":true,"result":{"type":"output_text","threadId":"t2"}}`),
+		sent(6, `{"id":"c","method":"respond","body":{"thread_id":"t1","approved":true}}`),
+		received(7, `{"type":"response","id":"c","success":true}`),
+		received(8, `{"type":"response","id":"a","success":true,"result":{"type":"output_text","threadId":"t1"}}`),
+	})
+	replay.SetSpeed(0)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- replay.Serve(context.Background(), inReader, outWriter) }()
+	lines := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(outReader)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a replayed message")
+			return ""
+		}
+	}
+	noMore := func() {
+		t.Helper()
+		select {
+		case line := <-lines:
+			t.Fatalf("Unexpected message: %s", line)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	send := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(inWriter, line+"\n"); err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+	}
+
+	// The first chat's replies before the respond do not wait for the
+	// second chat, which is never sent
+	send(`{"id":"new-a","method":"invokeAgent","body":{"prompt":"refund"}}`)
+	if line := next(); !strings.Contains(line, "response.requested") {
+		t.Errorf("Expected the input request, got %s", line)
+	}
+	if line := next(); !strings.Contains(line, "output_text_delta") {
+		t.Errorf("Expected the text delta without the second chat, got %s", line)
+	}
+	noMore()
+
+	// Its final output still waits for the respond in its thread
+	send(`{"id":"new-c","method":"respond","body":{"thread_id":"t1","approved":true}}`)
+	first, second := next(), next()
+	if !strings.Contains(first+second, `"id":"new-c"`) || !strings.Contains(first+second, `"type":"output_text"`) {
+		t.Errorf("Expected the respond reply and the final output, got %s and %s", first, second)
+	}
+
+	inWriter.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %v", err)
+	}
+}
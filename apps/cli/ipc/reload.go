@@ -0,0 +1,136 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shuttl-ai/cli/log"
+)
+
+// ReloadedNotificationID is the ID of the notification emitted on Output()
+// after Reload starts a new app process
+const ReloadedNotificationID = "__reloaded__"
+
+// reloadGracePeriod is how long Reload waits for the app to exit after its
+// stdin is closed before killing it
+const reloadGracePeriod = 5 * time.Second
+
+// ErrAppReloaded is delivered to requests that were in flight when the app
+// was reloaded
+var ErrAppReloaded = errors.New("app was reloaded before the request completed")
+
+// ErrAppCrashed is returned when a request is sent while a client holding on
+// crash waits for Reload
+var ErrAppCrashed = errors.New("app crashed and is waiting for a reload")
+
+// SetHoldOnCrash makes an unsupervised client wait for Reload after the app
+// crashes instead of stopping. Used by `shuttl dev` so a broken edit can be
+// fixed without restarting the CLI. Must be called before Start.
+func (c *Client) SetHoldOnCrash(hold bool) {
+	c.holdOnCrash = hold
+}
+
+// Reload gracefully stops the app process and starts a new one with the same
+// command, keeping the client and its channels. Requests in flight fail with
+// ErrAppReloaded. A crashed client holding on crash is started again.
+func (c *Client) Reload() error {
+	done := make(chan error, 1)
+
+	c.stateMu.Lock()
+	state := c.state
+	switch state {
+	case StateRunning:
+		c.reloadDone = done
+	case StateCrashed:
+	default:
+		c.stateMu.Unlock()
+		return fmt.Errorf("cannot reload app while it is %s", state)
+	}
+	c.state = StateRestarting
+	c.stateMu.Unlock()
+
+	if state == StateCrashed {
+		c.reloadCh <- done
+	} else {
+		// Close stdin to ask the app to exit, as Stop does
+		c.sendMu.Lock()
+		c.conn.CloseWrite()
+		c.sendMu.Unlock()
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case <-time.After(reloadGracePeriod):
+		log.Warn("App did not exit within %s; killing it", reloadGracePeriod)
+		if err := c.killProcess(); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+// takeReload returns the channel of a pending Reload call, if any, and
+// clears it
+func (c *Client) takeReload() chan error {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	done := c.reloadDone
+	c.reloadDone = nil
+	return done
+}
+
+// awaitReload blocks a crashed client until Reload is called or the client
+// is stopped
+func (c *Client) awaitReload() (Conn, *sync.WaitGroup, bool) {
+	log.Warn("App crashed; waiting for a reload")
+	select {
+	case done := <-c.reloadCh:
+		return c.respawn(done)
+	case <-c.ctx.Done():
+		c.setState(StateStopped)
+		return nil, nil, false
+	}
+}
+
+// respawn starts the new process for a Reload call and reports the outcome
+// on done
+func (c *Client) respawn(done chan error) (Conn, *sync.WaitGroup, bool) {
+	conn, readers, err := c.connect()
+	if err != nil {
+		done <- err
+		if c.stopRequested() {
+			c.setState(StateStopped)
+			return nil, nil, false
+		}
+		log.Error("Failed to reload app: %v", err)
+		return c.recover(err)
+	}
+
+	// Stop may have been called while the process was starting
+	c.stateMu.Lock()
+	if c.state == StateRestarting {
+		c.state = StateRunning
+	}
+	c.stateMu.Unlock()
+
+	log.Info("Reloaded app (%s)", c.describe(conn))
+	c.emit(OutputLine{
+		Source:    "supervisor",
+		Content:   "app reloaded",
+		Message:   &Message{ID: ReloadedNotificationID, Timestamp: time.Now()},
+		Timestamp: time.Now(),
+	})
+	done <- nil
+	return conn, readers, true
+}
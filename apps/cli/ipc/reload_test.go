@@ -0,0 +1,83 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientReload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// cat echoes pings back rather than answering them
+	client := NewClient([]string{"cat"})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	firstPID := client.ProcessID()
+	if err := client.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if !client.IsRunning() {
+		t.Fatalf("Expected client to be running after reload, got %s", client.State())
+	}
+	if client.ProcessID() == firstPID {
+		t.Error("Expected a new process after reload")
+	}
+	if client.Restarts() != 0 {
+		t.Errorf("Expected reload not to count as a restart, got %d", client.Restarts())
+	}
+
+	// The reloaded process still answers requests
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id := getID(RequestListAgents)
+	output, err := client.SendAndWaitForResponse(ctx, Request{ID: id, Method: "listAgents"})
+	if err != nil {
+		t.Fatalf("Request after reload failed: %v", err)
+	}
+	if output.Message.ID != id {
+		t.Errorf("Expected reply to %s, got %s", id, output.Message.ID)
+	}
+}
+
+func TestClientHoldOnCrash(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	client := NewClient([]string{"sh", "-c", "read line; exit 3"})
+	// The handshake ping would be the request that crashes the app
+	client.SetHandshakeTimeout(0)
+	client.SetHoldOnCrash(true)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"})
+
+	waitFor(t, func() bool { return client.State() == StateCrashed })
+	if client.Context().Err() != nil {
+		t.Fatal("Expected the client to stay alive while crashed")
+	}
+	if err := client.Send(Request{ID: "x", Method: "ping"}); !errors.Is(err, ErrAppCrashed) {
+		t.Errorf("Expected ErrAppCrashed, got %v", err)
+	}
+
+	if err := client.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !client.IsRunning() {
+		t.Errorf("Expected client to be running after reload, got %s", client.State())
+	}
+}
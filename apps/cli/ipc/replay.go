@@ -0,0 +1,318 @@
+package ipc
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ReplayNoMatchCode is the error code of the reply a replay sends to a
+// request that has no counterpart in the recording
+const ReplayNoMatchCode = "REPLAY_NO_MATCH"
+
+// Replay stands in for an app by answering requests with the messages of a
+// recording (see Recorder). A request is matched to a recorded request with
+// the same method, preferring one with the same body, and its recorded
+// replies are sent back under the new request ID.
+type Replay struct {
+	// Messages received before the first request, sent when serving starts
+	startup   []json.RawMessage
+	exchanges []*replayExchange
+	speed     float64
+
+	mu      sync.Mutex
+	writeMu sync.Mutex
+}
+
+// replayExchange is a recorded request and the messages that followed it
+type replayExchange struct {
+	index   int
+	request RecordRequest
+	time    time.Time
+	replies []replayReply
+	used    bool
+	// thread is the thread the request belongs to, from its body or, for a
+	// request starting a thread, from its replies
+	thread string
+
+	once      sync.Once
+	matched   chan struct{}
+	matchedAt time.Time
+}
+
+type replayReply struct {
+	message json.RawMessage
+	time    time.Time
+	// after is the index of the last request of the same exchange or thread
+	// sent before the reply; the reply waits until that request has been
+	// replayed too, so answers to input requests wait for the response
+	after int
+}
+
+// NewReplay builds a replay from recorded entries. Replies are sent at the
+// recorded pace; see SetSpeed.
+func NewReplay(entries []RecordEntry) *Replay {
+	r := &Replay{speed: 1}
+	byID := map[string]*replayExchange{}
+	// The index of the last request sent in each thread
+	lastInThread := map[string]int{}
+	for _, entry := range entries {
+		if entry.Direction == RecordSent {
+			exchange := &replayExchange{
+				index:   len(r.exchanges),
+				request: *entry.Request,
+				time:    entry.Time,
+				thread:  requestThread(entry.Request.Body),
+				matched: make(chan struct{}),
+			}
+			r.exchanges = append(r.exchanges, exchange)
+			byID[exchange.request.ID] = exchange
+			if exchange.thread != "" {
+				lastInThread[exchange.thread] = exchange.index
+			}
+			continue
+		}
+		if len(r.exchanges) == 0 {
+			r.startup = append(r.startup, entry.Message)
+			continue
+		}
+
+		// Replies belong to their request; notifications to the last request
+		last := r.exchanges[len(r.exchanges)-1]
+		exchange := last
+		var header struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(entry.Message, &header) == nil {
+			if owner, ok := byID[header.ID]; ok {
+				exchange = owner
+			}
+		}
+		if exchange.thread == "" {
+			exchange.thread = replyThread(entry.Message)
+		}
+
+		// Requests of other exchanges, such as a prompt in another thread,
+		// may be sent in a different order or not at all
+		after := exchange.index
+		if index, ok := lastInThread[exchange.thread]; ok && exchange.thread != "" && index > after {
+			after = index
+		}
+		exchange.replies = append(exchange.replies, replayReply{message: entry.Message, time: entry.Time, after: after})
+	}
+	return r
+}
+
+// requestThread returns the thread named in a request body, such as the
+// thread_id of a respond or the threadId of a prompt or trigger
+func requestThread(body json.RawMessage) string {
+	var fields struct {
+		ThreadID      string `json:"thread_id"`
+		ThreadIDCamel string `json:"threadId"`
+	}
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	return cmp.Or(fields.ThreadID, fields.ThreadIDCamel)
+}
+
+// replyThread returns the thread named in the result of a reply
+func replyThread(message json.RawMessage) string {
+	var reply struct {
+		Result struct {
+			ThreadID string `json:"threadId"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(message, &reply) != nil {
+		return ""
+	}
+	return reply.Result.ThreadID
+}
+
+// LoadReplay reads a recording and builds a replay from it
+func LoadReplay(path string) (*Replay, error) {
+	entries, err := ReadRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplay(entries), nil
+}
+
+// SetSpeed scales the recorded delays between a request and its replies:
+// 2 replays twice as fast and 0 sends replies without delay
+func (r *Replay) SetSpeed(speed float64) {
+	r.speed = speed
+}
+
+// Requests returns the number of requests in the recording
+func (r *Replay) Requests() int {
+	return len(r.exchanges)
+}
+
+// Serve reads line-delimited requests from in and writes the recorded
+// replies to out, as an app would over its stdin and stdout. It returns when
+// in is closed or ctx is done.
+func (r *Replay) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, message := range r.startup {
+		if err := r.write(out, message); err != nil {
+			return err
+		}
+	}
+
+	requests := NewMessageReader(in)
+	for {
+		data, err := requests.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read requests: %w", err)
+		}
+		var req RecordRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+		exchange := r.match(req)
+		if exchange == nil {
+			if err := r.write(out, noMatchReply(req)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		started := time.Now()
+		exchange.once.Do(func() {
+			exchange.matchedAt = started
+			close(exchange.matched)
+		})
+		go r.replay(ctx, out, exchange, req.ID, started)
+	}
+}
+
+// match finds the recorded request answering req: an unused one with the
+// same body, then an unused one with the same method, then the last used one
+// with the same body or method so repeated requests are answered too
+func (r *Replay) match(req RecordRequest) *replayExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sameMethod, usedSameBody, usedSameMethod *replayExchange
+	for _, exchange := range r.exchanges {
+		if exchange.request.Method != req.Method {
+			continue
+		}
+		sameBody := jsonEqual(exchange.request.Body, req.Body)
+		switch {
+		case !exchange.used && sameBody:
+			exchange.used = true
+			return exchange
+		case !exchange.used && sameMethod == nil:
+			sameMethod = exchange
+		case exchange.used && sameBody:
+			usedSameBody = exchange
+		case exchange.used:
+			usedSameMethod = exchange
+		}
+	}
+	switch {
+	case sameMethod != nil:
+		sameMethod.used = true
+		return sameMethod
+	case usedSameBody != nil:
+		return usedSameBody
+	}
+	return usedSameMethod
+}
+
+// replay sends the replies of an exchange, rewritten to answer the request
+// with the given ID
+func (r *Replay) replay(ctx context.Context, out io.Writer, exchange *replayExchange, id string, started time.Time) {
+	for _, reply := range exchange.replies {
+		from, at := exchange.time, started
+		if reply.after > exchange.index {
+			gate := r.exchanges[reply.after]
+			select {
+			case <-gate.matched:
+			case <-ctx.Done():
+				return
+			}
+			from, at = gate.time, gate.matchedAt
+		}
+
+		if r.speed > 0 {
+			delay := time.Until(at.Add(time.Duration(float64(reply.time.Sub(from)) / r.speed)))
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		message := reply.message
+		if rewritten, err := withID(message, exchange.request.ID, id); err == nil {
+			message = rewritten
+		}
+		if err := r.write(out, message); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Replay) write(out io.Writer, message json.RawMessage) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	_, err := out.Write(append(append([]byte{}, message...), '\n'))
+	return err
+}
+
+// withID replaces the ID of a message answering the request recordedID
+func withID(message json.RawMessage, recordedID, id string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return nil, err
+	}
+	var messageID string
+	if err := json.Unmarshal(fields["id"], &messageID); err != nil || messageID != recordedID {
+		return message, nil
+	}
+	fields["id"], _ = json.Marshal(id)
+	return json.Marshal(fields)
+}
+
+// noMatchReply is the error sent for a request missing from the recording
+func noMatchReply(req RecordRequest) json.RawMessage {
+	data, _ := json.Marshal(Message{
+		Type:      MessageTypeError,
+		ID:        req.ID,
+		Timestamp: time.Now(),
+		ErrorObj: &ErrorObject{
+			Code:    ReplayNoMatchCode,
+			Message: fmt.Sprintf("the recording has no %s request", req.Method),
+		},
+	})
+	return data
+}
+
+// jsonEqual reports whether two JSON documents hold the same value. Empty
+// documents equal null.
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if len(a) > 0 && json.Unmarshal(a, &va) != nil {
+		return false
+	}
+	if len(b) > 0 && json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,96 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shuttl-ai/cli/log"
+)
+
+// Kinds of input an agent can ask for
+const (
+	InputKindText     = "input"
+	InputKindApproval = "approval"
+)
+
+// InputRequest is a response.requested event in which the agent waits for
+// the user, e.g.
+// {"typeName":"response.requested","threadId":"t1","requestId":"r1","prompt":"Refund $30?","kind":"approval"}
+//
+// The SDK also sends response.requested, without a prompt, each time it calls
+// the model; those are not input requests.
+type InputRequest struct {
+	ThreadID  string   `json:"threadId"`
+	RequestID string   `json:"requestId,omitempty"`
+	Prompt    string   `json:"prompt"`
+	Kind      string   `json:"kind,omitempty"`
+	Options   []string `json:"options,omitempty"`
+}
+
+// IsApproval reports whether the agent asks for a yes or no answer
+func (r *InputRequest) IsApproval() bool {
+	return r.Kind == InputKindApproval
+}
+
+// ParseInputRequest decodes the result of a response.requested event,
+// returning nil if it does not ask the user for anything
+func ParseInputRequest(raw json.RawMessage) *InputRequest {
+	var request InputRequest
+	if err := json.Unmarshal(raw, &request); err != nil || request.Prompt == "" {
+		return nil
+	}
+	if request.Kind == "" {
+		request.Kind = InputKindText
+	}
+	return &request
+}
+
+// inputRequestThread returns the thread of an input request, and whether msg
+// is one
+func inputRequestThread(msg *Message) (string, bool) {
+	if msg == nil || msg.Type != "response.requested" {
+		return "", false
+	}
+	request := ParseInputRequest(msg.Result)
+	if request == nil {
+		return "", false
+	}
+	return request.ThreadID, true
+}
+
+// InputResponse is the user's reply to an InputRequest. Approved is set for
+// approvals; Response holds the text typed or the option picked.
+type InputResponse struct {
+	ThreadID  string `json:"thread_id"`
+	RequestID string `json:"request_id,omitempty"`
+	Response  string `json:"response,omitempty"`
+	Approved  *bool  `json:"approved,omitempty"`
+}
+
+// Respond sends the user's reply to an agent waiting for input. The agent's
+// replies keep arriving on the stream of the chat that asked.
+func (c *Client) Respond(ctx context.Context, response InputResponse) error {
+	c.wg.Add(1)
+	defer c.wg.Done()
+	if response.ThreadID == "" {
+		return fmt.Errorf("a thread ID is required to respond to an agent")
+	}
+	id := getID(RequestRespond)
+	req := Request{
+		ID:     id,
+		Method: "respond",
+		Body:   response,
+	}
+	log.Debug("Responding to input request in thread %s", response.ThreadID)
+	output, err := c.SendAndWaitForResponse(ctx, req)
+	if err != nil {
+		return err
+	}
+	if output.Message != nil && !output.Message.Success && output.Message.ErrorObj != nil {
+		return output.Message.ErrorObj
+	}
+	// The app is busy again, so the chat may time out again
+	c.pending.resume(response.ThreadID)
+	return nil
+}
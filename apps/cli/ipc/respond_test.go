@@ -0,0 +1,111 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseInputRequest(t *testing.T) {
+	t.Run("approval", func(t *testing.T) {
+		request := ParseInputRequest(json.RawMessage(`{"typeName":"response.requested","threadId":"t1","requestId":"r1","prompt":"Refund $30?","kind":"approval"}`))
+		if request == nil {
+			t.Fatal("Expected an input request")
+		}
+		if request.ThreadID != "t1" || request.RequestID != "r1" || !request.IsApproval() {
+			t.Errorf("Unexpected input request: %+v", request)
+		}
+	})
+
+	t.Run("kind defaults to input", func(t *testing.T) {
+		request := ParseInputRequest(json.RawMessage(`{"threadId":"t1","prompt":"Which account?","options":["personal","business"]}`))
+		if request == nil || request.Kind != InputKindText || len(request.Options) != 2 {
+			t.Errorf("Unexpected input request: %+v", request)
+		}
+	})
+
+	t.Run("model requests are not input requests", func(t *testing.T) {
+		if request := ParseInputRequest(json.RawMessage(`{"typeName":"response.requested","requested":{"model":"gpt-4.1"},"threadId":"t1"}`)); request != nil {
+			t.Errorf("Expected no input request, got %+v", request)
+		}
+	})
+
+	t.Run("parseResult", func(t *testing.T) {
+		result, err := parseResult("response.requested", json.RawMessage(`{"threadId":"t1","prompt":"Continue?","kind":"approval"}`))
+		if err != nil {
+			t.Fatalf("parseResult failed: %v", err)
+		}
+		if result.InputRequest == nil || result.ResponseRequested == nil {
+			t.Errorf("Expected the input request and the raw payload, got %+v", result)
+		}
+	})
+}
+
+func TestPendingRequestTimeoutPausesForInput(t *testing.T) {
+	table := newPendingTable()
+	request, err := table.register(Request{ID: "stream", Method: "invokeAgent"}, 50*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	defer table.release("stream")
+
+	request.deliver(OutputLine{Message: &Message{
+		Type:   "response.requested",
+		Result: json.RawMessage(`{"threadId":"t1","prompt":"Approve?","kind":"approval"}`),
+	}})
+
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-request.errChan:
+		t.Fatalf("Expected no timeout while waiting for the user, got %v", err)
+	default:
+	}
+
+	table.resume("other-thread")
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-request.errChan:
+		t.Fatalf("Expected no timeout after a reply in another thread, got %v", err)
+	default:
+	}
+
+	table.resume("t1")
+	select {
+	case err := <-request.errChan:
+		if _, ok := err.(*RequestTimeoutError); !ok {
+			t.Errorf("Expected RequestTimeoutError, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the timeout to restart after the reply")
+	}
+}
+
+func TestClientRespond(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// cat echoes pings back rather than answering them
+	client := NewClient([]string{"cat"})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Respond(ctx, InputResponse{}); err == nil {
+		t.Error("Expected an error without a thread ID")
+	}
+
+	approved := true
+	if err := client.Respond(ctx, InputResponse{ThreadID: "t1", RequestID: "r1", Approved: &approved}); err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if client.PendingRequests() != 0 {
+		t.Errorf("Expected no pending requests, got %d", client.PendingRequests())
+	}
+}
@@ -0,0 +1,70 @@
+package ipc
+
+import "sync/atomic"
+
+// Reasons a message from the app is dropped, as reported by DroppedMessages
+const (
+	// DropOutputFull counts lines discarded because nobody was reading the
+	// shared output channel
+	DropOutputFull = "output_full"
+	// DropErrorFull counts errors discarded because the error channel was full
+	DropErrorFull = "error_full"
+	// DropOrphaned counts replies to requests that were no longer pending
+	DropOrphaned = "orphaned"
+	// DropLogFull counts log events discarded because nobody was reading
+	// Logs()
+	DropLogFull = "log_full"
+)
+
+// dropCounters counts dropped messages by reason
+type dropCounters struct {
+	outputFull atomic.Uint64
+	errorFull  atomic.Uint64
+	orphaned   atomic.Uint64
+	logFull    atomic.Uint64
+}
+
+// DroppedMessages returns how many messages have been dropped, by reason
+func (c *Client) DroppedMessages() map[string]uint64 {
+	return map[string]uint64{
+		DropOutputFull: c.dropped.outputFull.Load(),
+		DropErrorFull:  c.dropped.errorFull.Load(),
+		DropOrphaned:   c.dropped.orphaned.Load(),
+		DropLogFull:    c.dropped.logFull.Load(),
+	}
+}
+
+// Drain reads Output() and Errors() until the client stops, passing what it
+// reads to onOutput and onError, either of which may be nil. Servers that
+// take replies from their requests run it in place of reading the shared
+// channels, so that QueueDepth and DroppedMessages count messages nobody
+// handled rather than a channel nobody reads.
+func (c *Client) Drain(onOutput func(OutputLine), onError func(error)) {
+	outputs, errs := c.Output(), c.Errors()
+	for outputs != nil || errs != nil {
+		select {
+		case output, ok := <-outputs:
+			if !ok {
+				outputs = nil
+			} else if onOutput != nil {
+				onOutput(output)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+			} else if onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// QueueDepth returns the number of lines waiting in the shared output channel
+func (c *Client) QueueDepth() int {
+	return len(c.outputChan)
+}
+
+// QueueCapacity returns the size of the shared output channel
+func (c *Client) QueueCapacity() int {
+	return cap(c.outputChan)
+}
@@ -0,0 +1,87 @@
+package ipc
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDroppedMessages(t *testing.T) {
+	client := NewClient([]string{"echo"})
+
+	// Nobody reads the output channel, so the oldest notifications go
+	for i := 0; i < client.QueueCapacity()+3; i++ {
+		client.route(OutputLine{Content: "{}", Message: &Message{ID: fmt.Sprintf("__note__%d", i)}})
+	}
+	if depth := client.QueueDepth(); depth != client.QueueCapacity() {
+		t.Errorf("Expected a full queue of %d, got %d", client.QueueCapacity(), depth)
+	}
+
+	// Orphaned replies are counted and reported until the error channel fills
+	for i := 0; i < cap(client.errChan)+2; i++ {
+		client.route(OutputLine{Content: "{}", Message: &Message{ID: fmt.Sprintf("unknown:%d", i)}})
+	}
+
+	dropped := client.DroppedMessages()
+	expected := map[string]uint64{
+		DropOutputFull: 3,
+		DropOrphaned:   uint64(cap(client.errChan) + 2),
+		DropErrorFull:  2,
+	}
+	for reason, count := range expected {
+		if dropped[reason] != count {
+			t.Errorf("Expected %d messages dropped for %s, got %d", count, reason, dropped[reason])
+		}
+	}
+}
+
+func TestClientDrain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// More notifications over time than the output channel holds, in
+	// bursts it does hold, then replies to requests that were never sent
+	notes, orphans := 300, 5
+	script := fmt.Sprintf(`
+i=0; while [ $i -lt %d ]; do
+  printf '{"id":"__note__"}\n'; i=$((i+1))
+  [ $((i %% 50)) -eq 0 ] && sleep 0.05
+done
+i=0; while [ $i -lt %d ]; do printf '{"id":"unknown:%%d"}\n' $i; i=$((i+1)); done`, notes, orphans)
+	client := NewClient([]string{"sh", "-c", script})
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	var outputs, errs atomic.Int64
+	drained := make(chan struct{})
+	go func() {
+		client.Drain(func(output OutputLine) {
+			if strings.Contains(output.Content, "__note__") {
+				outputs.Add(1)
+			}
+		}, func(error) { errs.Add(1) })
+		close(drained)
+	}()
+	<-drained
+
+	if got := outputs.Load(); got != int64(notes) {
+		t.Errorf("Expected all %d notifications to be drained, got %d", notes, got)
+	}
+	if got := errs.Load(); got != int64(orphans) {
+		t.Errorf("Expected %d orphaned reply errors, got %d", orphans, got)
+	}
+	if depth := client.QueueDepth(); depth != 0 {
+		t.Errorf("Expected an empty queue, got %d", depth)
+	}
+	expected := map[string]uint64{DropOutputFull: 0, DropErrorFull: 0, DropOrphaned: uint64(orphans)}
+	for reason, count := range client.DroppedMessages() {
+		if want, ok := expected[reason]; ok && count != want {
+			t.Errorf("Expected %d messages dropped for %s, got %d", want, reason, count)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RestartedNotificationID is the ID of the notification emitted on Output()
+// after the supervisor restarts the app
+const RestartedNotificationID = "__restarted__"
+
+// ErrAppRestarting is returned when a request is sent while the app is being
+// restarted or reloaded
+var ErrAppRestarting = errors.New("app is restarting")
+
+// RestartPolicy controls how a supervised client restarts its app after the
+// process exits unexpectedly
+type RestartPolicy struct {
+	// MaxRestarts caps the total number of restarts; zero means unlimited
+	MaxRestarts int
+	// InitialBackoff is the delay before restarting after a single crash.
+	// It doubles for every further crash within CrashLoopWindow.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between restarts
+	MaxBackoff time.Duration
+	// CrashLoopThreshold crashes within CrashLoopWindow trip the breaker and
+	// the client gives up; zero disables the breaker
+	CrashLoopThreshold int
+	CrashLoopWindow    time.Duration
+}
+
+// DefaultRestartPolicy returns the policy used by `--restart`
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{
+		MaxRestarts:        0,
+		InitialBackoff:     500 * time.Millisecond,
+		MaxBackoff:         30 * time.Second,
+		CrashLoopThreshold: 5,
+		CrashLoopWindow:    2 * time.Minute,
+	}
+}
+
+// ProcessCrashedError is delivered to requests that were in flight when the
+// app process exited unexpectedly
+type ProcessCrashedError struct {
+	PID        int
+	Err        error
+	Restarting bool
+}
+
+func (e *ProcessCrashedError) Error() string {
+	reason := "exited"
+	if e.Err != nil {
+		reason = e.Err.Error()
+	}
+	if e.PID == 0 {
+		// Attached over a socket, so there is no process to name
+		if e.Restarting {
+			return fmt.Sprintf("app disconnected (%s); reconnecting", reason)
+		}
+		return fmt.Sprintf("app disconnected (%s)", reason)
+	}
+	if e.Restarting {
+		return fmt.Sprintf("app process %d crashed (%s); restarting", e.PID, reason)
+	}
+	return fmt.Sprintf("app process %d crashed (%s)", e.PID, reason)
+}
+
+func (e *ProcessCrashedError) Unwrap() error {
+	return e.Err
+}
+
+// CrashLoopError is the reason a supervised client stops restarting its app
+type CrashLoopError struct {
+	Crashes  int
+	Window   time.Duration
+	Restarts int
+	// LimitReached is true when MaxRestarts was hit rather than the breaker
+	LimitReached bool
+	LastErr      error
+}
+
+func (e *CrashLoopError) Error() string {
+	if e.LimitReached {
+		return fmt.Sprintf("app crashed after %d restarts, the restart limit; giving up: %v", e.Restarts, e.LastErr)
+	}
+	return fmt.Sprintf("app crashed %d times within %s; giving up: %v", e.Crashes, e.Window, e.LastErr)
+}
+
+func (e *CrashLoopError) Unwrap() error {
+	return e.LastErr
+}
+
+// IsAppUnavailable reports whether err means the app was not running to
+// handle a request, as opposed to the request itself failing
+func IsAppUnavailable(err error) bool {
+	var crashErr *ProcessCrashedError
+	var loopErr *CrashLoopError
+	return errors.Is(err, ErrAppRestarting) || errors.Is(err, ErrAppReloaded) || errors.Is(err, ErrAppCrashed) ||
+		errors.As(err, &crashErr) || errors.As(err, &loopErr)
+}
+
+// ProcessStatus describes the app process and its restart history
+type ProcessStatus struct {
+	State           string     `json:"state"`
+	PID             int        `json:"pid,omitempty"`
+	ProtocolVersion string     `json:"protocolVersion,omitempty"`
+	Supervised      bool       `json:"supervised"`
+	Restarts        int        `json:"restarts"`
+	LastCrash       string     `json:"lastCrash,omitempty"`
+	LastCrashAt     *time.Time `json:"lastCrashAt,omitempty"`
+	LastRestartAt   *time.Time `json:"lastRestartAt,omitempty"`
+	GaveUp          string     `json:"gaveUp,omitempty"`
+}
+
+// supervisor keeps the crash history used to apply a RestartPolicy
+type supervisor struct {
+	policy RestartPolicy
+
+	mu            sync.Mutex
+	crashes       []time.Time
+	restarts      int
+	lastCrash     error
+	lastCrashAt   time.Time
+	lastRestartAt time.Time
+	gaveUp        error
+}
+
+func newSupervisor(policy RestartPolicy) *supervisor {
+	return &supervisor{policy: policy}
+}
+
+// crashed records a crash and returns how long to wait before restarting, or
+// a *CrashLoopError if the app should not be restarted again
+func (s *supervisor) crashed(err error, at time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCrash = err
+	s.lastCrashAt = at
+
+	// Only crashes within the window count towards the breaker and backoff
+	recent := s.crashes[:0]
+	for _, crash := range s.crashes {
+		if s.policy.CrashLoopWindow <= 0 || at.Sub(crash) < s.policy.CrashLoopWindow {
+			recent = append(recent, crash)
+		}
+	}
+	s.crashes = append(recent, at)
+
+	if s.policy.MaxRestarts > 0 && s.restarts >= s.policy.MaxRestarts {
+		s.gaveUp = &CrashLoopError{Restarts: s.restarts, LimitReached: true, LastErr: err}
+		return 0, s.gaveUp
+	}
+	if s.policy.CrashLoopThreshold > 0 && len(s.crashes) >= s.policy.CrashLoopThreshold {
+		s.gaveUp = &CrashLoopError{
+			Crashes:  len(s.crashes),
+			Window:   s.policy.CrashLoopWindow,
+			Restarts: s.restarts,
+			LastErr:  err,
+		}
+		return 0, s.gaveUp
+	}
+
+	return s.backoff(len(s.crashes)), nil
+}
+
+// backoff returns the delay before the restart that follows the nth recent crash
+func (s *supervisor) backoff(n int) time.Duration {
+	delay := s.policy.InitialBackoff
+	for i := 1; i < n; i++ {
+		delay *= 2
+		if s.policy.MaxBackoff > 0 && delay >= s.policy.MaxBackoff {
+			return s.policy.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// restarted records a successful restart
+func (s *supervisor) restarted(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restarts++
+	s.lastRestartAt = at
+}
+
+// fill copies the restart history into a status
+func (s *supervisor) fill(status *ProcessStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status.Supervised = true
+	status.Restarts = s.restarts
+	if s.lastCrash != nil {
+		status.LastCrash = s.lastCrash.Error()
+	}
+	if !s.lastCrashAt.IsZero() {
+		at := s.lastCrashAt
+		status.LastCrashAt = &at
+	}
+	if !s.lastRestartAt.IsZero() {
+		at := s.lastRestartAt
+		status.LastRestartAt = &at
+	}
+	if s.gaveUp != nil {
+		status.GaveUp = s.gaveUp.Error()
+	}
+}
@@ -0,0 +1,200 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSupervisorBackoff(t *testing.T) {
+	s := newSupervisor(RestartPolicy{
+		InitialBackoff:  100 * time.Millisecond,
+		MaxBackoff:      500 * time.Millisecond,
+		CrashLoopWindow: time.Minute,
+	})
+
+	now := time.Now()
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+	}
+	for i, want := range expected {
+		delay, err := s.crashed(fmt.Errorf("crash %d", i), now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("crash %d: unexpected error %v", i, err)
+		}
+		if delay != want {
+			t.Errorf("crash %d: expected delay %s, got %s", i, want, delay)
+		}
+		s.restarted(now)
+	}
+
+	// Crashes outside the window no longer count towards the backoff
+	delay, err := s.crashed(errors.New("much later"), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if delay != 100*time.Millisecond {
+		t.Errorf("Expected backoff to reset after the window, got %s", delay)
+	}
+}
+
+func TestSupervisorCrashLoopBreaker(t *testing.T) {
+	s := newSupervisor(RestartPolicy{
+		InitialBackoff:     time.Millisecond,
+		CrashLoopThreshold: 3,
+		CrashLoopWindow:    time.Minute,
+	})
+
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := s.crashed(errors.New("boom"), now); err != nil {
+			t.Fatalf("crash %d: unexpected error %v", i, err)
+		}
+		s.restarted(now)
+	}
+
+	_, err := s.crashed(errors.New("boom"), now)
+	var loopErr *CrashLoopError
+	if !errors.As(err, &loopErr) {
+		t.Fatalf("Expected CrashLoopError, got %v", err)
+	}
+	if loopErr.LimitReached || loopErr.Crashes != 3 {
+		t.Errorf("Unexpected crash loop error: %+v", loopErr)
+	}
+
+	var status ProcessStatus
+	s.fill(&status)
+	if status.Restarts != 2 || status.GaveUp == "" || status.LastCrash != "boom" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestSupervisorMaxRestarts(t *testing.T) {
+	s := newSupervisor(RestartPolicy{MaxRestarts: 1, InitialBackoff: time.Millisecond})
+
+	now := time.Now()
+	if _, err := s.crashed(errors.New("first"), now); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s.restarted(now)
+
+	_, err := s.crashed(errors.New("second"), now)
+	var loopErr *CrashLoopError
+	if !errors.As(err, &loopErr) || !loopErr.LimitReached {
+		t.Fatalf("Expected restart limit error, got %v", err)
+	}
+}
+
+func TestIsAppUnavailable(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{ErrAppRestarting, true},
+		{&ProcessCrashedError{PID: 1}, true},
+		{fmt.Errorf("wrapped: %w", &CrashLoopError{}), true},
+		{errors.New("tool failed"), false},
+		{nil, false},
+	}
+
+	for _, tc := range testCases {
+		if got := IsAppUnavailable(tc.err); got != tc.expected {
+			t.Errorf("IsAppUnavailable(%v) = %v, expected %v", tc.err, got, tc.expected)
+		}
+	}
+}
+
+func TestClientRestartsCrashedApp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	// Exits as soon as it receives a request, taking the request with it
+	client := NewClient([]string{"sh", "-c", "read line; exit 3"})
+	// The handshake ping would be the request that crashes the app
+	client.SetHandshakeTimeout(0)
+	client.SetRestartPolicy(RestartPolicy{
+		InitialBackoff:     10 * time.Millisecond,
+		CrashLoopThreshold: 3,
+		CrashLoopWindow:    time.Minute,
+	})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"})
+	var crashErr *ProcessCrashedError
+	if !errors.As(err, &crashErr) {
+		t.Fatalf("Expected ProcessCrashedError, got %v", err)
+	}
+	if !crashErr.Restarting {
+		t.Error("Expected crash error to report a restart")
+	}
+
+	waitFor(t, func() bool { return client.IsRunning() && client.Restarts() == 1 })
+
+	// Crash twice more to trip the breaker
+	for i := 0; i < 2; i++ {
+		waitFor(t, client.IsRunning)
+		client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"})
+	}
+
+	waitFor(t, func() bool { return client.State() == StateStopped })
+	status := client.Status()
+	if status.GaveUp == "" {
+		t.Errorf("Expected the supervisor to give up, got %+v", status)
+	}
+	if client.Context().Err() == nil {
+		t.Error("Expected the client context to be cancelled after giving up")
+	}
+}
+
+func TestClientWithoutRestartPolicyStops(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	client := NewClient([]string{"sh", "-c", "read line; exit 3"})
+	// The handshake ping would be the request that crashes the app
+	client.SetHandshakeTimeout(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"})
+	var crashErr *ProcessCrashedError
+	if !errors.As(err, &crashErr) || crashErr.Restarting {
+		t.Fatalf("Expected non-restarting ProcessCrashedError, got %v", err)
+	}
+
+	waitFor(t, func() bool { return client.State() == StateStopped })
+	if client.Restarts() != 0 {
+		t.Errorf("Expected no restarts, got %d", client.Restarts())
+	}
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
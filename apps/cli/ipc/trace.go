@@ -0,0 +1,97 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of the IPC spans
+const tracerName = "github.com/shuttl-ai/cli/ipc"
+
+// SetTracerProvider sets the provider of the client's IPC spans. By default
+// the global tracer provider is used, which does nothing unless a command
+// sets one up (see the tracing package). Must be called before Start.
+func (c *Client) SetTracerProvider(provider trace.TracerProvider) {
+	c.tracer = provider.Tracer(tracerName)
+}
+
+// Span attributes recorded for IPC requests and tool calls
+const (
+	AttrMessageID = attribute.Key("shuttl.ipc.message_id")
+	AttrMethod    = attribute.Key("shuttl.ipc.method")
+	AttrTool      = attribute.Key("shuttl.tool.name")
+	AttrToolCall  = attribute.Key("shuttl.tool.call_id")
+)
+
+// requestTrace is the span of a pending request and the spans of the tool
+// calls it is waiting on, keyed by call ID
+type requestTrace struct {
+	tracer trace.Tracer
+	ctx    context.Context
+	span   trace.Span
+	tools  map[string]trace.Span
+}
+
+// startTrace starts the span for a request sent with ctx
+func startTrace(ctx context.Context, tracer trace.Tracer, req Request) *requestTrace {
+	ctx, span := tracer.Start(ctx, "ipc "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrMessageID.String(req.ID), AttrMethod.String(req.Method)),
+	)
+	return &requestTrace{tracer: tracer, ctx: ctx, span: span, tools: make(map[string]trace.Span)}
+}
+
+// observe starts a span when the app reports a tool_call and ends the spans
+// of the calls listed in tool_calls_completed
+func (t *requestTrace) observe(msg *Message) {
+	if msg == nil {
+		return
+	}
+	switch msg.Type {
+	case "tool_call":
+		var toolCall ToolCallResult
+		if err := json.Unmarshal(msg.Result, &toolCall); err != nil || toolCall.ToolCall.CallID == "" {
+			return
+		}
+		_, span := t.tracer.Start(t.ctx, "tool "+toolCall.ToolCall.Name, trace.WithAttributes(
+			AttrTool.String(toolCall.ToolCall.Name),
+			AttrToolCall.String(toolCall.ToolCall.CallID),
+		))
+		t.tools[toolCall.ToolCall.CallID] = span
+
+	case "tool_calls_completed":
+		var completed ToolCallCompletedResult
+		if err := json.Unmarshal(msg.Result, &completed); err != nil {
+			return
+		}
+		for _, call := range completed {
+			if span, ok := t.tools[call.CallID]; ok {
+				if call.Failed() {
+					span.SetStatus(codes.Error, "tool call failed")
+				}
+				span.End()
+				delete(t.tools, call.CallID)
+			}
+		}
+	}
+}
+
+// fail records an error on the request span
+func (t *requestTrace) fail(err error) {
+	t.span.RecordError(err)
+	t.span.SetStatus(codes.Error, err.Error())
+}
+
+// end ends the request span. Tool calls that never completed are ended too
+// and marked as unfinished.
+func (t *requestTrace) end() {
+	for _, span := range t.tools {
+		span.SetStatus(codes.Error, "request ended before the tool call completed")
+		span.End()
+	}
+	t.span.End()
+}
@@ -0,0 +1,98 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	table := newPendingTable()
+	req := Request{ID: "invoke_trigger:A:api:1", Method: "invokeTrigger"}
+	p, err := table.register(req, 0, 10)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	p.startTrace(context.Background(), provider.Tracer(tracerName), req)
+
+	message := func(msgType string, result any) OutputLine {
+		data, _ := json.Marshal(result)
+		return OutputLine{Message: &Message{ID: req.ID, Type: MessageType(msgType), Success: true, Result: data}}
+	}
+	toolCall := func(name, callID string) map[string]any {
+		return map[string]any{"typeName": "tool_call", "toolCall": map[string]any{"name": name, "callId": callID}}
+	}
+	p.deliver(message("tool_call", toolCall("search", "c1")))
+	p.deliver(message("tool_call", toolCall("add", "c2")))
+	p.deliver(message("tool_calls_completed", []map[string]any{{"call_id": "c1", "output": "ok"}}))
+	p.fail(errors.New("boom"))
+	table.release(req.ID)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 ended spans, got %d", len(spans))
+	}
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+
+	request := byName["ipc invokeTrigger"]
+	if request == nil {
+		t.Fatal("Expected an ipc invokeTrigger span")
+	}
+	if request.Status().Code != codes.Error {
+		t.Errorf("Expected the failed request span to have an error status, got %v", request.Status())
+	}
+	for _, name := range []string{"tool search", "tool add"} {
+		span := byName[name]
+		if span == nil {
+			t.Fatalf("Expected a %q span", name)
+		}
+		if span.Parent().SpanID() != request.SpanContext().SpanID() {
+			t.Errorf("Expected %q to be a child of the request span", name)
+		}
+	}
+	if byName["tool search"].Status().Code == codes.Error {
+		t.Error("Expected the completed tool call to succeed")
+	}
+	if byName["tool add"].Status().Code != codes.Error {
+		t.Error("Expected the unfinished tool call to be marked as an error")
+	}
+}
+
+func TestClientTracerProvider(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	client := NewClient([]string{"cat"})
+	client.SetHandshakeTimeout(0)
+	client.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+	defer client.Close()
+
+	// cat echoes the request, which serves as its reply
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.SendAndWaitForResponse(ctx, Request{ID: getID(RequestListAgents), Method: "listAgents"}); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "ipc listAgents" {
+		t.Fatalf("Expected the client's provider to record an ipc listAgents span, got %d spans", len(spans))
+	}
+}
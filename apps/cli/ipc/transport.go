@@ -0,0 +1,230 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Transport connects a client to an app. The protocol is the same on every
+// transport: line-delimited JSON requests in, line-delimited JSON messages
+// out.
+type Transport interface {
+	// Open starts the app or connects to it. Cancelling ctx must end the
+	// connection.
+	Open(ctx context.Context) (Conn, error)
+	// String describes the app for logs and errors
+	String() string
+}
+
+// Conn is an open connection to an app
+type Conn interface {
+	// Write sends requests to the app
+	io.Writer
+	// Output returns the messages written by the app
+	Output() io.Reader
+	// Stderr returns the app's stderr, or nil if the transport carries none
+	Stderr() io.Reader
+	// CloseWrite tells the app no more requests will come, which asks it
+	// to finish
+	CloseWrite() error
+	// Kill ends the connection at once, killing the app if the transport
+	// started it
+	Kill() error
+	// Wait blocks until the app exits or disconnects. It is called after
+	// the output has been read to the end.
+	Wait() error
+	// PID returns the app's process ID, or 0 if it is not known
+	PID() int
+}
+
+// ErrDisconnected is returned by Wait when an app the client attached to
+// closes the connection
+var ErrDisconnected = errors.New("app closed the connection")
+
+// ParseTransport returns the transport for an address of an app that is
+// already running: unix:///path/to/app.sock or tcp://host:port
+func ParseTransport(address string) (Transport, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app address %q: %w", address, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			// unix:app.sock is relative to the working directory
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("invalid app address %q: missing socket path", address)
+		}
+		return &SocketTransport{Network: "unix", Address: path}, nil
+	case "tcp":
+		if u.Host == "" || u.Port() == "" {
+			return nil, fmt.Errorf("invalid app address %q: use tcp://host:port", address)
+		}
+		return &SocketTransport{Network: "tcp", Address: u.Host}, nil
+	}
+	return nil, fmt.Errorf("unsupported app address %q; use unix:///path/to/app.sock or tcp://host:port", address)
+}
+
+// StdioTransport starts the app as a subprocess and talks to it over its
+// stdin and stdout. Its stderr is read as log output.
+type StdioTransport struct {
+	Command []string
+}
+
+// Open starts the app process
+func (t *StdioTransport) Open(ctx context.Context) (Conn, error) {
+	if len(t.Command) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+
+	// Create the command with arguments
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Env = append(os.Environ(), "_SHUTTL_CONTROL=true")
+
+	// Get pipes
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+
+	// Start the process
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+	return &stdioConn{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr}, nil
+}
+
+func (t *StdioTransport) String() string {
+	return strings.Join(t.Command, " ")
+}
+
+type stdioConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr io.Reader
+}
+
+func (c *stdioConn) Write(p []byte) (int, error) {
+	n, err := c.stdin.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to stdin: %w", err)
+	}
+	return n, nil
+}
+
+func (c *stdioConn) Output() io.Reader { return c.stdout }
+func (c *stdioConn) Stderr() io.Reader { return c.stderr }
+func (c *stdioConn) CloseWrite() error { return c.stdin.Close() }
+func (c *stdioConn) Wait() error       { return c.cmd.Wait() }
+func (c *stdioConn) PID() int          { return c.cmd.Process.Pid }
+
+func (c *stdioConn) Kill() error {
+	if err := c.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+	return nil
+}
+
+// SocketTransport connects to an app that is already running and listening
+// on a Unix domain socket or TCP address, such as an app started in a
+// debugger or a container. The app's stderr is not carried.
+type SocketTransport struct {
+	// Network is "unix" or "tcp"
+	Network string
+	Address string
+}
+
+// Open connects to the app
+func (t *SocketTransport) Open(ctx context.Context) (Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, t.Network, t.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to app at %s: %w", t, err)
+	}
+	c := &socketConn{conn: conn}
+	c.stop = context.AfterFunc(ctx, func() { c.Kill() })
+	return c, nil
+}
+
+func (t *SocketTransport) String() string {
+	if t.Network == "unix" {
+		return "unix://" + t.Address
+	}
+	return t.Network + "://" + t.Address
+}
+
+type socketConn struct {
+	conn net.Conn
+	stop func() bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *socketConn) Write(p []byte) (int, error) {
+	n, err := c.conn.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to app: %w", err)
+	}
+	return n, nil
+}
+
+func (c *socketConn) Output() io.Reader { return c.conn }
+func (c *socketConn) Stderr() io.Reader { return nil }
+func (c *socketConn) PID() int          { return 0 }
+
+// CloseWrite half-closes the connection so the app reads EOF but can still
+// send the replies it owes
+func (c *socketConn) CloseWrite() error {
+	if conn, ok := c.conn.(interface{ CloseWrite() error }); ok {
+		return conn.CloseWrite()
+	}
+	return c.Kill()
+}
+
+func (c *socketConn) Kill() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
+
+// Wait closes the connection once the app has stopped sending. The app is
+// not ours to wait for, so ending the connection is reported as
+// ErrDisconnected.
+func (c *socketConn) Wait() error {
+	c.stop()
+	c.mu.Lock()
+	killed := c.closed
+	c.mu.Unlock()
+	c.Kill()
+	if killed {
+		return nil
+	}
+	return ErrDisconnected
+}
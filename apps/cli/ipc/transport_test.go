@@ -0,0 +1,170 @@
+package ipc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTransport(t *testing.T) {
+	testCases := []struct {
+		address string
+		network string
+		target  string
+		wantErr bool
+	}{
+		{"unix:///tmp/app.sock", "unix", "/tmp/app.sock", false},
+		{"unix:app.sock", "unix", "app.sock", false},
+		{"tcp://localhost:7000", "tcp", "localhost:7000", false},
+		{"tcp://127.0.0.1:9", "tcp", "127.0.0.1:9", false},
+		{"tcp://localhost", "", "", true},
+		{"unix://", "", "", true},
+		{"http://localhost:7000", "", "", true},
+		{"/tmp/app.sock", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		transport, err := ParseTransport(tc.address)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseTransport(%q) should fail", tc.address)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTransport(%q) failed: %v", tc.address, err)
+			continue
+		}
+		socket, ok := transport.(*SocketTransport)
+		if !ok || socket.Network != tc.network || socket.Address != tc.target {
+			t.Errorf("ParseTransport(%q) = %#v, expected %s %s", tc.address, transport, tc.network, tc.target)
+		}
+	}
+}
+
+// serveSocket answers pings and echoes other requests on every connection to
+// the listener. Connections are closed after a request with method "hangup".
+func serveSocket(t *testing.T, listener net.Listener) {
+	t.Helper()
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					var req Request
+					if json.Unmarshal(scanner.Bytes(), &req) != nil {
+						continue
+					}
+					switch req.Method {
+					case "ping":
+						fmt.Fprintf(conn, `{"id":%q,"success":true,"result":{"pong":true,"protocol_version":"1.0"}}`+"\n", req.ID)
+					case "hangup":
+						return
+					default:
+						fmt.Fprintf(conn, `{"id":%q,"success":true,"result":{"method":%q}}`+"\n", req.ID, req.Method)
+					}
+				}
+			}()
+		}
+	}()
+}
+
+func TestSocketTransport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	unixListener, err := net.Listen("unix", filepath.Join(t.TempDir(), "app.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen on a Unix socket: %v", err)
+	}
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on TCP: %v", err)
+	}
+
+	for _, listener := range []net.Listener{unixListener, tcpListener} {
+		address := listener.Addr().Network() + "://" + listener.Addr().String()
+		t.Run(listener.Addr().Network(), func(t *testing.T) {
+			serveSocket(t, listener)
+			transport, err := ParseTransport(address)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", address, err)
+			}
+
+			client := NewClientWithTransport(transport)
+			if err := client.Start(); err != nil {
+				t.Fatalf("Failed to attach: %v", err)
+			}
+			defer client.Close()
+			if client.ProcessID() != 0 || client.Command() != nil {
+				t.Error("An attached client should not report a process")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			output, err := client.SendAndWaitForResponse(ctx, Request{ID: "req-1", Method: "listAgents"})
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if string(output.Message.Result) != `{"method":"listAgents"}` {
+				t.Errorf("Unexpected result %s", output.Message.Result)
+			}
+
+			// The app hanging up is a crash
+			errCh, _ := client.SendAsyncWithResult(ctx, Request{ID: "req-2", Method: "hangup"})
+			err = <-errCh
+			var crash *ProcessCrashedError
+			if !errors.As(err, &crash) || !errors.Is(err, ErrDisconnected) {
+				t.Errorf("Expected a disconnect, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSocketTransportReconnects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on TCP: %v", err)
+	}
+	serveSocket(t, listener)
+
+	client := NewClientWithTransport(&SocketTransport{Network: "tcp", Address: listener.Addr().String()})
+	policy := DefaultRestartPolicy()
+	policy.InitialBackoff = 10 * time.Millisecond
+	client.SetRestartPolicy(policy)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to attach: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client.Send(Request{ID: "bye", Method: "hangup"})
+	for client.Restarts() == 0 || client.State() != StateRunning {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Client did not reconnect; state %s", client.State())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if _, err := client.SendAndWaitForResponse(ctx, Request{ID: "again", Method: "listAgents"}); err != nil {
+		t.Errorf("Request after reconnecting failed: %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package ipc
 
+import "time"
+
 // Common payload types for IPC communication with Shuttl applications
 
 // FileAttachment represents a file attached to a chat message
@@ -29,6 +31,13 @@ type LogEvent struct {
 	Level   string `json:"level"` // "debug", "info", "warn", "error"
 	Message string `json:"message"`
 	AgentID string `json:"agent_id,omitempty"`
+
+	// Source is LogSourceApp for log events and LogSourceStderr for lines
+	// written to stderr
+	Source string `json:"-"`
+	// Timestamp is when the event was logged, or received if the app did
+	// not say
+	Timestamp time.Time `json:"-"`
 }
 
 // ErrorPayload represents an error response payload
@@ -50,6 +59,7 @@ const (
 	RequestChat       = "chat"
 	RequestStatus     = "status"
 	RequestShutdown   = "shutdown"
+	RequestRespond    = "respond"
 )
 
 // Common event types
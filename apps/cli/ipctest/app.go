@@ -0,0 +1,153 @@
+// Package ipctest provides a fake Shuttl app for testing code built on
+// ipc.Client without running a real app.
+//
+// An App is a script: the agents, toolkits and triggers it lists, the steps
+// it plays in reply to prompts and trigger events, and the results of its
+// tools. Serve plays the script over a pair of streams; Command returns a
+// command that runs it in a child process, so a Client can spawn it like any
+// other app:
+//
+//	app := &ipctest.App{
+//		Agents: []ipc.AgentInfo{{Name: "Bot"}},
+//		Replies: []ipctest.Reply{{
+//			Agent: "Bot",
+//			Steps: []ipctest.Step{ipctest.Delta("Hel"), ipctest.Delta("lo"), ipctest.Text("Hello")},
+//		}},
+//	}
+//	client := ipctest.NewClient(t, app)
+package ipctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// App is the script of a fake app. The zero value answers pings and lists
+// nothing.
+type App struct {
+	Agents   []ipc.AgentInfo   `json:"agents,omitempty"`
+	Toolkits []ipc.ToolkitInfo `json:"toolkits,omitempty"`
+	Triggers []ipc.TriggerInfo `json:"triggers,omitempty"`
+
+	// Replies are played in answer to invokeAgent and invokeTrigger; the
+	// first one matching the request is used
+	Replies []Reply `json:"replies,omitempty"`
+
+	// Tools holds the results of invokeTool by "toolkit/tool"
+	Tools map[string]ToolResult `json:"tools,omitempty"`
+
+	// Errors makes every request for a method fail with the error
+	Errors map[string]*ipc.ErrorObject `json:"errors,omitempty"`
+
+	// ProtocolVersion is reported in answer to pings; the default is
+	// ipc.ProtocolVersion
+	ProtocolVersion string `json:"protocolVersion,omitempty"`
+	// Capabilities are listed in answer to pings, such as
+	// ipc.CapabilityAttachmentPaths
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	received requestLog
+}
+
+// Reply is what the app does when an agent is prompted or a trigger invoked
+type Reply struct {
+	Agent string `json:"agent"`
+	// Trigger selects invocations of the agent's trigger instead of prompts
+	Trigger string `json:"trigger,omitempty"`
+	// Contains restricts the reply to prompts, or trigger request bodies,
+	// containing the text
+	Contains string `json:"contains,omitempty"`
+	Steps    []Step `json:"steps"`
+}
+
+// matches reports whether the reply answers a prompt or trigger event
+func (r *Reply) matches(agent, trigger, input string) bool {
+	return r.Agent == agent && r.Trigger == trigger && strings.Contains(input, r.Contains)
+}
+
+// Step is one thing the app does while replying. Exactly one field should be
+// set; use the constructors.
+type Step struct {
+	Delta string           `json:"delta,omitempty"`
+	Text  string           `json:"text,omitempty"`
+	Call  *ToolCall        `json:"call,omitempty"`
+	Log   *ipc.LogEvent    `json:"log,omitempty"`
+	Sleep time.Duration    `json:"sleep,omitempty"`
+	Error *ipc.ErrorObject `json:"error,omitempty"`
+	// Crash makes the app exit with status 1, or stop serving in process
+	Crash bool `json:"crash,omitempty"`
+}
+
+// ToolCall is a tool call made by an agent
+type ToolCall struct {
+	Name    string         `json:"name"`
+	Args    map[string]any `json:"args,omitempty"`
+	Output  string         `json:"output"`
+	IsError bool           `json:"isError,omitempty"`
+}
+
+// ToolResult is the result of a tool invoked with invokeTool. A tool fails
+// when Error is set.
+type ToolResult struct {
+	Result json.RawMessage  `json:"result,omitempty"`
+	Error  *ipc.ErrorObject `json:"error,omitempty"`
+}
+
+// Delta streams part of the agent's text as an output_text_delta
+func Delta(text string) Step {
+	return Step{Delta: text}
+}
+
+// Text sends the agent's final text as output_text
+func Text(text string) Step {
+	return Step{Text: text}
+}
+
+// Call has the agent call a tool: a tool_call followed by
+// tool_calls_completed with the output
+func Call(name string, args map[string]any, output string) Step {
+	return Step{Call: &ToolCall{Name: name, Args: args, Output: output}}
+}
+
+// FailedCall has the agent call a tool that fails
+func FailedCall(name string, args map[string]any, output string) Step {
+	return Step{Call: &ToolCall{Name: name, Args: args, Output: output, IsError: true}}
+}
+
+// Log sends a log event
+func Log(level, message string) Step {
+	return Step{Log: &ipc.LogEvent{Level: level, Message: message}}
+}
+
+// Sleep pauses the reply
+func Sleep(d time.Duration) Step {
+	return Step{Sleep: d}
+}
+
+// Fail ends the reply with an error
+func Fail(code, message string) Step {
+	return Step{Error: &ipc.ErrorObject{Code: code, Message: message}}
+}
+
+// Crash makes the app exit in the middle of the reply
+func Crash() Step {
+	return Step{Crash: true}
+}
+
+// Result returns a tool result encoding value as JSON
+func Result(value any) ToolResult {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("ipctest: tool result is not JSON: %v", err))
+	}
+	return ToolResult{Result: data}
+}
+
+// ToolError returns a tool result failing with an error
+func ToolError(code, message string) ToolResult {
+	return ToolResult{Error: &ipc.ErrorObject{Code: code, Message: message}}
+}
@@ -0,0 +1,153 @@
+package ipctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// serveArg is the argument telling a re-executed test binary to play the
+// script in the file that follows instead of running tests
+const serveArg = "-ipctest.serve"
+
+// script is the file handed to the child process
+type script struct {
+	App *App `json:"app"`
+	// Requests is where the child writes the requests it receives
+	Requests string `json:"requests"`
+}
+
+// requestLog holds the requests received by an app
+type requestLog struct {
+	mu       sync.Mutex
+	requests []ipc.RecordRequest
+	path     string
+}
+
+// record keeps a request received by the app
+func (a *App) record(req ipc.Request, data []byte) {
+	var recorded ipc.RecordRequest
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		recorded = ipc.RecordRequest{ID: req.ID, Method: req.Method}
+	}
+	l := &a.received
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, recorded)
+	if l.path == "" {
+		return
+	}
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	line, _ := json.Marshal(recorded)
+	file.Write(append(line, '\n'))
+}
+
+// Requests returns the requests the app has received, in order, including
+// the requests received by child processes started from Command
+func (a *App) Requests() []ipc.RecordRequest {
+	l := &a.received
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		return append([]ipc.RecordRequest(nil), l.requests...)
+	}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil
+	}
+	var requests []ipc.RecordRequest
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var req ipc.RecordRequest
+		if decoder.Decode(&req) != nil {
+			break
+		}
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// Command returns a command that plays the script in a child process: the
+// running test binary, re-executed to serve the app instead of running
+// tests. The script is written to a temporary directory of t. Pass the
+// command to ipc.NewClient.
+func (a *App) Command(t testing.TB) []string {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("ipctest: failed to find the test binary: %v", err)
+	}
+
+	dir := t.TempDir()
+	l := &a.received
+	l.mu.Lock()
+	if l.path == "" {
+		l.path = filepath.Join(dir, "requests.jsonl")
+	}
+	requests := l.path
+	l.mu.Unlock()
+
+	data, err := json.Marshal(script{App: a, Requests: requests})
+	if err != nil {
+		t.Fatalf("ipctest: failed to encode app: %v", err)
+	}
+	path := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("ipctest: failed to write app: %v", err)
+	}
+	return []string{executable, serveArg, path}
+}
+
+// NewClient starts a client for the app and stops it when the test ends
+func NewClient(t testing.TB, app *App) *ipc.Client {
+	t.Helper()
+	client := ipc.NewClient(app.Command(t))
+	if err := client.Start(); err != nil {
+		t.Fatalf("ipctest: failed to start app: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// init serves the app when the test binary was started by Command. It runs
+// before the testing package parses flags, so tests need no TestMain.
+func init() {
+	if len(os.Args) != 3 || os.Args[1] != serveArg {
+		return
+	}
+	os.Exit(serveScript(os.Args[2]))
+}
+
+// serveScript plays a script over stdin and stdout and returns the exit
+// status
+func serveScript(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ipctest: %v\n", err)
+		return 2
+	}
+	var s script
+	if err := json.Unmarshal(data, &s); err != nil || s.App == nil {
+		fmt.Fprintf(os.Stderr, "ipctest: invalid app %s: %v\n", path, err)
+		return 2
+	}
+	s.App.received.path = s.Requests
+
+	if err := s.App.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ipctest: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,213 @@
+package ipctest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func testApp() *App {
+	return &App{
+		Agents:   []ipc.AgentInfo{{Name: "Support", Model: ipc.Model{Identifier: "gpt-4.1"}, Toolkits: []string{"Orders"}}},
+		Toolkits: []ipc.ToolkitInfo{{Name: "Orders", Tools: []ipc.ToolInfo{{Name: "lookup", Description: "Find an order"}}}},
+		Triggers: []ipc.TriggerInfo{{Name: "webhook", TriggerType: "api", AgentName: "Support"}},
+		Replies: []Reply{
+			{Agent: "Support", Contains: "broken", Steps: []Step{Fail("MODEL_ERROR", "rate limited")}},
+			{Agent: "Support", Contains: "crash", Steps: []Step{Delta("Going"), Crash()}},
+			{Agent: "Support", Steps: []Step{
+				Log("info", "looking up order"),
+				Call("lookup", map[string]any{"id": 42}, `{"status":"shipped"}`),
+				Delta("Your order "),
+				Delta("has shipped"),
+				Text("Your order has shipped"),
+			}},
+			{Agent: "Support", Trigger: "webhook", Steps: []Step{Text("Handled")}},
+		},
+		Tools: map[string]ToolResult{
+			"Orders/lookup": Result(map[string]string{"status": "shipped"}),
+			"Orders/cancel": ToolError("FORBIDDEN", "orders cannot be cancelled"),
+		},
+		Errors: map[string]*ipc.ErrorObject{"listPrompts": {Code: "UNAVAILABLE", Message: "no prompts"}},
+	}
+}
+
+func TestClientAgainstApp(t *testing.T) {
+	app := testApp()
+	client := NewClient(t, app)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	agents, err := client.GetAgents(ctx)
+	if err != nil || len(agents) != 1 || agents[0].Toolkits[0] != "Orders" {
+		t.Errorf("Unexpected agents %+v, error %v", agents, err)
+	}
+	toolkits, err := client.GetToolkits(ctx)
+	if err != nil || len(toolkits) != 1 || toolkits[0].Tools[0].Name != "lookup" {
+		t.Errorf("Unexpected toolkits %+v, error %v", toolkits, err)
+	}
+	triggers, err := client.GetTriggers(ctx)
+	if err != nil || len(triggers) != 1 || triggers[0].AgentName != "Support" {
+		t.Errorf("Unexpected triggers %+v, error %v", triggers, err)
+	}
+	tools, err := client.GetTools(ctx)
+	if err != nil || len(tools) != 1 || tools[0].ToolkitName != "Orders" {
+		t.Errorf("Unexpected tools %+v, error %v", tools, err)
+	}
+	if _, err := client.GetPrompts(ctx); err == nil || !strings.Contains(err.Error(), "UNAVAILABLE") {
+		t.Errorf("Expected the scripted error, got %v", err)
+	}
+
+	t.Run("invokeTool", func(t *testing.T) {
+		result, err := client.InvokeTool(ctx, "Orders", "lookup", map[string]any{"id": 42})
+		if err != nil || string(result) != `{"status":"shipped"}` {
+			t.Errorf("Unexpected result %s, error %v", result, err)
+		}
+		if _, err := client.InvokeTool(ctx, "Orders", "cancel", nil); err == nil || !strings.Contains(err.Error(), "FORBIDDEN") {
+			t.Errorf("Expected the tool to fail, got %v", err)
+		}
+		if _, err := client.InvokeTool(ctx, "Orders", "missing", nil); err == nil || !strings.Contains(err.Error(), "NOT_FOUND") {
+			t.Errorf("Expected an unknown tool to fail, got %v", err)
+		}
+	})
+
+	t.Run("invokeAgent", func(t *testing.T) {
+		results, errs := client.StartChat(ctx, "Support", "where is my order?")
+		var types []string
+		var deltas, output, threadID, call string
+		for result := range results {
+			types = append(types, result.Type)
+			switch result.Type {
+			case "output_text_delta":
+				deltas += result.TextDelta.OutputTextDelta.Delta
+			case "output_text":
+				output = result.FinalOutput.OutputText.Text
+			case "tool_call":
+				call = result.ToolCall.ToolCall.Name + string(result.ToolCall.ToolCall.Arguments)
+			case "status":
+				threadID = result.Status.ThreadID
+			}
+		}
+		select {
+		case err := <-errs:
+			t.Fatalf("Chat failed: %v", err)
+		default:
+		}
+		expected := "status tool_call tool_calls_completed output_text_delta output_text_delta output_text status"
+		if got := strings.Join(types, " "); got != expected {
+			t.Errorf("Expected results %q, got %q", expected, got)
+		}
+		if deltas != "Your order has shipped" || output != "Your order has shipped" {
+			t.Errorf("Unexpected text: deltas %q, output %q", deltas, output)
+		}
+		if call != `lookup"{\"id\":42}"` {
+			t.Errorf("Unexpected tool call %s", call)
+		}
+		if threadID == "" {
+			t.Error("Expected a thread ID")
+		}
+	})
+
+	t.Run("scripted error", func(t *testing.T) {
+		results, errs := client.StartChat(ctx, "Support", "this is broken")
+		for range results {
+		}
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), "rate limited") {
+			t.Errorf("Expected the scripted error, got %v", err)
+		}
+	})
+
+	t.Run("invokeTrigger", func(t *testing.T) {
+		response, err := client.InvokeTrigger(ctx, ipc.TriggerRequest{
+			AgentName:   "Support",
+			TriggerName: "webhook",
+			TriggerType: "api",
+			HTTPRequest: &ipc.SerializedHTTPRequest{Method: "POST", Body: json.RawMessage(`{"order":42}`)},
+		})
+		if err != nil || !response.Success || len(response.Events) != 1 || response.ThreadID == "" {
+			t.Errorf("Unexpected response %+v, error %v", response, err)
+		}
+	})
+
+	requests := app.Requests()
+	methods := map[string]int{}
+	for _, req := range requests {
+		methods[req.Method]++
+	}
+	if methods["ping"] == 0 || methods["invokeTool"] != 3 || methods["invokeAgent"] != 2 || methods["invokeTrigger"] != 1 {
+		t.Errorf("Unexpected requests received: %v", methods)
+	}
+}
+
+func TestClientCrash(t *testing.T) {
+	client := NewClient(t, testApp())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	results, errs := client.StartChat(ctx, "Support", "crash please")
+	for range results {
+	}
+	err := <-errs
+	var crash *ipc.ProcessCrashedError
+	if !errors.As(err, &crash) {
+		t.Errorf("Expected the app to crash, got %v", err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	app := testApp()
+	inReader, inWriter := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- app.Serve(context.Background(), inReader, &out) }()
+
+	io.WriteString(inWriter, `{"id":"1","method":"invokeAgent","body":{"agent":"Support","prompt":"crash"}}`+"\n")
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCrashed) {
+			t.Errorf("Expected ErrCrashed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after a crash")
+	}
+	if !strings.Contains(out.String(), `"delta":"Going"`) {
+		t.Errorf("Expected the delta before the crash, got %s", out.String())
+	}
+	if got := app.Requests(); len(got) != 1 || got[0].ID != "1" {
+		t.Errorf("Unexpected requests %+v", got)
+	}
+}
+
+func TestServeListener(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "app.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	app := testApp()
+	done := make(chan error, 1)
+	go func() { done <- app.ServeListener(context.Background(), listener) }()
+
+	client := ipc.NewClientWithTransport(&ipc.SocketTransport{Network: "unix", Address: listener.Addr().String()})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Failed to attach: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if result, err := client.InvokeTool(ctx, "Orders", "lookup", nil); err != nil || string(result) != `{"status":"shipped"}` {
+		t.Errorf("Unexpected result %s, error %v", result, err)
+	}
+	client.Close()
+
+	listener.Close()
+	if err := <-done; err == nil || errors.Is(err, ErrCrashed) {
+		t.Errorf("Expected the closed listener to stop serving, got %v", err)
+	}
+}
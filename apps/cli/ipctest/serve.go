@@ -0,0 +1,391 @@
+package ipctest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// ErrCrashed is returned by Serve when a reply plays a Crash step
+var ErrCrashed = errors.New("ipctest: app crashed")
+
+// server plays an App's script to one client
+type server struct {
+	app *App
+	out io.Writer
+
+	writeMu sync.Mutex
+	framing string
+	threads int
+	calls   int
+	mu      sync.Mutex
+
+	crashed chan struct{}
+	crash   sync.Once
+}
+
+// Serve reads requests from in and writes the app's replies to out, as an
+// app does over its stdin and stdout. Replies to invokeAgent and
+// invokeTrigger are played concurrently. Serve returns nil when in is
+// closed, ErrCrashed when a reply crashes and the context's error when ctx
+// is done.
+func (a *App) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	// Replies still playing are stopped before Serve returns
+	var replies sync.WaitGroup
+	defer replies.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := &server{app: a, out: out, crashed: make(chan struct{})}
+
+	requests := make(chan ipc.Request)
+	readErr := make(chan error, 1)
+	go func() {
+		messages := ipc.NewMessageReader(in)
+		for {
+			data, err := messages.Next()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+			var req ipc.Request
+			if err := json.Unmarshal(data, &req); err != nil {
+				continue
+			}
+			a.record(req, data)
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case req := <-requests:
+			replies.Add(1)
+			go func() {
+				defer replies.Done()
+				s.handle(ctx, req)
+			}()
+		case err := <-readErr:
+			return err
+		case <-s.crashed:
+			return ErrCrashed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ServeListener serves the app on every connection accepted by the
+// listener, as an app waiting for `shuttl dev --attach` would. It returns
+// when the listener is closed, when a reply crashes or when ctx is done.
+func (a *App) ServeListener(ctx context.Context, listener net.Listener) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	var conns sync.WaitGroup
+	defer conns.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return err
+		}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			defer conn.Close()
+			// Unblock the read when the app stops
+			done := context.AfterFunc(ctx, func() { conn.Close() })
+			defer done()
+			if err := a.Serve(ctx, conn, conn); errors.Is(err, ErrCrashed) {
+				cancel(err)
+			}
+		}()
+	}
+}
+
+// handle answers one request
+func (s *server) handle(ctx context.Context, req ipc.Request) {
+	if failure, ok := s.app.Errors[req.Method]; ok {
+		s.fail(req.ID, failure)
+		return
+	}
+
+	switch req.Method {
+	case "ping":
+		version := s.app.ProtocolVersion
+		if version == "" {
+			version = ipc.ProtocolVersion
+		}
+		// Like the SDK, write content-length frames if the client reads them
+		var ping ipc.PingRequest
+		decodeBody(req, &ping)
+		framing := ipc.FramingNDJSON
+		if slices.Contains(ping.Framing, ipc.FramingContentLength) {
+			framing = ipc.FramingContentLength
+		}
+		s.writeMu.Lock()
+		s.framing = framing
+		s.writeMu.Unlock()
+		s.reply(req.ID, "", ipc.PingResult{
+			Pong:            true,
+			Timestamp:       time.Now().UnixMilli(),
+			ProtocolVersion: version,
+			Framing:         framing,
+			Capabilities:    s.app.Capabilities,
+		})
+	case "listAgents":
+		s.reply(req.ID, "", nonNil(s.app.Agents))
+	case "listToolkits":
+		s.reply(req.ID, "", nonNil(s.app.Toolkits))
+	case "listTriggers":
+		s.reply(req.ID, "", nonNil(s.app.Triggers))
+	case "listTools":
+		tools := []ipc.SingleToolInfo{}
+		for _, toolkit := range s.app.Toolkits {
+			for _, tool := range toolkit.Tools {
+				tools = append(tools, ipc.SingleToolInfo{Name: tool.Name, Description: tool.Description, Args: tool.Args, ToolkitName: toolkit.Name})
+			}
+		}
+		s.reply(req.ID, "", tools)
+	case "invokeTool":
+		s.invokeTool(req)
+	case "invokeAgent":
+		s.invokeAgent(ctx, req)
+	case "invokeTrigger":
+		s.invokeTrigger(ctx, req)
+	default:
+		s.fail(req.ID, &ipc.ErrorObject{Code: "UNKNOWN_METHOD", Message: fmt.Sprintf("unknown method %s", req.Method)})
+	}
+}
+
+func (s *server) invokeTool(req ipc.Request) {
+	var body ipc.ToolInvokeRequest
+	if err := decodeBody(req, &body); err != nil {
+		s.fail(req.ID, &ipc.ErrorObject{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	result, ok := s.app.Tools[body.Toolkit+"/"+body.Tool]
+	switch {
+	case !ok:
+		s.fail(req.ID, &ipc.ErrorObject{Code: "NOT_FOUND", Message: fmt.Sprintf("Tool not found: %s/%s", body.Toolkit, body.Tool)})
+	case result.Error != nil:
+		s.fail(req.ID, result.Error)
+	default:
+		s.reply(req.ID, "", nonNilRaw(result.Result))
+	}
+}
+
+func (s *server) invokeAgent(ctx context.Context, req ipc.Request) {
+	var body ipc.ChatRequest
+	if err := decodeBody(req, &body); err != nil {
+		s.fail(req.ID, &ipc.ErrorObject{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	reply := s.app.findReply(body.Agent, "", body.Prompt)
+	if reply == nil {
+		s.fail(req.ID, &ipc.ErrorObject{Code: "NO_REPLY", Message: fmt.Sprintf("no reply scripted for agent %s and prompt %q", body.Agent, body.Prompt)})
+		return
+	}
+
+	threadID := s.threadID(body.ThreadID)
+	s.reply(req.ID, "status", ipc.StatusResult{ThreadID: threadID, Status: "invoked"})
+	if !s.play(ctx, req.ID, reply.Steps) {
+		return
+	}
+	s.reply(req.ID, "status", ipc.StatusResult{ThreadID: threadID, Status: "completed"})
+}
+
+func (s *server) invokeTrigger(ctx context.Context, req ipc.Request) {
+	var body ipc.TriggerRequest
+	if err := decodeBody(req, &body); err != nil {
+		s.fail(req.ID, &ipc.ErrorObject{Code: "INVALID_PARAMS", Message: err.Error()})
+		return
+	}
+	var event string
+	if body.HTTPRequest != nil {
+		event = string(body.HTTPRequest.Body)
+	}
+	reply := s.app.findReply(body.AgentName, body.TriggerName, event)
+	if reply == nil {
+		s.fail(req.ID, &ipc.ErrorObject{Code: "NO_REPLY", Message: fmt.Sprintf("no reply scripted for trigger %s/%s", body.AgentName, body.TriggerName)})
+		return
+	}
+
+	var threadID *string
+	if body.ThreadID != "" {
+		threadID = &body.ThreadID
+	}
+	status := map[string]string{
+		"agentName":   body.AgentName,
+		"triggerName": body.TriggerName,
+		"triggerType": body.TriggerType,
+		"threadId":    s.threadID(threadID),
+		"status":      "acknowledged",
+	}
+	s.reply(req.ID, "", status)
+	if !s.play(ctx, req.ID, reply.Steps) {
+		return
+	}
+	status["status"] = "completed"
+	s.reply(req.ID, "", status)
+}
+
+// play sends the steps of a reply and reports whether the reply should be
+// completed
+func (s *server) play(ctx context.Context, id string, steps []Step) bool {
+	sequence := 0
+	for _, step := range steps {
+		switch {
+		case step.Delta != "":
+			var delta ipc.TextDeltaResult
+			delta.TypeName = "output_text_delta"
+			delta.OutputTextDelta.Delta = step.Delta
+			delta.OutputTextDelta.SequenceNumber = sequence
+			sequence++
+			s.reply(id, "output_text_delta", delta)
+		case step.Text != "":
+			var output ipc.FinalOutputResult
+			output.OutputText.Text = step.Text
+			s.reply(id, "output_text", output)
+		case step.Call != nil:
+			s.call(id, step.Call)
+		case step.Log != nil:
+			s.reply(ipc.LogNotificationID, string(ipc.MessageTypeEvent), step.Log)
+		case step.Sleep > 0:
+			select {
+			case <-time.After(step.Sleep):
+			case <-ctx.Done():
+				return false
+			}
+		case step.Error != nil:
+			s.fail(id, step.Error)
+			return false
+		case step.Crash:
+			s.crash.Do(func() { close(s.crashed) })
+			return false
+		}
+	}
+	return true
+}
+
+// call sends a tool call and its output
+func (s *server) call(id string, call *ToolCall) {
+	s.mu.Lock()
+	s.calls++
+	callID := fmt.Sprintf("call-%d", s.calls)
+	s.mu.Unlock()
+
+	args, _ := json.Marshal(nonNilMap(call.Args))
+	arguments, _ := json.Marshal(string(args))
+	var toolCall ipc.ToolCallResult
+	toolCall.TypeName = "tool_call"
+	toolCall.ToolCall.Name = call.Name
+	toolCall.ToolCall.Arguments = arguments
+	toolCall.ToolCall.CallID = callID
+	s.reply(id, "tool_call", toolCall)
+	s.reply(id, "tool_calls_completed", ipc.ToolCallCompletedResult{{
+		Output:  call.Output,
+		Type:    "function_call_output",
+		CallID:  callID,
+		IsError: call.IsError,
+	}})
+}
+
+// threadID returns the thread to continue, or a new one
+func (s *server) threadID(existing *string) string {
+	if existing != nil && *existing != "" {
+		return *existing
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threads++
+	return fmt.Sprintf("thread-%d", s.threads)
+}
+
+// reply sends a successful message
+func (s *server) reply(id, msgType string, result any) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		s.fail(id, &ipc.ErrorObject{Code: "INTERNAL_ERROR", Message: err.Error()})
+		return
+	}
+	s.write(ipc.Message{Type: ipc.MessageType(msgType), ID: id, Success: true, Timestamp: time.Now(), Result: data})
+}
+
+// fail sends an error message
+func (s *server) fail(id string, failure *ipc.ErrorObject) {
+	s.write(ipc.Message{Type: ipc.MessageTypeError, ID: id, Timestamp: time.Now(), ErrorObj: failure})
+}
+
+func (s *server) write(msg ipc.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	ipc.WriteMessage(s.out, s.framing, data)
+}
+
+// findReply returns the first reply matching a prompt or trigger event
+func (a *App) findReply(agent, trigger, input string) *Reply {
+	for i := range a.Replies {
+		if a.Replies[i].matches(agent, trigger, input) {
+			return &a.Replies[i]
+		}
+	}
+	return nil
+}
+
+// decodeBody decodes the body of a request into v
+func decodeBody(req ipc.Request, v any) error {
+	data, err := json.Marshal(req.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s body: %w", req.Method, err)
+	}
+	return nil
+}
+
+// nonNil keeps empty lists from being sent as null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func nonNilMap(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+func nonNilRaw(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("null")
+	}
+	return raw
+}
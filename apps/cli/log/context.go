@@ -0,0 +1,20 @@
+package log
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying the scoped logger, so code handling a
+// request can log with the request's fields
+func NewContext(ctx context.Context, logger *Scoped) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the scoped logger carried by ctx, or one without
+// fields on the default logger
+func FromContext(ctx context.Context) *Scoped {
+	if logger, ok := ctx.Value(contextKey{}).(*Scoped); ok {
+		return logger
+	}
+	return With(nil)
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files
+const backupTimeFormat = "20060102T150405.000"
+
+// FileOptions limits the size and age of log files
+type FileOptions struct {
+	// MaxSize is the size in bytes at which the file is rotated. Zero never
+	// rotates.
+	MaxSize int64
+	// MaxAge is how long rotated files are kept. Zero keeps them forever.
+	MaxAge time.Duration
+}
+
+// RotatingFile is a log file that is renamed to <name>-<timestamp><ext> once
+// it would grow past MaxSize. Rotated files older than MaxAge are removed.
+type RotatingFile struct {
+	path    string
+	options FileOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+	// failing is set once a rotation has failed, so that the error is only
+	// returned once until a rotation succeeds
+	failing bool
+	now     func() time.Time
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory
+// if needed, and removes expired rotated files
+func OpenRotatingFile(path string, options FileOptions) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, options: options, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.prune()
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if p would take the file past MaxSize
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	if f.options.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.options.MaxSize {
+		rotateErr = f.rotate()
+	}
+	// A failed rotation may not have been able to reopen the file
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Close closes the file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// rotate moves the file aside and opens a new one. If the file cannot be
+// moved, it is reopened and appended to, and the rotation is tried again on
+// the next write. Only the first of a run of failures is returned.
+func (f *RotatingFile) rotate() error {
+	f.file.Close()
+	f.file = nil
+	renameErr := os.Rename(f.path, f.backupName(f.now()))
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		if f.failing {
+			return nil
+		}
+		f.failing = true
+		return fmt.Errorf("failed to rotate log file: %w", renameErr)
+	}
+	f.failing = false
+	f.prune()
+	return nil
+}
+
+// backupName returns an unused name for the file rotated at t. Files rotated
+// within the same millisecond get a -1, -2, ... suffix.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext) + "-" + t.Format(backupTimeFormat)
+	name := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// prune removes rotated files older than MaxAge, going by the time in their
+// name
+func (f *RotatingFile) prune() {
+	if f.options.MaxAge <= 0 {
+		return
+	}
+	dir, base := filepath.Split(f.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return
+	}
+	cutoff := f.now().Add(-f.options.MaxAge)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		stamp, _, _ = strings.Cut(stamp, "-")
+		rotated, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		if rotated.Before(cutoff) {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Run("rotates before exceeding max size", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "shuttl.log")
+		file, err := OpenRotatingFile(path, FileOptions{MaxSize: 10})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile failed: %v", err)
+		}
+		defer file.Close()
+		now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+		file.now = func() time.Time { return now }
+
+		file.Write([]byte("12345678\n"))
+		file.Write([]byte("abcdefgh\n"))
+
+		current, _ := os.ReadFile(path)
+		if string(current) != "abcdefgh\n" {
+			t.Errorf("Expected the second write in the current file, got %q", current)
+		}
+		rotated, err := os.ReadFile(filepath.Join(dir, "shuttl-20260102T030405.000.log"))
+		if err != nil {
+			t.Fatalf("Expected a rotated file: %v", err)
+		}
+		if string(rotated) != "12345678\n" {
+			t.Errorf("Expected the first write in the rotated file, got %q", rotated)
+		}
+	})
+
+	t.Run("keeps backups rotated in the same millisecond", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "shuttl.log")
+		file, err := OpenRotatingFile(path, FileOptions{MaxSize: 10, MaxAge: time.Hour})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile failed: %v", err)
+		}
+		defer file.Close()
+		now := time.Now()
+		file.now = func() time.Time { return now }
+
+		for _, line := range []string{"first\n", "second\n", "third\n"} {
+			if _, err := file.Write([]byte(line)); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+
+		stamp := now.Format(backupTimeFormat)
+		for name, want := range map[string]string{
+			"shuttl-" + stamp + ".log":   "first\n",
+			"shuttl-" + stamp + "-1.log": "second\n",
+			"shuttl.log":                 "third\n",
+		} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || string(data) != want {
+				t.Errorf("Expected %q in %s, got %q (%v)", want, name, data, err)
+			}
+		}
+	})
+
+	t.Run("keeps appending when rotation fails", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "shuttl.log")
+		file, err := OpenRotatingFile(path, FileOptions{MaxSize: 10})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile failed: %v", err)
+		}
+		defer file.Close()
+
+		file.Write([]byte("12345678\n"))
+		// Renaming a file that is gone fails
+		if err := os.Remove(path); err != nil {
+			t.Fatalf("Failed to remove the log file: %v", err)
+		}
+
+		n, err := file.Write([]byte("abcdefgh\n"))
+		if err == nil || !strings.Contains(err.Error(), "failed to rotate log file") {
+			t.Errorf("Expected the rotation error, got %v", err)
+		}
+		if n != 9 {
+			t.Errorf("Expected the entry to be written anyway, wrote %d bytes", n)
+		}
+		os.Remove(path)
+		if _, err := file.Write([]byte("ijklmnop\n")); err != nil {
+			t.Errorf("Expected the failure to be reported once, got %v", err)
+		}
+		if _, err := file.Write([]byte("qrstuvwx\n")); err != nil {
+			t.Errorf("Expected writes to continue after the failure, got %v", err)
+		}
+
+		current, _ := os.ReadFile(path)
+		if string(current) != "qrstuvwx\n" {
+			t.Errorf("Expected the latest write in the current file, got %q", current)
+		}
+	})
+
+	t.Run("removes rotated files older than max age", func(t *testing.T) {
+		dir := t.TempDir()
+		now := time.Now()
+		old := filepath.Join(dir, "app-"+now.Add(-48*time.Hour).Format(backupTimeFormat)+".log")
+		recent := filepath.Join(dir, "app-"+now.Add(-time.Hour).Format(backupTimeFormat)+".log")
+		oldSuffixed := filepath.Join(dir, "app-"+now.Add(-48*time.Hour).Format(backupTimeFormat)+"-1.log")
+		other := filepath.Join(dir, "app-notes.log")
+		for _, name := range []string{old, oldSuffixed, recent, other} {
+			if err := os.WriteFile(name, []byte("x"), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+
+		file, err := OpenRotatingFile(filepath.Join(dir, "app.log"), FileOptions{MaxAge: 24 * time.Hour})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile failed: %v", err)
+		}
+		file.Close()
+
+		for _, name := range []string{old, oldSuffixed} {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("Expected %s to be removed", filepath.Base(name))
+			}
+		}
+		for _, name := range []string{recent, other} {
+			if _, err := os.Stat(name); err != nil {
+				t.Errorf("Expected %s to be kept: %v", filepath.Base(name), err)
+			}
+		}
+	})
+
+	t.Run("appends to an existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "shuttl.log")
+		for _, line := range []string{"one\n", "two\n"} {
+			file, err := OpenRotatingFile(path, FileOptions{})
+			if err != nil {
+				t.Fatalf("OpenRotatingFile failed: %v", err)
+			}
+			file.Write([]byte(line))
+			file.Close()
+		}
+
+		data, _ := os.ReadFile(path)
+		if strings.Count(string(data), "\n") != 2 {
+			t.Errorf("Expected both lines, got %q", data)
+		}
+	})
+
+	t.Run("write after close fails", func(t *testing.T) {
+		file, err := OpenRotatingFile(filepath.Join(t.TempDir(), "shuttl.log"), FileOptions{})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile failed: %v", err)
+		}
+		file.Close()
+		if _, err := file.Write([]byte("late\n")); err == nil {
+			t.Error("Expected an error writing to a closed file")
+		}
+	})
+}
@@ -1,7 +1,14 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -11,8 +18,20 @@ type Entry struct {
 	Timestamp time.Time
 	Level     string
 	Message   string
+	Fields    Fields
 }
 
+// Fields are structured key/value pairs attached to a log entry
+type Fields map[string]interface{}
+
+// Well-known field names
+const (
+	FieldAgent     = "agent"
+	FieldTrigger   = "trigger"
+	FieldThreadID  = "thread_id"
+	FieldRequestID = "request_id"
+)
+
 // Logger is a thread-safe logger that stores logs in a buffer
 type Logger struct {
 	entries []Entry
@@ -20,6 +39,12 @@ type Logger struct {
 	maxSize int
 	mode    LogMode
 	level   LogLevel
+	format  Format
+	console io.Writer
+	file    io.Writer
+	// fileFailed is set while writes to the file fail, so that the failure
+	// is reported once
+	fileFailed bool
 }
 
 type LogLevel string
@@ -42,12 +67,39 @@ const (
 
 type LogMode int
 
+// Every mode also writes entries at or above the level to the file set with
+// SetFile, if any
 const (
+	// LogToConsole writes entries at or above the level to stdout
 	LogToConsole LogMode = iota
+	// LogToFile writes entries only to the file
 	LogToFile
+	// LogToEntries keeps every entry in the buffer, e.g. for the TUI
 	LogToEntries
 )
 
+// Format is how entries are written to the console or the file
+type Format string
+
+const (
+	// FormatText writes "[15:04:05.000] [INFO ] message key=value"
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line with time, level, msg and
+	// the entry's fields
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the Format named s
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown log format %q; use %s or %s", s, FormatText, FormatJSON)
+}
+
 var (
 	// Default is the global debug logger
 	Default *Logger
@@ -58,44 +110,120 @@ func init() {
 	Default = New(1000)
 }
 
-// New creates a new debug logger with the specified max size
+// New creates a new debug logger with the specified max size. It writes to
+// the console until SetMode selects another sink.
 func New(maxSize int) *Logger {
 	return &Logger{
 		entries: make([]Entry, 0, maxSize),
 		maxSize: maxSize,
 		mode:    LogToConsole,
 		level:   LogLevelInfo,
+		format:  FormatText,
+		console: os.Stdout,
 	}
 }
 
 func (l *Logger) SetMode(mode LogMode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.mode = mode
 }
 
+// Mode returns the current sink
+func (l *Logger) Mode() LogMode {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.mode
+}
+
 func (l *Logger) SetLevel(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.level = level
 }
 
-// Log adds a log entry with the given level
-func (l *Logger) Log(level, format string, args ...interface{}) {
-	if l.mode == LogToConsole {
-		if logLevels[LogLevel(level)] < logLevels[l.level] {
-			return
-		}
-		fmt.Printf("[%s] [%-5s] %s\n",
-			time.Now().Format("15:04:05.000"),
-			level,
-			fmt.Sprintf(format, args...),
-		)
-		return
-	}
+// SetFormat sets how entries are written to the console and the file
+func (l *Logger) SetFormat(format Format) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.format = format
+}
+
+// SetConsole replaces stdout as the console writer
+func (l *Logger) SetConsole(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.console = w
+}
+
+// SetFile sets the writer, usually a RotatingFile, that entries at or above
+// the level are written to in every mode. The previous writer is returned so
+// the caller can close it.
+func (l *Logger) SetFile(w io.Writer) io.Writer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	previous := l.file
+	l.file = w
+	return previous
+}
 
+// Log adds a log entry with the given level
+func (l *Logger) Log(level, format string, args ...interface{}) {
+	l.LogFields(level, nil, format, args...)
+}
+
+// LogFields adds a log entry with the given level and structured fields
+func (l *Logger) LogFields(level string, fields Fields, format string, args ...interface{}) {
 	entry := Entry{
 		Timestamp: time.Now(),
 		Level:     level,
 		Message:   fmt.Sprintf(format, args...),
+		Fields:    fields,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	enabled := logLevels[LogLevel(level)] >= logLevels[l.level]
+	if enabled && l.file != nil {
+		l.writeFile(entry)
+	}
+	l.add(entry, enabled)
+}
+
+// writeFile writes an entry to the file. The first of a run of failed writes
+// is reported to the console, or to the buffer in LogToEntries mode. The
+// caller must hold l.mu.
+func (l *Logger) writeFile(entry Entry) {
+	err := l.write(l.file, entry)
+	if err == nil || l.fileFailed {
+		l.fileFailed = err != nil
+		return
+	}
+	l.fileFailed = true
+	warning := Entry{
+		Timestamp: time.Now(),
+		Level:     string(LogLevelWarn),
+		Message:   fmt.Sprintf("Failed to write log file: %v", err),
+	}
+	if l.mode == LogToFile {
+		// The file is the only sink, so the warning goes to the console
+		if l.console != nil {
+			l.write(l.console, warning)
+		}
+		return
+	}
+	l.add(warning, logLevels[LogLevelWarn] >= logLevels[l.level])
+}
+
+// add writes an entry to the console or keeps it in the buffer, depending on
+// the mode. The caller must hold l.mu.
+func (l *Logger) add(entry Entry, enabled bool) {
+	if l.mode != LogToEntries {
+		if enabled && l.mode == LogToConsole && l.console != nil {
+			l.write(l.console, entry)
+		}
+		return
 	}
 
 	l.entries = append(l.entries, entry)
@@ -106,6 +234,26 @@ func (l *Logger) Log(level, format string, args ...interface{}) {
 	}
 }
 
+// write writes an entry to w in the logger's format. The caller must hold
+// l.mu.
+func (l *Logger) write(w io.Writer, entry Entry) error {
+	if l.format == FormatJSON {
+		// Blank lines only space out the console banner
+		if strings.TrimSpace(entry.Message) == "" {
+			return nil
+		}
+		_, err := w.Write(formatJSON(entry))
+		return err
+	}
+	_, err := io.WriteString(w, formatText(entry))
+	return err
+}
+
+// With returns a logger that adds fields to every entry
+func (l *Logger) With(fields Fields) *Scoped {
+	return &Scoped{logger: l, fields: fields}
+}
+
 // GetEntries returns a copy of all log entries
 func (l *Logger) GetEntries() []Entry {
 	l.mu.RLock()
@@ -139,15 +287,119 @@ func (l *Logger) Format() string {
 		return ""
 	}
 
-	var result string
+	var result strings.Builder
 	for _, entry := range l.entries {
-		result += fmt.Sprintf("[%s] [%-5s] %s\n",
-			entry.Timestamp.Format("15:04:05.000"),
-			entry.Level,
-			entry.Message,
-		)
+		result.WriteString(formatText(entry))
+	}
+	return result.String()
+}
+
+// formatText renders an entry as a console line, with its fields sorted by
+// name after the message
+func formatText(entry Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] [%-5s] %s",
+		entry.Timestamp.Format("15:04:05.000"),
+		entry.Level,
+		entry.Message,
+	)
+	for _, key := range sortedKeys(entry.Fields) {
+		value := fmt.Sprint(entry.Fields[key])
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// formatJSON renders an entry as a JSON line. time, level and msg come
+// first and cannot be overridden by fields.
+func formatJSON(entry Entry) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, entry.Timestamp.UTC().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, entry.Level)
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, strings.TrimSpace(entry.Message))
+	for _, key := range sortedKeys(entry.Fields) {
+		if key == "time" || key == "level" || key == "msg" {
+			continue
+		}
+		b.WriteByte(',')
+		writeJSON(&b, key)
+		b.WriteByte(':')
+		writeJSON(&b, entry.Fields[key])
 	}
-	return result
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func writeJSON(b *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(data)
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Scoped logs through a Logger with a fixed set of fields
+type Scoped struct {
+	logger *Logger
+	fields Fields
+}
+
+// With returns a logger with fields added to the ones already set
+func (s *Scoped) With(fields Fields) *Scoped {
+	merged := make(Fields, len(s.fields)+len(fields))
+	for key, value := range s.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Scoped{logger: s.logger, fields: merged}
+}
+
+func (s *Scoped) log(level, format string, args ...interface{}) {
+	if s.logger != nil {
+		s.logger.LogFields(level, s.fields, format, args...)
+	}
+}
+
+// Info logs a message at info level
+func (s *Scoped) Info(format string, args ...interface{}) { s.log("INFO", format, args...) }
+
+// Debug logs a message at debug level
+func (s *Scoped) Debug(format string, args ...interface{}) { s.log("DEBUG", format, args...) }
+
+// Warn logs a message at warning level
+func (s *Scoped) Warn(format string, args ...interface{}) { s.log("WARN", format, args...) }
+
+// Error logs a message at error level
+func (s *Scoped) Error(format string, args ...interface{}) { s.log("ERROR", format, args...) }
+
+// IPC logs an IPC-related message
+func (s *Scoped) IPC(format string, args ...interface{}) { s.log("IPC", format, args...) }
+
+// With returns a logger that adds fields to every entry of the default
+// logger
+func With(fields Fields) *Scoped {
+	return &Scoped{logger: Default, fields: fields}
 }
 
 // Info logs a message at info level using the default logger
@@ -1,11 +1,22 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
+// newBuffered returns a logger that keeps its entries, as the TUI's does
+func newBuffered(maxSize int) *Logger {
+	logger := New(maxSize)
+	logger.SetMode(LogToEntries)
+	return logger
+}
+
 func TestNew(t *testing.T) {
 	logger := New(100)
 
@@ -20,10 +31,14 @@ func TestNew(t *testing.T) {
 	if len(logger.entries) != 0 {
 		t.Errorf("Expected empty entries, got %d", len(logger.entries))
 	}
+
+	if logger.Mode() != LogToConsole {
+		t.Errorf("Expected a new logger to write to the console, got mode %d", logger.Mode())
+	}
 }
 
 func TestLog(t *testing.T) {
-	logger := New(100)
+	logger := newBuffered(100)
 
 	t.Run("logs entry with level and message", func(t *testing.T) {
 		logger.Log("INFO", "test message")
@@ -47,7 +62,7 @@ func TestLog(t *testing.T) {
 	})
 
 	t.Run("supports format arguments", func(t *testing.T) {
-		logger := New(100)
+		logger := newBuffered(100)
 		logger.Log("DEBUG", "value: %d, name: %s", 42, "test")
 
 		entries := logger.GetEntries()
@@ -62,7 +77,7 @@ func TestLog(t *testing.T) {
 }
 
 func TestMaxSize(t *testing.T) {
-	logger := New(3)
+	logger := newBuffered(3)
 
 	// Log more than max size
 	logger.Log("INFO", "message 1")
@@ -87,7 +102,7 @@ func TestMaxSize(t *testing.T) {
 }
 
 func TestGetEntries(t *testing.T) {
-	logger := New(100)
+	logger := newBuffered(100)
 
 	logger.Log("INFO", "test1")
 	logger.Log("DEBUG", "test2")
@@ -104,7 +119,7 @@ func TestGetEntries(t *testing.T) {
 }
 
 func TestClear(t *testing.T) {
-	logger := New(100)
+	logger := newBuffered(100)
 
 	logger.Log("INFO", "test1")
 	logger.Log("INFO", "test2")
@@ -121,7 +136,7 @@ func TestClear(t *testing.T) {
 }
 
 func TestLen(t *testing.T) {
-	logger := New(100)
+	logger := newBuffered(100)
 
 	if logger.Len() != 0 {
 		t.Errorf("Expected Len 0, got %d", logger.Len())
@@ -143,7 +158,7 @@ func TestLen(t *testing.T) {
 
 func TestFormat(t *testing.T) {
 	t.Run("empty logger returns empty string", func(t *testing.T) {
-		logger := New(100)
+		logger := newBuffered(100)
 		result := logger.Format()
 
 		if result != "" {
@@ -152,7 +167,7 @@ func TestFormat(t *testing.T) {
 	})
 
 	t.Run("formats entries correctly", func(t *testing.T) {
-		logger := New(100)
+		logger := newBuffered(100)
 		logger.Log("INFO", "test message")
 		logger.Log("DEBUG", "debug message")
 
@@ -177,7 +192,7 @@ func TestFormat(t *testing.T) {
 }
 
 func TestConcurrentAccess(t *testing.T) {
-	logger := New(1000)
+	logger := newBuffered(1000)
 	var wg sync.WaitGroup
 
 	// Spawn multiple goroutines to log concurrently
@@ -199,13 +214,164 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestConsoleOutput(t *testing.T) {
+	t.Run("text format appends sorted fields", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(100)
+		logger.SetMode(LogToConsole)
+		logger.SetConsole(&out)
+
+		logger.With(Fields{FieldTrigger: "api", FieldAgent: "Bot", "note": "two words"}).Info("invoked")
+
+		line := out.String()
+		if !strings.Contains(line, `[INFO ] invoked agent=Bot note="two words" trigger=api`) {
+			t.Errorf("Unexpected line: %q", line)
+		}
+		if logger.Len() != 0 {
+			t.Errorf("Expected no buffered entries in console mode, got %d", logger.Len())
+		}
+	})
+
+	t.Run("json format writes one object per line", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(100)
+		logger.SetMode(LogToConsole)
+		logger.SetConsole(&out)
+		logger.SetFormat(FormatJSON)
+
+		logger.With(Fields{FieldThreadID: "t-1", "msg": "ignored"}).Warn("  slow response ")
+		logger.Log("INFO", "")
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("Expected 1 line (blank messages are skipped), got %d: %q", len(lines), out.String())
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+			t.Fatalf("Line is not JSON: %v", err)
+		}
+		if record["level"] != "WARN" || record["msg"] != "slow response" || record["thread_id"] != "t-1" {
+			t.Errorf("Unexpected record: %v", record)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, record["time"].(string)); err != nil {
+			t.Errorf("Expected an RFC 3339 time, got %v", record["time"])
+		}
+		if !strings.HasPrefix(lines[0], `{"time":`) {
+			t.Errorf("Expected time to come first, got %s", lines[0])
+		}
+	})
+
+	t.Run("level filters console and file output", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(100)
+		logger.SetMode(LogToFile)
+		logger.SetFile(&out)
+
+		logger.Log("DEBUG", "hidden")
+		logger.Log("ERROR", "shown")
+
+		if strings.Contains(out.String(), "hidden") || !strings.Contains(out.String(), "shown") {
+			t.Errorf("Unexpected output: %q", out.String())
+		}
+	})
+
+	t.Run("file receives entries in every mode", func(t *testing.T) {
+		for _, mode := range []LogMode{LogToConsole, LogToFile, LogToEntries} {
+			var console, file bytes.Buffer
+			logger := New(100)
+			logger.SetMode(mode)
+			logger.SetConsole(&console)
+			logger.SetFile(&file)
+
+			logger.Log("INFO", "hello")
+
+			if !strings.Contains(file.String(), "hello") {
+				t.Errorf("Mode %d: expected the entry in the file, got %q", mode, file.String())
+			}
+			if got := strings.Contains(console.String(), "hello"); got != (mode == LogToConsole) {
+				t.Errorf("Mode %d: unexpected console output %q", mode, console.String())
+			}
+			if got := logger.Len(); got != 0 != (mode == LogToEntries) {
+				t.Errorf("Mode %d: unexpected %d buffered entries", mode, got)
+			}
+		}
+	})
+
+	t.Run("file failures are reported once", func(t *testing.T) {
+		var console bytes.Buffer
+		logger := New(100)
+		logger.SetMode(LogToFile)
+		logger.SetConsole(&console)
+		file := &failingWriter{err: errors.New("disk full")}
+		logger.SetFile(file)
+
+		logger.Log("INFO", "one")
+		logger.Log("INFO", "two")
+		if got := strings.Count(console.String(), "Failed to write log file: disk full"); got != 1 {
+			t.Errorf("Expected one warning on the console, got %q", console.String())
+		}
+		if strings.Contains(console.String(), "one") {
+			t.Errorf("Expected only the warning on the console, got %q", console.String())
+		}
+
+		file.err = nil
+		logger.Log("INFO", "three")
+		file.err = errors.New("disk full")
+		logger.Log("INFO", "four")
+		if got := strings.Count(console.String(), "Failed to write log file"); got != 2 {
+			t.Errorf("Expected a new warning after the file recovered, got %q", console.String())
+		}
+	})
+}
+
+// failingWriter fails every write while err is set
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func TestScopedWith(t *testing.T) {
+	logger := newBuffered(100)
+	base := logger.With(Fields{FieldAgent: "Bot"})
+	scoped := base.With(Fields{FieldRequestID: "r-1"})
+
+	scoped.Info("first")
+	base.Info("second")
+
+	entries := logger.GetEntries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Fields[FieldAgent] != "Bot" || entries[0].Fields[FieldRequestID] != "r-1" {
+		t.Errorf("Expected merged fields, got %v", entries[0].Fields)
+	}
+	if _, ok := entries[1].Fields[FieldRequestID]; ok {
+		t.Errorf("With should not modify the parent's fields, got %v", entries[1].Fields)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("JSON"); err != nil || format != FormatJSON {
+		t.Errorf("Expected json, got %q (%v)", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
 func TestGlobalFunctions(t *testing.T) {
 	// Save and restore the default logger
 	originalDefault := Default
 	defer func() { Default = originalDefault }()
 
 	// Create a fresh logger for testing
-	Default = New(100)
+	Default = newBuffered(100)
 
 	t.Run("Info logs at INFO level", func(t *testing.T) {
 		Default.Clear()
@@ -0,0 +1,249 @@
+package manifest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// APITriggerType is the triggerType reported for API triggers
+const APITriggerType = "api"
+
+// Trigger argument keys written by the SDK's API trigger
+const (
+	ArgMethods = "methods"
+	ArgRoute   = "route"
+)
+
+// Routes shuttl serve registers for itself, which triggers cannot use
+const (
+	HealthRoute    = "GET /health"
+	MetricsRoute   = "GET /metrics"
+	RespondRoute   = "POST /threads/{threadId}/respond"
+	EndpointsRoute = "GET /{$}"
+)
+
+// ReservedRoutes are the routes serve registers before the triggers'
+var ReservedRoutes = []string{HealthRoute, MetricsRoute, RespondRoute, EndpointsRoute}
+
+// APIMethods are the HTTP methods an API trigger can accept
+var APIMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// APIRoute describes where a trigger is served over HTTP
+type APIRoute struct {
+	// Methods accepted by the trigger, POST unless the trigger says otherwise
+	Methods []string `json:"methods"`
+	// Route is the path template, e.g. /orders/{id}. Defaults to
+	// /<agent>/<trigger>.
+	Route string `json:"route"`
+	// Params are the names of the path parameters in Route
+	Params []string `json:"params,omitempty"`
+}
+
+// Patterns returns the http.ServeMux patterns for the route, one per method
+func (r *APIRoute) Patterns() []string {
+	patterns := make([]string, len(r.Methods))
+	for i, method := range r.Methods {
+		patterns[i] = method + " " + r.Route
+	}
+	return patterns
+}
+
+// RouteOf returns how a trigger is served. Only API triggers can choose their
+// methods and route; other triggers accept POST at /<agent>/<trigger>.
+func RouteOf(trigger ipc.TriggerInfo) (*APIRoute, error) {
+	route := &APIRoute{
+		Methods: []string{http.MethodPost},
+		Route:   fmt.Sprintf("/%s/%s", trigger.AgentName, trigger.Name),
+	}
+	if trigger.TriggerType != APITriggerType {
+		return route, nil
+	}
+
+	if raw, ok := trigger.Args[ArgMethods]; ok && raw != nil {
+		methods, err := parseMethods(raw)
+		if err != nil {
+			return nil, err
+		}
+		route.Methods = methods
+	}
+
+	if raw, ok := trigger.Args[ArgRoute]; ok && raw != nil {
+		template, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("route must be a string, got %T", raw)
+		}
+		route.Route = template
+	}
+
+	params, err := ParseRoute(route.Route)
+	if err != nil {
+		return nil, err
+	}
+	route.Params = params
+	return route, nil
+}
+
+func parseMethods(raw any) ([]string, error) {
+	var list []any
+	switch v := raw.(type) {
+	case []any:
+		list = v
+	case []string:
+		for _, method := range v {
+			list = append(list, method)
+		}
+	default:
+		return nil, fmt.Errorf("methods must be a list, got %T", raw)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("methods must not be empty")
+	}
+
+	seen := make(map[string]bool)
+	var methods []string
+	for _, item := range list {
+		method, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("methods must be strings, got %T", item)
+		}
+		method = strings.ToUpper(method)
+		if !isAPIMethod(method) {
+			return nil, fmt.Errorf("unsupported method %q; use one of %s", method, strings.Join(APIMethods, ", "))
+		}
+		if !seen[method] {
+			seen[method] = true
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+func isAPIMethod(method string) bool {
+	for _, m := range APIMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRoute checks a route template and returns the names of its path
+// parameters. Templates use http.ServeMux syntax: /orders/{id} matches one
+// segment and a final {path...} matches the rest of the path.
+func ParseRoute(route string) ([]string, error) {
+	if !strings.HasPrefix(route, "/") {
+		return nil, fmt.Errorf("route %q must start with /", route)
+	}
+
+	var params []string
+	seen := make(map[string]bool)
+	segments := strings.Split(strings.TrimPrefix(route, "/"), "/")
+	for i, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			return nil, fmt.Errorf("route %q: a path parameter must be a whole segment, got %q", route, segment)
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		if strings.HasSuffix(name, "...") {
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("route %q: %s must be the last segment", route, segment)
+			}
+			name = strings.TrimSuffix(name, "...")
+		}
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("route %q: invalid path parameter name %q", route, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("route %q: duplicate path parameter %q", route, name)
+		}
+		seen[name] = true
+		params = append(params, name)
+	}
+	return params, nil
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// checkRoutes finds API triggers with invalid routes and triggers whose
+// routes would conflict when served, with each other or with ReservedRoutes
+func checkRoutes(m *Manifest) []Issue {
+	var issues []Issue
+
+	// http.ServeMux panics on conflicting patterns, which is exactly the
+	// check serve needs
+	mux := http.NewServeMux()
+	var registered []string
+	owners := make(map[string]string)
+	for _, pattern := range ReservedRoutes {
+		registerPattern(mux, pattern)
+		registered = append(registered, pattern)
+	}
+	for i, trigger := range m.Triggers {
+		route, err := RouteOf(trigger)
+		if err != nil {
+			issues = append(issues, Issue{Path: pointer("triggers", i, "args"), Message: err.Error()})
+			continue
+		}
+
+		name := trigger.AgentName + "/" + trigger.Name
+		for _, pattern := range route.Patterns() {
+			if err := registerPattern(mux, pattern); err != nil {
+				message := fmt.Sprintf("route %s of trigger %q cannot be served: %v", pattern, name, err)
+				for _, other := range registered {
+					if !conflicts(other, pattern) {
+						continue
+					}
+					if owner, ok := owners[other]; ok {
+						message = fmt.Sprintf("route %s of trigger %q conflicts with %s of trigger %q", pattern, name, other, owner)
+					} else {
+						message = fmt.Sprintf("route %s of trigger %q conflicts with %s, which shuttl serve uses itself", pattern, name, other)
+					}
+					break
+				}
+				issues = append(issues, Issue{Path: pointer("triggers", i, "args", ArgRoute), Message: message})
+				continue
+			}
+			registered = append(registered, pattern)
+			owners[pattern] = name
+		}
+	}
+	return issues
+}
+
+// registerPattern adds pattern to mux, returning the panic raised for
+// conflicting patterns as an error
+func registerPattern(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	return nil
+}
+
+// conflicts reports whether two patterns conflict when registered together
+func conflicts(a, b string) bool {
+	mux := http.NewServeMux()
+	if registerPattern(mux, a) != nil {
+		return false
+	}
+	return registerPattern(mux, b) != nil
+}
@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func TestRouteOf(t *testing.T) {
+	testCases := []struct {
+		name     string
+		trigger  ipc.TriggerInfo
+		expected APIRoute
+	}{
+		{
+			name:     "api trigger defaults",
+			trigger:  ipc.TriggerInfo{Name: "api", TriggerType: "api", AgentName: "Orders"},
+			expected: APIRoute{Methods: []string{"POST"}, Route: "/Orders/api"},
+		},
+		{
+			name: "api trigger with methods and route",
+			trigger: ipc.TriggerInfo{Name: "order", TriggerType: "api", AgentName: "Orders", Args: map[string]any{
+				"methods": []any{"get", "DELETE", "GET"},
+				"route":   "/orders/{id}/items/{rest...}",
+			}},
+			expected: APIRoute{Methods: []string{"GET", "DELETE"}, Route: "/orders/{id}/items/{rest...}", Params: []string{"id", "rest"}},
+		},
+		{
+			name: "other triggers ignore route arguments",
+			trigger: ipc.TriggerInfo{Name: "rate", TriggerType: "rate", AgentName: "Reporter", Args: map[string]any{
+				"route": "/custom",
+			}},
+			expected: APIRoute{Methods: []string{"POST"}, Route: "/Reporter/rate"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			route, err := RouteOf(tc.trigger)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*route, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, *route)
+			}
+		})
+	}
+}
+
+func TestRouteOfErrors(t *testing.T) {
+	testCases := []struct {
+		args   map[string]any
+		substr string
+	}{
+		{map[string]any{"methods": []any{"TRACE"}}, "unsupported method"},
+		{map[string]any{"methods": []any{}}, "must not be empty"},
+		{map[string]any{"methods": "GET"}, "must be a list"},
+		{map[string]any{"route": "orders"}, "must start with /"},
+		{map[string]any{"route": "/orders/id-{id}"}, "whole segment"},
+		{map[string]any{"route": "/orders/{id}/{id}"}, "duplicate path parameter"},
+		{map[string]any{"route": "/files/{path...}/meta"}, "last segment"},
+		{map[string]any{"route": "/orders/{1st}"}, "invalid path parameter"},
+	}
+
+	for _, tc := range testCases {
+		_, err := RouteOf(ipc.TriggerInfo{Name: "api", TriggerType: "api", AgentName: "A", Args: tc.args})
+		if err == nil || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("RouteOf(%v): expected error containing %q, got %v", tc.args, tc.substr, err)
+		}
+	}
+}
+
+func TestValidateRouteConflicts(t *testing.T) {
+	m := validManifest()
+	m.Triggers = []ipc.TriggerInfo{
+		{Name: "get", TriggerType: "api", AgentName: "SupportBot", Args: map[string]any{"methods": []any{"GET"}, "route": "/orders/{id}"}},
+		{Name: "update", TriggerType: "api", AgentName: "SupportBot", Args: map[string]any{"methods": []any{"PUT"}, "route": "/orders/{id}"}},
+		{Name: "lookup", TriggerType: "api", AgentName: "Reporter", Args: map[string]any{"methods": []any{"GET"}, "route": "/orders/{orderId}"}},
+	}
+
+	err := Validate(mustMarshal(t, m))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if len(validationErr.Issues) != 1 {
+		t.Fatalf("Expected one conflict, got %v", validationErr.Issues)
+	}
+	issue := validationErr.Issues[0]
+	if issue.Path != "/triggers/2/args/route" || !strings.Contains(issue.Message, `"SupportBot/get"`) {
+		t.Errorf("Unexpected issue: %v", issue)
+	}
+}
+
+func TestValidateReservedRoutes(t *testing.T) {
+	m := validManifest()
+	m.Triggers = []ipc.TriggerInfo{
+		{Name: "health", TriggerType: "api", AgentName: "SupportBot", Args: map[string]any{"methods": []any{"GET"}, "route": "/health"}},
+		{Name: "reply", TriggerType: "api", AgentName: "SupportBot", Args: map[string]any{"methods": []any{"POST"}, "route": "/threads/{id}/respond"}},
+		{Name: "ping", TriggerType: "api", AgentName: "SupportBot", Args: map[string]any{"methods": []any{"POST"}, "route": "/health"}},
+	}
+
+	err := Validate(mustMarshal(t, m))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if len(validationErr.Issues) != 2 {
+		t.Fatalf("Expected two conflicts, got %v", validationErr.Issues)
+	}
+	for i, want := range []string{HealthRoute, RespondRoute} {
+		issue := validationErr.Issues[i]
+		if issue.Path != fmt.Sprintf("/triggers/%d/args/route", i) || !strings.Contains(issue.Message, want) || !strings.Contains(issue.Message, "shuttl serve uses itself") {
+			t.Errorf("Unexpected issue: %v", issue)
+		}
+	}
+}
+
+func TestValidateAPIArgsSchema(t *testing.T) {
+	m := validManifest()
+	m.Triggers[0].Args = map[string]any{"methods": []any{"FETCH"}}
+
+	err := Validate(mustMarshal(t, m))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	for _, issue := range validationErr.Issues {
+		if strings.HasPrefix(issue.Path, "/triggers/0/args/methods") {
+			return
+		}
+	}
+	t.Errorf("Expected an issue for the methods argument, got %v", validationErr.Issues)
+}
@@ -0,0 +1,412 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// ChangeKind describes how an entry differs between two manifests
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Entity kinds compared by Compare, in the order changes are reported
+const (
+	EntityApp     = "app"
+	EntityAgent   = "agent"
+	EntityTrigger = "trigger"
+	EntityToolkit = "toolkit"
+	EntityTool    = "tool"
+)
+
+var entityOrder = map[string]int{
+	EntityApp:     0,
+	EntityAgent:   1,
+	EntityTrigger: 2,
+	EntityToolkit: 3,
+	EntityTool:    4,
+}
+
+// Change is a single semantic difference between two manifests
+type Change struct {
+	Kind   ChangeKind `json:"kind"`
+	Entity string     `json:"entity"`
+	// Name identifies the entry: the agent name, "<agent>/<trigger>" or
+	// "<toolkit>/<tool>"
+	Name string `json:"name"`
+	// Field is the changed field for ChangeChanged, e.g. "model" or "args.city"
+	Field string `json:"field,omitempty"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+	// Breaking is set for changes that can break existing callers, such as
+	// removed triggers or tool arguments
+	Breaking bool `json:"breaking"`
+}
+
+// Diff is the result of comparing two manifests
+type Diff struct {
+	Changes []Change `json:"changes"`
+}
+
+// HasChanges reports whether the manifests differ
+func (d *Diff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// Breaking returns the breaking changes
+func (d *Diff) Breaking() []Change {
+	var breaking []Change
+	for _, change := range d.Changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// Compare returns the semantic differences between two manifests. Array
+// order and the build time are ignored.
+func Compare(old, new *Manifest) *Diff {
+	d := &Diff{Changes: []Change{}}
+
+	if old.App != new.App {
+		d.add(Change{Kind: ChangeChanged, Entity: EntityApp, Name: "app", Field: "command", Old: old.App, New: new.App})
+	}
+	if old.Version != new.Version {
+		d.add(Change{Kind: ChangeChanged, Entity: EntityApp, Name: "app", Field: "version", Old: old.Version, New: new.Version})
+	}
+
+	d.compareAgents(old.Agents, new.Agents)
+	d.compareTriggers(old.Triggers, new.Triggers)
+	d.compareToolkits(old.Toolkits, new.Toolkits)
+	d.compareTools(toolsOf(old), toolsOf(new))
+
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if a.Entity != b.Entity {
+			return entityOrder[a.Entity] < entityOrder[b.Entity]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Field < b.Field
+	})
+	return d
+}
+
+func (d *Diff) add(change Change) {
+	d.Changes = append(d.Changes, change)
+}
+
+func (d *Diff) compareAgents(old, new []ipc.AgentInfo) {
+	oldByName := make(map[string]ipc.AgentInfo, len(old))
+	for _, agent := range old {
+		oldByName[agent.Name] = agent
+	}
+	newByName := make(map[string]ipc.AgentInfo, len(new))
+	for _, agent := range new {
+		newByName[agent.Name] = agent
+	}
+
+	for name := range oldByName {
+		if _, ok := newByName[name]; !ok {
+			d.add(Change{Kind: ChangeRemoved, Entity: EntityAgent, Name: name, Breaking: true})
+		}
+	}
+	for name, n := range newByName {
+		o, ok := oldByName[name]
+		if !ok {
+			d.add(Change{Kind: ChangeAdded, Entity: EntityAgent, Name: name})
+			continue
+		}
+		if o.SystemPrompt != n.SystemPrompt {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityAgent, Name: name, Field: "systemPrompt", Old: o.SystemPrompt, New: n.SystemPrompt})
+		}
+		if o.Model.Identifier != n.Model.Identifier {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityAgent, Name: name, Field: "model", Old: o.Model.Identifier, New: n.Model.Identifier})
+		}
+		if o.Model.Key != n.Model.Key {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityAgent, Name: name, Field: "model.key", Old: o.Model.Key, New: n.Model.Key})
+		}
+		if !sameSet(o.Toolkits, n.Toolkits) {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityAgent, Name: name, Field: "toolkits", Old: sorted(o.Toolkits), New: sorted(n.Toolkits)})
+		}
+	}
+}
+
+func (d *Diff) compareTriggers(old, new []ipc.TriggerInfo) {
+	key := func(t ipc.TriggerInfo) string { return t.AgentName + "/" + t.Name }
+	oldByKey := make(map[string]ipc.TriggerInfo, len(old))
+	for _, trigger := range old {
+		oldByKey[key(trigger)] = trigger
+	}
+	newByKey := make(map[string]ipc.TriggerInfo, len(new))
+	for _, trigger := range new {
+		newByKey[key(trigger)] = trigger
+	}
+
+	for name, o := range oldByKey {
+		if _, ok := newByKey[name]; !ok {
+			d.add(Change{Kind: ChangeRemoved, Entity: EntityTrigger, Name: name, Old: o.TriggerType, Breaking: true})
+		}
+	}
+	for name, n := range newByKey {
+		o, ok := oldByKey[name]
+		if !ok {
+			d.add(Change{Kind: ChangeAdded, Entity: EntityTrigger, Name: name, New: n.TriggerType})
+			continue
+		}
+		if o.TriggerType != n.TriggerType {
+			// Callers of an API trigger cannot use a rate trigger and vice versa
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: name, Field: "triggerType", Old: o.TriggerType, New: n.TriggerType, Breaking: true})
+		}
+		oldArgs, newArgs := o.Args, n.Args
+		if o.TriggerType == APITriggerType && n.TriggerType == APITriggerType {
+			d.compareRoutes(name, o, n)
+			oldArgs, newArgs = withoutRouteArgs(oldArgs), withoutRouteArgs(newArgs)
+		}
+		if !jsonEqual(oldArgs, newArgs) {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: name, Field: "args", Old: oldArgs, New: newArgs})
+		}
+	}
+}
+
+// compareRoutes reports changes to where an API trigger is served. Callers
+// of a moved route or a dropped method get 404 or 405.
+func (d *Diff) compareRoutes(name string, old, new ipc.TriggerInfo) {
+	oldRoute, errOld := RouteOf(old)
+	newRoute, errNew := RouteOf(new)
+	if errOld != nil || errNew != nil {
+		return
+	}
+
+	if oldRoute.Route != newRoute.Route {
+		d.add(Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: name, Field: "route", Old: oldRoute.Route, New: newRoute.Route, Breaking: true})
+	}
+	if !sameSet(oldRoute.Methods, newRoute.Methods) {
+		removed := false
+		for _, method := range oldRoute.Methods {
+			removed = removed || !isIn(method, newRoute.Methods)
+		}
+		d.add(Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: name, Field: "methods", Old: sorted(oldRoute.Methods), New: sorted(newRoute.Methods), Breaking: removed})
+	}
+}
+
+// withoutRouteArgs returns API trigger args without the keys compared by
+// compareRoutes
+func withoutRouteArgs(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		if key != ArgMethods && key != ArgRoute {
+			out[key] = value
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func isIn(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Diff) compareToolkits(old, new []ipc.ToolkitInfo) {
+	oldNames := make(map[string]bool, len(old))
+	for _, toolkit := range old {
+		oldNames[toolkit.Name] = true
+	}
+	newNames := make(map[string]bool, len(new))
+	for _, toolkit := range new {
+		newNames[toolkit.Name] = true
+	}
+
+	for name := range oldNames {
+		if !newNames[name] {
+			d.add(Change{Kind: ChangeRemoved, Entity: EntityToolkit, Name: name, Breaking: true})
+		}
+	}
+	for name := range newNames {
+		if !oldNames[name] {
+			d.add(Change{Kind: ChangeAdded, Entity: EntityToolkit, Name: name})
+		}
+	}
+}
+
+func (d *Diff) compareTools(old, new map[string]ipc.SingleToolInfo) {
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			d.add(Change{Kind: ChangeRemoved, Entity: EntityTool, Name: name, Breaking: true})
+		}
+	}
+	for name, n := range new {
+		o, ok := old[name]
+		if !ok {
+			d.add(Change{Kind: ChangeAdded, Entity: EntityTool, Name: name})
+			continue
+		}
+		if o.Description != n.Description {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTool, Name: name, Field: "description", Old: o.Description, New: n.Description})
+		}
+		d.compareArgs(name, o.Args, n.Args)
+	}
+}
+
+// compareArgs reports changes to a tool's argument schema, one per argument.
+// Removed arguments and type changes break callers that still send them.
+func (d *Diff) compareArgs(tool string, old, new map[string]any) {
+	for arg, o := range old {
+		n, ok := new[arg]
+		switch {
+		case !ok:
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTool, Name: tool, Field: "args." + arg, Old: o, Breaking: true})
+		case !jsonEqual(o, n):
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTool, Name: tool, Field: "args." + arg, Old: o, New: n,
+				Breaking: !jsonEqual(schemaType(o), schemaType(n))})
+		}
+	}
+	for arg, n := range new {
+		if _, ok := old[arg]; !ok {
+			d.add(Change{Kind: ChangeChanged, Entity: EntityTool, Name: tool, Field: "args." + arg, New: n})
+		}
+	}
+}
+
+// toolsOf indexes a manifest's tools by "<toolkit>/<tool>", falling back to
+// the tools listed under each toolkit for manifests without a tools list
+func toolsOf(m *Manifest) map[string]ipc.SingleToolInfo {
+	tools := make(map[string]ipc.SingleToolInfo)
+	for _, tool := range m.Tools {
+		tools[tool.ToolkitName+"/"+tool.Name] = tool
+	}
+	if len(m.Tools) > 0 {
+		return tools
+	}
+	for _, toolkit := range m.Toolkits {
+		for _, tool := range toolkit.Tools {
+			tools[toolkit.Name+"/"+tool.Name] = ipc.SingleToolInfo{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Args:        tool.Args,
+				ToolkitName: toolkit.Name,
+			}
+		}
+	}
+	return tools
+}
+
+// schemaType returns the "type" of an argument schema, if it has one
+func schemaType(schema any) any {
+	if obj, ok := schema.(map[string]any); ok {
+		return obj["type"]
+	}
+	return nil
+}
+
+// jsonEqual compares values as they would be written to JSON, so that
+// numbers decoded from different sources compare equal
+func jsonEqual(a, b any) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+func sameSet(a, b []string) bool {
+	return reflect.DeepEqual(sorted(a), sorted(b))
+}
+
+func sorted(values []string) []string {
+	out := append([]string{}, values...)
+	sort.Strings(out)
+	return out
+}
+
+// Summary describes the change in a single line, e.g.
+// `agent SupportBot: model "gpt-4o" → "gpt-4.1"`
+func (c Change) Summary() string {
+	subject := fmt.Sprintf("%s %s", c.Entity, c.Name)
+	if c.Entity == EntityApp {
+		subject = "app"
+	}
+
+	switch c.Kind {
+	case ChangeAdded:
+		return subject + " added"
+	case ChangeRemoved:
+		return subject + " removed"
+	}
+
+	if strings.HasPrefix(c.Field, "args.") {
+		arg := strings.TrimPrefix(c.Field, "args.")
+		switch {
+		case c.Old == nil:
+			return fmt.Sprintf("%s: argument %q added", subject, arg)
+		case c.New == nil:
+			return fmt.Sprintf("%s: argument %q removed", subject, arg)
+		default:
+			return fmt.Sprintf("%s: argument %q schema changed %s → %s", subject, arg, compactJSON(c.Old), compactJSON(c.New))
+		}
+	}
+
+	switch c.Field {
+	case "systemPrompt":
+		added, removed := lineChanges(fmt.Sprint(c.Old), fmt.Sprint(c.New))
+		return fmt.Sprintf("%s: system prompt changed (+%d -%d lines)", subject, added, removed)
+	case "model.key":
+		return fmt.Sprintf("%s: model key changed", subject)
+	default:
+		return fmt.Sprintf("%s: %s %s → %s", subject, c.Field, compactJSON(c.Old), compactJSON(c.New))
+	}
+}
+
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// lineChanges counts the lines added and removed between two texts using
+// the longest common subsequence of their lines
+func lineChanges(old, new string) (added, removed int) {
+	a := strings.Split(old, "\n")
+	b := strings.Split(new, "\n")
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	common := lcs[0][0]
+	return len(b) - common, len(a) - common
+}
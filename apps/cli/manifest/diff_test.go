@@ -0,0 +1,168 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func TestCompareIdenticalManifests(t *testing.T) {
+	old := validManifest()
+	new := validManifest()
+	new.BuildTime = "2026-02-02T00:00:00Z"
+
+	// Reordering arrays is not a change
+	new.Agents[0], new.Agents[1] = new.Agents[1], new.Agents[0]
+	new.Triggers[0], new.Triggers[2] = new.Triggers[2], new.Triggers[0]
+
+	diff := Compare(&old, &new)
+	if diff.HasChanges() {
+		t.Errorf("Expected no changes, got %+v", diff.Changes)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(m *Manifest)
+		expected Change
+	}{
+		{
+			name:     "agent added",
+			modify:   func(m *Manifest) { m.Agents = append(m.Agents, ipc.AgentInfo{Name: "Planner"}) },
+			expected: Change{Kind: ChangeAdded, Entity: EntityAgent, Name: "Planner"},
+		},
+		{
+			name:     "agent removed",
+			modify:   func(m *Manifest) { m.Agents = m.Agents[:1]; m.Triggers = m.Triggers[:1] },
+			expected: Change{Kind: ChangeRemoved, Entity: EntityAgent, Name: "Reporter", Breaking: true},
+		},
+		{
+			name:     "system prompt changed",
+			modify:   func(m *Manifest) { m.Agents[0].SystemPrompt = "Be brief." },
+			expected: Change{Kind: ChangeChanged, Entity: EntityAgent, Name: "SupportBot", Field: "systemPrompt", Old: "", New: "Be brief."},
+		},
+		{
+			name:     "model changed",
+			modify:   func(m *Manifest) { m.Agents[1].Model.Identifier = "gpt-4.1" },
+			expected: Change{Kind: ChangeChanged, Entity: EntityAgent, Name: "Reporter", Field: "model", Old: "gpt-4o", New: "gpt-4.1"},
+		},
+		{
+			name:     "trigger removed",
+			modify:   func(m *Manifest) { m.Triggers = m.Triggers[1:] },
+			expected: Change{Kind: ChangeRemoved, Entity: EntityTrigger, Name: "SupportBot/api", Old: "api", Breaking: true},
+		},
+		{
+			name: "trigger added",
+			modify: func(m *Manifest) {
+				m.Triggers = append(m.Triggers, ipc.TriggerInfo{Name: "email", TriggerType: "email", AgentName: "SupportBot"})
+			},
+			expected: Change{Kind: ChangeAdded, Entity: EntityTrigger, Name: "SupportBot/email", New: "email"},
+		},
+		{
+			name:     "trigger type changed",
+			modify:   func(m *Manifest) { m.Triggers[0].TriggerType = "rate" },
+			expected: Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: "SupportBot/api", Field: "triggerType", Old: "api", New: "rate", Breaking: true},
+		},
+		{
+			name:     "api trigger route changed",
+			modify:   func(m *Manifest) { m.Triggers[0].Args = map[string]any{"route": "/support"} },
+			expected: Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: "SupportBot/api", Field: "route", Old: "/SupportBot/api", New: "/support", Breaking: true},
+		},
+		{
+			name:     "api trigger method added",
+			modify:   func(m *Manifest) { m.Triggers[0].Args = map[string]any{"methods": []any{"POST", "GET"}} },
+			expected: Change{Kind: ChangeChanged, Entity: EntityTrigger, Name: "SupportBot/api", Field: "methods", Old: []string{"POST"}, New: []string{"GET", "POST"}},
+		},
+		{
+			name:     "tool removed",
+			modify:   func(m *Manifest) { m.Tools = nil; m.Toolkits[0].Tools = nil },
+			expected: Change{Kind: ChangeRemoved, Entity: EntityTool, Name: "Utility/add", Breaking: true},
+		},
+		{
+			name: "tool argument added",
+			modify: func(m *Manifest) {
+				m.Tools[0].Args = map[string]any{"a": map[string]any{"type": "number"}}
+			},
+			expected: Change{Kind: ChangeChanged, Entity: EntityTool, Name: "Utility/add", Field: "args.a", New: map[string]any{"type": "number"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			old := validManifest()
+			new := validManifest()
+			tc.modify(&new)
+
+			diff := Compare(&old, &new)
+			for _, change := range diff.Changes {
+				if change.Kind == tc.expected.Kind && change.Entity == tc.expected.Entity &&
+					change.Name == tc.expected.Name && change.Field == tc.expected.Field {
+					if change.Breaking != tc.expected.Breaking {
+						t.Errorf("Expected breaking=%v, got %+v", tc.expected.Breaking, change)
+					}
+					if !jsonEqual(change.Old, tc.expected.Old) || !jsonEqual(change.New, tc.expected.New) {
+						t.Errorf("Expected %v → %v, got %v → %v", tc.expected.Old, tc.expected.New, change.Old, change.New)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected change %+v, got %+v", tc.expected, diff.Changes)
+		})
+	}
+}
+
+func TestCompareToolArguments(t *testing.T) {
+	old := validManifest()
+	old.Tools[0].Args = map[string]any{
+		"a":    map[string]any{"type": "number"},
+		"b":    map[string]any{"type": "number"},
+		"note": map[string]any{"type": "string"},
+	}
+	new := validManifest()
+	new.Tools[0].Args = map[string]any{
+		"a":    map[string]any{"type": "number", "description": "first"},
+		"b":    map[string]any{"type": "string"},
+		"unit": map[string]any{"type": "string"},
+	}
+
+	breaking := map[string]bool{}
+	for _, change := range Compare(&old, &new).Changes {
+		breaking[change.Field] = change.Breaking
+	}
+
+	expected := map[string]bool{
+		"args.a":    false, // description only
+		"args.b":    true,  // type changed
+		"args.note": true,  // removed
+		"args.unit": false, // added
+	}
+	for field, want := range expected {
+		got, ok := breaking[field]
+		if !ok {
+			t.Errorf("Expected a change for %s", field)
+		} else if got != want {
+			t.Errorf("Expected %s breaking=%v, got %v", field, want, got)
+		}
+	}
+}
+
+func TestChangeSummary(t *testing.T) {
+	testCases := []struct {
+		change   Change
+		expected string
+	}{
+		{Change{Kind: ChangeAdded, Entity: EntityAgent, Name: "Planner"}, "agent Planner added"},
+		{Change{Kind: ChangeRemoved, Entity: EntityTrigger, Name: "A/api"}, "trigger A/api removed"},
+		{Change{Kind: ChangeChanged, Entity: EntityAgent, Name: "A", Field: "model", Old: "x", New: "y"}, `agent A: model "x" → "y"`},
+		{Change{Kind: ChangeChanged, Entity: EntityAgent, Name: "A", Field: "systemPrompt", Old: "one\ntwo", New: "one\nthree\nfour"}, "agent A: system prompt changed (+2 -1 lines)"},
+		{Change{Kind: ChangeChanged, Entity: EntityTool, Name: "T/add", Field: "args.a", Old: map[string]any{"type": "number"}}, `tool T/add: argument "a" removed`},
+		{Change{Kind: ChangeChanged, Entity: EntityApp, Name: "app", Field: "command", Old: "a", New: "b"}, `app: command "a" → "b"`},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.change.Summary(); got != tc.expected {
+			t.Errorf("Summary() = %q, expected %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// CurrentVersion is the manifest version written by `shuttl build`
+const CurrentVersion = "1.0"
+
+// SupportedVersions lists the manifest versions this CLI can serve
+var SupportedVersions = []string{"1.0"}
+
+// DefaultPath is where `shuttl build` writes the manifest and `shuttl serve`
+// looks for it
+const DefaultPath = "shuttl-manifest.json"
+
+// Manifest represents the complete manifest structure
+type Manifest struct {
+	Version   string               `json:"version"`
+	BuildTime string               `json:"buildTime"`
+	App       string               `json:"app"`
+	Agents    []ipc.AgentInfo      `json:"agents"`
+	Toolkits  []ipc.ToolkitInfo    `json:"toolkits"`
+	Tools     []ipc.SingleToolInfo `json:"tools"`
+	Triggers  []ipc.TriggerInfo    `json:"triggers"`
+	Models    []ipc.ModelInfo      `json:"models"`
+	Prompts   []ipc.PromptInfo     `json:"prompts"`
+}
+
+// Parse validates data and decodes it into a Manifest. Invalid manifests
+// return a *ValidationError listing every problem found.
+func Parse(data []byte) (*Manifest, error) {
+	if err := Validate(data); err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Load reads, validates and decodes the manifest at path
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// IsSupportedVersion reports whether this CLI can serve manifests of version
+func IsSupportedVersion(version string) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
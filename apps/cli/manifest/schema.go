@@ -0,0 +1,63 @@
+package manifest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+//go:embed schema/*.json
+var schemaFiles embed.FS
+
+// compiled caches schemas by major version
+var (
+	compiledMu sync.Mutex
+	compiled   = make(map[string]*jsonschema.Schema)
+)
+
+// Schema returns the JSON Schema document for a manifest version
+func Schema(version string) ([]byte, error) {
+	major, _, _ := strings.Cut(version, ".")
+	data, err := schemaFiles.ReadFile(fmt.Sprintf("schema/v%s.json", major))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for manifest version %q", version)
+	}
+	return data, nil
+}
+
+// compileSchema returns the compiled JSON Schema for a manifest version
+func compileSchema(version string) (*jsonschema.Schema, error) {
+	major, _, _ := strings.Cut(version, ".")
+
+	compiledMu.Lock()
+	defer compiledMu.Unlock()
+	if schema, ok := compiled[major]; ok {
+		return schema, nil
+	}
+
+	data, err := Schema(version)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest schema: %w", err)
+	}
+
+	url := fmt.Sprintf("https://shuttl.dev/schemas/manifest/v%s.json", major)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("failed to load manifest schema: %w", err)
+	}
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile manifest schema: %w", err)
+	}
+
+	compiled[major] = schema
+	return schema, nil
+}
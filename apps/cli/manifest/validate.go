@@ -0,0 +1,194 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Issue is a single problem found in a manifest
+type Issue struct {
+	// Path is a JSON pointer to the offending value, e.g. /triggers/2/agentName
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// ValidationError is returned for manifests that parse but are invalid
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.String()
+	}
+	return fmt.Sprintf("invalid manifest: %s", strings.Join(messages, "; "))
+}
+
+// Validate checks a manifest against the JSON Schema for its version and for
+// problems the schema cannot express, such as triggers referencing unknown
+// agents. It returns nil, a *ValidationError, or an error if data is not JSON.
+func Validate(data []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("manifest is not valid JSON: %w", err)
+	}
+
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return &ValidationError{Issues: []Issue{{Message: "manifest must be a JSON object"}}}
+	}
+
+	// The schema depends on the version, so check it first
+	version, _ := obj["version"].(string)
+	if !IsSupportedVersion(version) {
+		message := fmt.Sprintf("unsupported manifest version %q; this CLI supports %s", version, strings.Join(SupportedVersions, ", "))
+		if _, present := obj["version"]; !present {
+			message = "missing manifest version; rebuild the manifest with 'shuttl build'"
+		}
+		return &ValidationError{Issues: []Issue{{Path: "/version", Message: message}}}
+	}
+
+	schema, err := compileSchema(version)
+	if err != nil {
+		return err
+	}
+
+	var issues []Issue
+	if err := schema.Validate(doc); err != nil {
+		var schemaErr *jsonschema.ValidationError
+		if !errors.As(err, &schemaErr) {
+			return err
+		}
+		issues = schemaIssues(schemaErr)
+	}
+
+	// Values of the wrong type are already reported by the schema
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err == nil {
+		issues = append(issues, checkReferences(&m)...)
+		issues = append(issues, checkRoutes(&m)...)
+	} else if len(issues) == 0 {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if len(issues) > 0 {
+		return &ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// schemaIssues flattens a schema validation error into one issue per failed
+// keyword
+func schemaIssues(err *jsonschema.ValidationError) []Issue {
+	var issues []Issue
+	for _, unit := range err.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		issues = append(issues, Issue{Path: unit.InstanceLocation, Message: unit.Error.String()})
+	}
+	if len(issues) == 0 {
+		issues = append(issues, Issue{Message: err.Error()})
+	}
+	return issues
+}
+
+// checkReferences finds duplicate names and references to agents and
+// toolkits that are not in the manifest
+func checkReferences(m *Manifest) []Issue {
+	var issues []Issue
+
+	agents := make(map[string]int)
+	for i, agent := range m.Agents {
+		if first, ok := agents[agent.Name]; ok {
+			issues = append(issues, Issue{
+				Path:    pointer("agents", i, "name"),
+				Message: fmt.Sprintf("duplicate agent %q, first defined at %s", agent.Name, pointer("agents", first)),
+			})
+			continue
+		}
+		agents[agent.Name] = i
+	}
+
+	toolkits := make(map[string]int)
+	for i, toolkit := range m.Toolkits {
+		if first, ok := toolkits[toolkit.Name]; ok {
+			issues = append(issues, Issue{
+				Path:    pointer("toolkits", i, "name"),
+				Message: fmt.Sprintf("duplicate toolkit %q, first defined at %s", toolkit.Name, pointer("toolkits", first)),
+			})
+			continue
+		}
+		toolkits[toolkit.Name] = i
+	}
+
+	for i, agent := range m.Agents {
+		for j, toolkit := range agent.Toolkits {
+			if _, ok := toolkits[toolkit]; !ok {
+				issues = append(issues, Issue{
+					Path:    pointer("agents", i, "toolkits", j),
+					Message: fmt.Sprintf("agent %q uses unknown toolkit %q", agent.Name, toolkit),
+				})
+			}
+		}
+	}
+
+	for i, tool := range m.Tools {
+		if _, ok := toolkits[tool.ToolkitName]; !ok {
+			issues = append(issues, Issue{
+				Path:    pointer("tools", i, "toolkitName"),
+				Message: fmt.Sprintf("tool %q belongs to unknown toolkit %q", tool.Name, tool.ToolkitName),
+			})
+		}
+	}
+
+	// Triggers are served at /<agent>/<trigger>, so names only need to be
+	// unique per agent
+	triggers := make(map[[2]string]int)
+	for i, trigger := range m.Triggers {
+		if _, ok := agents[trigger.AgentName]; !ok {
+			issues = append(issues, Issue{
+				Path:    pointer("triggers", i, "agentName"),
+				Message: fmt.Sprintf("trigger %q references unknown agent %q", trigger.Name, trigger.AgentName),
+			})
+		}
+
+		key := [2]string{trigger.AgentName, trigger.Name}
+		if first, ok := triggers[key]; ok {
+			issues = append(issues, Issue{
+				Path:    pointer("triggers", i, "name"),
+				Message: fmt.Sprintf("duplicate trigger %q for agent %q, first defined at %s", trigger.Name, trigger.AgentName, pointer("triggers", first)),
+			})
+			continue
+		}
+		triggers[key] = i
+	}
+
+	return issues
+}
+
+// pointer builds a JSON pointer from path tokens
+func pointer(tokens ...any) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		s := fmt.Sprint(token)
+		s = strings.ReplaceAll(s, "~", "~0")
+		s = strings.ReplaceAll(s, "/", "~1")
+		sb.WriteString("/")
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
@@ -0,0 +1,184 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func validManifest() Manifest {
+	return Manifest{
+		Version:   CurrentVersion,
+		BuildTime: "2026-01-01T00:00:00Z",
+		App:       "node ./dist/main.js",
+		Agents: []ipc.AgentInfo{
+			{Name: "SupportBot", Model: ipc.Model{Identifier: "gpt-4o"}, Toolkits: []string{"Utility"}},
+			{Name: "Reporter", Model: ipc.Model{Identifier: "gpt-4o"}},
+		},
+		Toolkits: []ipc.ToolkitInfo{{Name: "Utility", Tools: []ipc.ToolInfo{{Name: "add"}}}},
+		Tools:    []ipc.SingleToolInfo{{Name: "add", ToolkitName: "Utility"}},
+		Triggers: []ipc.TriggerInfo{
+			{Name: "api", TriggerType: "api", AgentName: "SupportBot"},
+			{Name: "api", TriggerType: "api", AgentName: "Reporter"},
+			{Name: "rate", TriggerType: "rate", AgentName: "Reporter", Args: map[string]any{"ms_rate": 1000}},
+		},
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return data
+}
+
+func TestValidateAcceptsValidManifest(t *testing.T) {
+	if err := Validate(mustMarshal(t, validManifest())); err != nil {
+		t.Fatalf("Expected valid manifest, got %v", err)
+	}
+
+	// Apps without toolkits or triggers marshal them as null
+	m := validManifest()
+	m.Agents[0].Toolkits = nil
+	m.Toolkits, m.Tools, m.Triggers = nil, nil, nil
+	if err := Validate(mustMarshal(t, m)); err != nil {
+		t.Fatalf("Expected manifest with empty lists to be valid, got %v", err)
+	}
+}
+
+func TestValidateReportsIssues(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(m *Manifest)
+		path   string
+		substr string
+	}{
+		{
+			name:   "unsupported version",
+			modify: func(m *Manifest) { m.Version = "2.0" },
+			path:   "/version",
+			substr: "unsupported manifest version",
+		},
+		{
+			name:   "missing app",
+			modify: func(m *Manifest) { m.App = "" },
+			path:   "/app",
+		},
+		{
+			name:   "empty trigger name",
+			modify: func(m *Manifest) { m.Triggers[0].Name = "" },
+			path:   "/triggers/0/name",
+		},
+		{
+			name:   "duplicate agent",
+			modify: func(m *Manifest) { m.Agents[1].Name = "SupportBot" },
+			path:   "/agents/1/name",
+			substr: "duplicate agent",
+		},
+		{
+			name:   "duplicate toolkit",
+			modify: func(m *Manifest) { m.Toolkits = append(m.Toolkits, m.Toolkits[0]) },
+			path:   "/toolkits/1/name",
+			substr: "duplicate toolkit",
+		},
+		{
+			name:   "duplicate trigger for the same agent",
+			modify: func(m *Manifest) { m.Triggers[2].Name = "api" },
+			path:   "/triggers/2/name",
+			substr: "duplicate trigger",
+		},
+		{
+			name:   "trigger for unknown agent",
+			modify: func(m *Manifest) { m.Triggers[1].AgentName = "Ghost" },
+			path:   "/triggers/1/agentName",
+			substr: "unknown agent",
+		},
+		{
+			name:   "agent with unknown toolkit",
+			modify: func(m *Manifest) { m.Agents[0].Toolkits = append(m.Agents[0].Toolkits, "Missing") },
+			path:   "/agents/0/toolkits/1",
+			substr: "unknown toolkit",
+		},
+		{
+			name:   "tool in unknown toolkit",
+			modify: func(m *Manifest) { m.Tools[0].ToolkitName = "Missing" },
+			path:   "/tools/0/toolkitName",
+			substr: "unknown toolkit",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := validManifest()
+			tc.modify(&m)
+
+			err := Validate(mustMarshal(t, m))
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			for _, issue := range validationErr.Issues {
+				if issue.Path == tc.path && strings.Contains(issue.Message, tc.substr) {
+					return
+				}
+			}
+			t.Errorf("Expected issue at %s containing %q, got %v", tc.path, tc.substr, validationErr.Issues)
+		})
+	}
+}
+
+func TestValidateWrongTypes(t *testing.T) {
+	data := []byte(`{"version": "1.0", "app": "node main.js", "triggers": [{"name": "api", "triggerType": "api", "agentName": 3}]}`)
+
+	var validationErr *ValidationError
+	if err := Validate(data); !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if validationErr.Issues[0].Path != "/triggers/0/agentName" {
+		t.Errorf("Expected issue for agentName, got %v", validationErr.Issues)
+	}
+}
+
+func TestValidateRejectsNonJSON(t *testing.T) {
+	err := Validate([]byte("not json"))
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Fatalf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	if err := os.WriteFile(path, mustMarshal(t, validManifest()), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if len(m.Triggers) != 3 || m.App != "node ./dist/main.js" {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema("1.3")
+	if err != nil {
+		t.Fatalf("Expected schema for 1.x, got %v", err)
+	}
+	if !json.Valid(data) {
+		t.Error("Expected schema to be valid JSON")
+	}
+
+	if _, err := Schema("9.0"); err == nil {
+		t.Error("Expected no schema for version 9.0")
+	}
+}
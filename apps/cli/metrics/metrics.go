@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+const namespace = "shuttl"
+
+// Trigger results recorded by ObserveResponse and ObserveStreamEvent
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Source reports the state of the app's IPC connection
+type Source interface {
+	Restarts() int
+	PendingRequests() int
+	QueueDepth() int
+	QueueCapacity() int
+	DroppedMessages() map[string]uint64
+}
+
+// Metrics collects the metrics served by `shuttl serve` at /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	streamDuration  *prometheus.HistogramVec
+	activeStreams   *prometheus.GaugeVec
+	results         *prometheus.CounterVec
+	toolCalls       *prometheus.CounterVec
+}
+
+// New creates the metrics for a server whose app is reported by source
+func New(source Source) *Metrics {
+	labels := []string{"agent", "trigger"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests to trigger endpoints by status code.",
+		}, append(labels, "code")),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of non-streaming trigger requests.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, labels),
+		streamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stream_duration_seconds",
+			Help:      "Duration of streaming (SSE) trigger sessions.",
+			Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800},
+		}, labels),
+		activeStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "streams_active",
+			Help:      "Streaming trigger sessions currently open.",
+		}, labels),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "trigger_results_total",
+			Help:      "Trigger invocations by result: success or failure.",
+		}, append(labels, "result")),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "Tool calls made by agents, from tool_call events.",
+		}, append(labels, "tool")),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.requestDuration, m.streamDuration, m.activeStreams, m.results, m.toolCalls,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "app_restarts_total",
+			Help:      "Times the app was restarted after crashing.",
+		}, func() float64 { return float64(source.Restarts()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ipc_pending_requests",
+			Help:      "IPC requests waiting for replies from the app.",
+		}, func() float64 { return float64(source.PendingRequests()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ipc_queue_depth",
+			Help:      "Messages waiting in the IPC output queue.",
+		}, func() float64 { return float64(source.QueueDepth()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ipc_queue_capacity",
+			Help:      "Size of the IPC output queue.",
+		}, func() float64 { return float64(source.QueueCapacity()) }),
+		&droppedCollector{source: source},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Wrap counts the requests handled by next and records how long they take.
+// Responses with Content-Type text/event-stream are recorded as streaming
+// sessions.
+func (m *Metrics) Wrap(agent, trigger string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		streaming := false
+		rec.onHeader = func() {
+			if rec.Header().Get("Content-Type") == "text/event-stream" {
+				streaming = true
+				m.activeStreams.WithLabelValues(agent, trigger).Inc()
+			}
+		}
+
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(start).Seconds()
+		m.requests.WithLabelValues(agent, trigger, strconv.Itoa(rec.code)).Inc()
+		if streaming {
+			m.activeStreams.WithLabelValues(agent, trigger).Dec()
+			m.streamDuration.WithLabelValues(agent, trigger).Observe(elapsed)
+		} else {
+			m.requestDuration.WithLabelValues(agent, trigger).Observe(elapsed)
+		}
+	})
+}
+
+// ObserveResponse records the result of a trigger invocation and the tools
+// it called. Errors from the IPC call count as failures.
+func (m *Metrics) ObserveResponse(agent, trigger string, response *ipc.TriggerResponse, err error) {
+	result := ResultFailure
+	if err == nil && response != nil && response.Success {
+		result = ResultSuccess
+	}
+	m.results.WithLabelValues(agent, trigger, result).Inc()
+
+	if response != nil {
+		for _, tool := range response.ToolCalls {
+			m.toolCalls.WithLabelValues(agent, trigger, tool).Inc()
+		}
+	}
+}
+
+// ObserveStreamEvent records tool calls and the result of a streaming
+// trigger invocation as its events arrive
+func (m *Metrics) ObserveStreamEvent(agent, trigger string, event *ipc.TriggerStreamEvent) {
+	switch {
+	case event.Type == "tool_call":
+		if tool := ipc.ToolCallName(event.Data); tool != "" {
+			m.toolCalls.WithLabelValues(agent, trigger, tool).Inc()
+		}
+	case event.Type == "error":
+		m.results.WithLabelValues(agent, trigger, ResultFailure).Inc()
+	case event.Completed:
+		m.results.WithLabelValues(agent, trigger, ResultSuccess).Inc()
+	}
+}
+
+// droppedCollector exports the app's dropped message counts by reason
+type droppedCollector struct {
+	source Source
+}
+
+var droppedDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "ipc", "dropped_messages_total"),
+	"Messages from the app dropped by the CLI, by reason.",
+	[]string{"reason"}, nil,
+)
+
+func (c *droppedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- droppedDesc
+}
+
+func (c *droppedCollector) Collect(ch chan<- prometheus.Metric) {
+	for reason, count := range c.source.DroppedMessages() {
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(count), reason)
+	}
+}
+
+// statusRecorder remembers the status code written by a handler. It keeps
+// the Flusher of the underlying writer so that streaming still works.
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+	onHeader    func()
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.code = code
+		r.onHeader()
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
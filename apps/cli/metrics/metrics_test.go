@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Restarts() int        { return 2 }
+func (fakeSource) PendingRequests() int { return 1 }
+func (fakeSource) QueueDepth() int      { return 7 }
+func (fakeSource) QueueCapacity() int   { return 100 }
+func (fakeSource) DroppedMessages() map[string]uint64 {
+	return map[string]uint64{ipc.DropOutputFull: 3, ipc.DropOrphaned: 0}
+}
+
+// scrape returns the metrics text served by m
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from /metrics, got %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func expectLines(t *testing.T, text string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestSourceMetrics(t *testing.T) {
+	text := scrape(t, New(fakeSource{}))
+	expectLines(t, text,
+		"shuttl_app_restarts_total 2",
+		"shuttl_ipc_pending_requests 1",
+		"shuttl_ipc_queue_depth 7",
+		"shuttl_ipc_queue_capacity 100",
+		`shuttl_ipc_dropped_messages_total{reason="output_full"} 3`,
+		`shuttl_ipc_dropped_messages_total{reason="orphaned"} 0`,
+	)
+}
+
+func TestWrap(t *testing.T) {
+	m := New(fakeSource{})
+
+	plain := m.Wrap("Bot", "api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	plain.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/Bot/api", nil))
+
+	streaming := m.Wrap("Bot", "chat", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected the wrapped writer to support flushing")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: connected\n\n")
+	}))
+	streaming.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/Bot/chat", nil))
+
+	text := scrape(t, m)
+	expectLines(t, text,
+		`shuttl_http_requests_total{agent="Bot",code="401",trigger="api"} 1`,
+		`shuttl_http_requests_total{agent="Bot",code="200",trigger="chat"} 1`,
+		`shuttl_http_request_duration_seconds_count{agent="Bot",trigger="api"} 1`,
+		`shuttl_stream_duration_seconds_count{agent="Bot",trigger="chat"} 1`,
+		`shuttl_streams_active{agent="Bot",trigger="chat"} 0`,
+	)
+	if strings.Contains(text, `shuttl_http_request_duration_seconds_count{agent="Bot",trigger="chat"}`) {
+		t.Error("Expected streaming requests to be left out of the latency histogram")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	m := New(fakeSource{})
+
+	m.ObserveResponse("Bot", "api", &ipc.TriggerResponse{Success: true, ToolCalls: []string{"search", "search", "add"}}, nil)
+	m.ObserveResponse("Bot", "api", &ipc.TriggerResponse{Success: false}, nil)
+	m.ObserveResponse("Bot", "api", nil, errors.New("app crashed"))
+
+	toolCall, _ := json.Marshal(map[string]any{"typeName": "tool_call", "toolCall": map[string]any{"name": "lookup"}})
+	m.ObserveStreamEvent("Bot", "chat", &ipc.TriggerStreamEvent{Type: "tool_call", Data: toolCall})
+	m.ObserveStreamEvent("Bot", "chat", &ipc.TriggerStreamEvent{Type: "output_text"})
+	m.ObserveStreamEvent("Bot", "chat", &ipc.TriggerStreamEvent{Type: "status", Completed: true})
+
+	text := scrape(t, m)
+	expectLines(t, text,
+		`shuttl_trigger_results_total{agent="Bot",result="success",trigger="api"} 1`,
+		`shuttl_trigger_results_total{agent="Bot",result="failure",trigger="api"} 2`,
+		`shuttl_trigger_results_total{agent="Bot",result="success",trigger="chat"} 1`,
+		`shuttl_tool_calls_total{agent="Bot",tool="search",trigger="api"} 2`,
+		`shuttl_tool_calls_total{agent="Bot",tool="add",trigger="api"} 1`,
+		`shuttl_tool_calls_total{agent="Bot",tool="lookup",trigger="chat"} 1`,
+	)
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+// Trigger argument keys written by the SDK's Rate trigger
+const (
+	ArgRate           = "ms_rate"
+	ArgCronExpression = "cronExpression"
+	ArgTimezone       = "timezone"
+)
+
+// Trigger types reported by the SDK's Rate trigger: Rate.cron reports
+// CronTriggerType and the fixed intervals report RateTriggerType
+const (
+	RateTriggerType = "rate"
+	CronTriggerType = "cron"
+)
+
+// IsScheduled reports whether triggers of a type run on a schedule
+func IsScheduled(triggerType string) bool {
+	return triggerType == RateTriggerType || triggerType == CronTriggerType
+}
+
+// Schedule computes the next activation time after a given time
+type Schedule interface {
+	Next(time.Time) time.Time
+	String() string
+}
+
+// IntervalSchedule fires at a fixed interval
+type IntervalSchedule struct {
+	Interval time.Duration
+}
+
+// Next returns the next activation time
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.Interval)
+}
+
+func (s IntervalSchedule) String() string {
+	return fmt.Sprintf("every %s", s.Interval)
+}
+
+// CronSchedule fires according to a cron expression in a timezone
+type CronSchedule struct {
+	Expression string
+	Location   *time.Location
+	schedule   cron.Schedule
+}
+
+// Next returns the next activation time
+func (s CronSchedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t.In(s.Location))
+}
+
+func (s CronSchedule) String() string {
+	return fmt.Sprintf("cron %q (%s)", s.Expression, s.Location)
+}
+
+// cronParser accepts standard 5-field expressions, an optional leading
+// seconds field, and descriptors such as @hourly
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ParseCron parses a cron expression evaluated in the given timezone.
+// An empty timezone defaults to UTC, matching the SDK.
+func ParseCron(expression string, timezone string) (*CronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, fmt.Errorf("empty cron expression")
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+	return &CronSchedule{Expression: expression, Location: loc, schedule: schedule}, nil
+}
+
+// ScheduleFromTrigger builds a schedule from a rate or cron trigger's manifest
+// args.
+// A cron expression takes precedence over ms_rate when both are present.
+func ScheduleFromTrigger(trigger ipc.TriggerInfo) (Schedule, error) {
+	if !IsScheduled(trigger.TriggerType) {
+		return nil, fmt.Errorf("trigger %s/%s is not a rate trigger (type: %s)", trigger.AgentName, trigger.Name, trigger.TriggerType)
+	}
+
+	if expr, ok := trigger.Args[ArgCronExpression].(string); ok && expr != "" {
+		timezone, _ := trigger.Args[ArgTimezone].(string)
+		return ParseCron(expr, timezone)
+	}
+
+	if raw, ok := trigger.Args[ArgRate]; ok {
+		ms, ok := raw.(float64)
+		if !ok {
+			return nil, fmt.Errorf("trigger %s/%s has a non-numeric %s: %v", trigger.AgentName, trigger.Name, ArgRate, raw)
+		}
+		if ms <= 0 {
+			return nil, fmt.Errorf("trigger %s/%s has a non-positive %s: %v", trigger.AgentName, trigger.Name, ArgRate, ms)
+		}
+		return IntervalSchedule{Interval: time.Duration(ms) * time.Millisecond}, nil
+	}
+
+	return nil, fmt.Errorf("trigger %s/%s has neither %s nor %s in its args", trigger.AgentName, trigger.Name, ArgCronExpression, ArgRate)
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/log"
+)
+
+// InvokeFunc invokes a trigger for a single scheduled tick
+type InvokeFunc func(ctx context.Context, trigger ipc.TriggerInfo, scheduledAt time.Time) (*ipc.TriggerResponse, error)
+
+// Entry is a rate trigger registered with the scheduler
+type Entry struct {
+	Trigger  ipc.TriggerInfo
+	Schedule Schedule
+
+	mu      sync.Mutex
+	running bool
+	next    time.Time
+	ticks   int
+	skipped int
+}
+
+// Name returns the agent/trigger name of the entry
+func (e *Entry) Name() string {
+	return fmt.Sprintf("%s/%s", e.Trigger.AgentName, e.Trigger.Name)
+}
+
+// Next returns the next scheduled activation time
+func (e *Entry) Next() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.next
+}
+
+// Scheduler fires rate triggers on their schedules
+type Scheduler struct {
+	invoke  InvokeFunc
+	timeout time.Duration
+	entries []*Entry
+	now     func() time.Time
+
+	wg sync.WaitGroup
+}
+
+// New creates a scheduler that calls invoke on every tick. Each tick is
+// bounded by timeout; a zero timeout means ticks are only cancelled on Stop.
+func New(invoke InvokeFunc, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		invoke:  invoke,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Add registers a rate trigger, parsing its schedule from the manifest args
+func (s *Scheduler) Add(trigger ipc.TriggerInfo) (*Entry, error) {
+	schedule, err := ScheduleFromTrigger(trigger)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{Trigger: trigger, Schedule: schedule}
+	s.entries = append(s.entries, entry)
+	return entry, nil
+}
+
+// Entries returns the registered entries
+func (s *Scheduler) Entries() []*Entry {
+	return s.entries
+}
+
+// Start runs every entry until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, entry := range s.entries {
+		s.wg.Add(1)
+		go s.run(ctx, entry)
+	}
+}
+
+// Wait blocks until all entries and in-flight ticks have finished
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, entry *Entry) {
+	defer s.wg.Done()
+
+	for {
+		next := entry.Schedule.Next(s.now())
+		if next.IsZero() {
+			log.Warn("⏰ %s has no future activations, stopping", entry.Name())
+			return
+		}
+		entry.mu.Lock()
+		entry.next = next
+		entry.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.fire(ctx, entry, next)
+		}
+	}
+}
+
+// fire starts a tick unless the previous tick of the same entry is still running
+func (s *Scheduler) fire(ctx context.Context, entry *Entry, scheduledAt time.Time) {
+	entry.mu.Lock()
+	if entry.running {
+		entry.skipped++
+		entry.mu.Unlock()
+		log.Warn("⏰ %s tick skipped: previous run still in progress", entry.Name())
+		return
+	}
+	entry.running = true
+	entry.ticks++
+	tick := entry.ticks
+	entry.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			entry.mu.Lock()
+			entry.running = false
+			entry.mu.Unlock()
+		}()
+
+		tickCtx := ctx
+		if s.timeout > 0 {
+			var cancel context.CancelFunc
+			tickCtx, cancel = context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+		}
+
+		log.Info("⏰ %s tick #%d fired", entry.Name(), tick)
+		start := s.now()
+		response, err := s.invoke(tickCtx, entry.Trigger, scheduledAt)
+		elapsed := s.now().Sub(start).Round(time.Millisecond)
+
+		switch {
+		case err != nil:
+			log.Error("⏰ %s tick #%d failed after %s: %v", entry.Name(), tick, elapsed, err)
+		case response == nil:
+			log.Error("⏰ %s tick #%d returned no response after %s", entry.Name(), tick, elapsed)
+		case !response.Success:
+			log.Error("⏰ %s tick #%d failed after %s (thread: %s): %s", entry.Name(), tick, elapsed, threadOrNone(response.ThreadID), response.Error)
+		default:
+			log.Info("⏰ %s tick #%d completed in %s (thread: %s)", entry.Name(), tick, elapsed, threadOrNone(response.ThreadID))
+		}
+	}()
+}
+
+func threadOrNone(threadID string) string {
+	if threadID == "" {
+		return "none"
+	}
+	return threadID
+}
@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func TestScheduleFromTrigger(t *testing.T) {
+	t.Run("interval from ms_rate", func(t *testing.T) {
+		schedule, err := ScheduleFromTrigger(ipc.TriggerInfo{
+			Name:        "every-minute",
+			TriggerType: "rate",
+			AgentName:   "agent",
+			Args:        map[string]any{"ms_rate": float64(60000)},
+		})
+		if err != nil {
+			t.Fatalf("ScheduleFromTrigger failed: %v", err)
+		}
+
+		interval, ok := schedule.(IntervalSchedule)
+		if !ok {
+			t.Fatalf("Expected IntervalSchedule, got %T", schedule)
+		}
+		if interval.Interval != time.Minute {
+			t.Errorf("Expected interval 1m, got %s", interval.Interval)
+		}
+	})
+
+	t.Run("cron with timezone", func(t *testing.T) {
+		schedule, err := ScheduleFromTrigger(ipc.TriggerInfo{
+			Name:        "nightly",
+			TriggerType: "rate",
+			AgentName:   "agent",
+			Args:        map[string]any{"cronExpression": "0 2 * * *", "timezone": "America/New_York"},
+		})
+		if err != nil {
+			t.Fatalf("ScheduleFromTrigger failed: %v", err)
+		}
+
+		loc, _ := time.LoadLocation("America/New_York")
+		from := time.Date(2025, 1, 15, 12, 0, 0, 0, loc)
+		next := schedule.Next(from)
+		expected := time.Date(2025, 1, 16, 2, 0, 0, 0, loc)
+		if !next.Equal(expected) {
+			t.Errorf("Expected next activation %s, got %s", expected, next)
+		}
+	})
+
+	t.Run("cron defaults to UTC", func(t *testing.T) {
+		schedule, err := ScheduleFromTrigger(ipc.TriggerInfo{
+			TriggerType: "cron",
+			Args:        map[string]any{"cronExpression": "@hourly"},
+		})
+		if err != nil {
+			t.Fatalf("ScheduleFromTrigger failed: %v", err)
+		}
+
+		cronSchedule := schedule.(*CronSchedule)
+		if cronSchedule.Location.String() != "UTC" {
+			t.Errorf("Expected UTC location, got %s", cronSchedule.Location)
+		}
+	})
+
+	t.Run("cron with seconds field", func(t *testing.T) {
+		schedule, err := ScheduleFromTrigger(ipc.TriggerInfo{
+			TriggerType: "rate",
+			Args:        map[string]any{"cronExpression": "*/30 * * * * *"},
+		})
+		if err != nil {
+			t.Fatalf("ScheduleFromTrigger failed: %v", err)
+		}
+
+		from := time.Date(2025, 1, 1, 0, 0, 10, 0, time.UTC)
+		if next := schedule.Next(from); !next.Equal(from.Add(20 * time.Second)) {
+			t.Errorf("Expected next activation at :30, got %s", next)
+		}
+	})
+
+	errorCases := []struct {
+		name    string
+		trigger ipc.TriggerInfo
+	}{
+		{"not a rate trigger", ipc.TriggerInfo{TriggerType: "api", Args: map[string]any{"ms_rate": float64(1000)}}},
+		{"missing args", ipc.TriggerInfo{TriggerType: "rate"}},
+		{"cron without an expression", ipc.TriggerInfo{TriggerType: "cron"}},
+		{"non-numeric rate", ipc.TriggerInfo{TriggerType: "rate", Args: map[string]any{"ms_rate": "fast"}}},
+		{"zero rate", ipc.TriggerInfo{TriggerType: "rate", Args: map[string]any{"ms_rate": float64(0)}}},
+		{"invalid cron", ipc.TriggerInfo{TriggerType: "rate", Args: map[string]any{"cronExpression": "not a cron"}}},
+		{"invalid timezone", ipc.TriggerInfo{TriggerType: "rate", Args: map[string]any{"cronExpression": "* * * * *", "timezone": "Mars/Olympus"}}},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ScheduleFromTrigger(tc.trigger); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSchedulerFiresTriggers(t *testing.T) {
+	var calls atomic.Int32
+	invoke := func(ctx context.Context, trigger ipc.TriggerInfo, scheduledAt time.Time) (*ipc.TriggerResponse, error) {
+		calls.Add(1)
+		return &ipc.TriggerResponse{Success: true, ThreadID: "thread-1"}, nil
+	}
+
+	s := New(invoke, time.Second)
+	_, err := s.Add(ipc.TriggerInfo{
+		Name:        "fast",
+		TriggerType: "rate",
+		AgentName:   "agent",
+		Args:        map[string]any{"ms_rate": float64(20)},
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(110 * time.Millisecond)
+	cancel()
+	s.Wait()
+
+	if calls.Load() < 2 {
+		t.Errorf("Expected at least 2 ticks, got %d", calls.Load())
+	}
+}
+
+func TestSchedulerSkipsOverlappingTicks(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	invoke := func(ctx context.Context, trigger ipc.TriggerInfo, scheduledAt time.Time) (*ipc.TriggerResponse, error) {
+		calls.Add(1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return &ipc.TriggerResponse{Success: true}, nil
+	}
+
+	s := New(invoke, 0)
+	entry, err := s.Add(ipc.TriggerInfo{
+		Name:        "slow",
+		TriggerType: "rate",
+		AgentName:   "agent",
+		Args:        map[string]any{"ms_rate": float64(10)},
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(80 * time.Millisecond)
+	close(release)
+	cancel()
+	s.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected exactly 1 invocation while the first was running, got %d", calls.Load())
+	}
+	entry.mu.Lock()
+	skipped := entry.skipped
+	entry.mu.Unlock()
+	if skipped == 0 {
+		t.Error("Expected overlapping ticks to be skipped")
+	}
+}
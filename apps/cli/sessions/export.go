@@ -0,0 +1,172 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Transcript formats
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// ExportDir is where transcripts are written by default, relative to the
+// project directory
+const ExportDir = ".shuttl/exports"
+
+// FormatForPath returns the transcript format for a file name, going by its
+// extension
+func FormatForPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("cannot tell the transcript format of %q; use .md or .json", path)
+}
+
+// Extension returns the file extension for a transcript format
+func Extension(format string) string {
+	if format == FormatJSON {
+		return ".json"
+	}
+	return ".md"
+}
+
+// ExportPath returns the default transcript path of a session in the project
+// in projectDir
+func ExportPath(projectDir string, session *Session, format string) string {
+	return filepath.Join(projectDir, filepath.FromSlash(ExportDir), session.ID+Extension(format))
+}
+
+// ExportFile writes the transcript of a session to path, creating its
+// directory. The format follows the file extension.
+func ExportFile(session *Session, path string) error {
+	format, err := FormatForPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, session, format); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// Export writes the transcript of a session, tool calls included
+func Export(w io.Writer, session *Session, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(session)
+	case FormatMarkdown:
+		_, err := io.WriteString(w, markdown(session))
+		return err
+	}
+	return fmt.Errorf("unknown transcript format %q; use %s or %s", format, FormatMarkdown, FormatJSON)
+}
+
+func markdown(session *Session) string {
+	var b strings.Builder
+
+	title := session.Title
+	if title == "" {
+		title = "Chat with " + session.Agent
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- **Agent:** %s\n", session.Agent)
+	if session.ThreadID != "" {
+		fmt.Fprintf(&b, "- **Thread:** `%s`\n", session.ThreadID)
+	}
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.ID)
+	fmt.Fprintf(&b, "- **Started:** %s\n", session.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- **Updated:** %s\n", session.UpdatedAt.Format(time.RFC3339))
+
+	for _, message := range session.Messages {
+		b.WriteString("\n---\n\n")
+		switch message.Role {
+		case RoleUser:
+			fmt.Fprintf(&b, "**You** · %s\n\n", message.Time.Format("15:04:05"))
+			b.WriteString(strings.TrimSpace(message.Content))
+			b.WriteString("\n")
+			if len(message.Attachments) > 0 {
+				b.WriteString("\nAttachments:\n\n")
+				for _, name := range message.Attachments {
+					fmt.Fprintf(&b, "- `%s`\n", name)
+				}
+			}
+
+		case RoleTool:
+			call := message.ToolCall
+			if call == nil {
+				continue
+			}
+			label := "Tool call"
+			if call.Failed {
+				label = "Tool call failed"
+			}
+			fmt.Fprintf(&b, "**%s** `%s` · %s", label, call.Name, message.Time.Format("15:04:05"))
+			if call.CallID != "" {
+				fmt.Fprintf(&b, " · `%s`", call.CallID)
+			}
+			if call.Completed && call.DurationMs > 0 {
+				fmt.Fprintf(&b, " · %s", time.Duration(call.DurationMs)*time.Millisecond)
+			}
+			b.WriteString("\n\nArguments:\n\n")
+			b.WriteString(codeBlock("json", PrettyJSON(call.Arguments)))
+			if call.Completed {
+				b.WriteString("\nOutput:\n\n")
+				b.WriteString(codeBlock("", call.Output))
+			} else {
+				b.WriteString("\n_The tool call did not complete._\n")
+			}
+
+		default:
+			fmt.Fprintf(&b, "**%s** · %s\n\n", session.Agent, message.Time.Format("15:04:05"))
+			b.WriteString(strings.TrimSpace(message.Content))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// PrettyJSON indents JSON arguments, leaving anything else as it is.
+// Arguments sent as a JSON-encoded string are decoded first.
+func PrettyJSON(data json.RawMessage) string {
+	if len(data) == 0 {
+		return "{}"
+	}
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil && json.Valid([]byte(encoded)) {
+		data = json.RawMessage(encoded)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// codeBlock fences content with more backticks than it contains in a row
+func codeBlock(language, content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + strings.TrimRight(content, "\n") + "\n" + fence + "\n"
+}
@@ -0,0 +1,103 @@
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HistoryDir is where prompt histories are stored, one file per agent,
+// relative to the project directory
+const HistoryDir = ".shuttl/history"
+
+// MaxHistory is how many prompts are kept per agent
+const MaxHistory = 500
+
+// History is the prompts sent to an agent, oldest first, kept across runs
+type History struct {
+	path    string
+	entries []string
+}
+
+// LoadHistory reads the prompt history of agent in the project in
+// projectDir. A missing file is an empty history.
+func LoadHistory(projectDir, agent string) (*History, error) {
+	h := &History{path: historyPath(projectDir, agent)}
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("failed to read prompt history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return h, fmt.Errorf("failed to parse prompt history %s: %w", h.path, err)
+	}
+	return h, nil
+}
+
+// NewHistory returns a history that is not saved
+func NewHistory() *History {
+	return &History{}
+}
+
+func historyPath(projectDir, agent string) string {
+	// Agent names are used as file names, so keep them to one path element
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == 0 {
+			return '_'
+		}
+		return r
+	}, agent)
+	return filepath.Join(projectDir, filepath.FromSlash(HistoryDir), name+".json")
+}
+
+// Entries returns the prompts, oldest first
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Len returns the number of prompts
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// Add records a prompt and saves the history. Blank prompts and repeats of
+// the last prompt are not recorded.
+func (h *History) Add(prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == prompt {
+		return nil
+	}
+	h.entries = append(h.entries, prompt)
+	if len(h.entries) > MaxHistory {
+		h.entries = h.entries[len(h.entries)-MaxHistory:]
+	}
+	return h.save()
+}
+
+func (h *History) save() error {
+	if h.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt history: %w", err)
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save prompt history: %w", err)
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save prompt history: %w", err)
+	}
+	return nil
+}
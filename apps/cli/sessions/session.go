@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Message roles
+const (
+	RoleUser  = "user"
+	RoleAgent = "agent"
+	RoleTool  = "tool"
+)
+
+// titleLength is how much of the first prompt is used as a session's title
+const titleLength = 60
+
+// Session is a chat conversation with an agent. ThreadID is the app's
+// thread, sent with later prompts to continue the conversation.
+type Session struct {
+	ID        string    `json:"id"`
+	Agent     string    `json:"agent"`
+	ThreadID  string    `json:"threadId,omitempty"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Messages  []Message `json:"messages"`
+}
+
+// Message is a prompt, a reply, or a tool call made by the agent
+type Message struct {
+	Role        string    `json:"role"`
+	Content     string    `json:"content,omitempty"`
+	Attachments []string  `json:"attachments,omitempty"`
+	ToolCall    *ToolCall `json:"toolCall,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// ToolCall is a tool invocation and, once it completed, its output and how
+// long it took
+type ToolCall struct {
+	CallID     string          `json:"callId"`
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Output     string          `json:"output,omitempty"`
+	Completed  bool            `json:"completed"`
+	Failed     bool            `json:"failed,omitempty"`
+	DurationMs int64           `json:"durationMs,omitempty"`
+}
+
+// New returns an empty session with agent
+func New(agent string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        newID(now),
+		Agent:     agent,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Messages:  []Message{},
+	}
+}
+
+// newID returns a sortable, unique session ID such as
+// 20260102-030405-1a2b3c
+func newID(now time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Add appends a message. The first prompt becomes the title.
+func (s *Session) Add(message Message) {
+	if message.Time.IsZero() {
+		message.Time = time.Now()
+	}
+	if s.Title == "" && message.Role == RoleUser {
+		s.Title = Title(message.Content)
+	}
+	s.Messages = append(s.Messages, message)
+	s.UpdatedAt = message.Time
+}
+
+// Title shortens a prompt to a single-line title
+func Title(prompt string) string {
+	title := strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(title); len(runes) > titleLength {
+		title = string(runes[:titleLength-1]) + "…"
+	}
+	return title
+}
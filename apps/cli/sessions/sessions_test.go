@@ -0,0 +1,218 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleSession() *Session {
+	session := New("SupportBot")
+	session.ThreadID = "thread_123"
+	session.Add(Message{Role: RoleUser, Content: "Where is   order\n42?", Attachments: []string{"receipt.pdf"}})
+	session.Add(Message{Role: RoleTool, ToolCall: &ToolCall{
+		CallID:     "call_1",
+		Name:       "lookup_order",
+		Arguments:  json.RawMessage(`{"id":42}`),
+		Output:     "```shipped```",
+		Completed:  true,
+		DurationMs: 1500,
+	}})
+	session.Add(Message{Role: RoleTool, ToolCall: &ToolCall{CallID: "call_2", Name: "notify", Arguments: json.RawMessage(`"{\"to\":\"ops\"}"`)}})
+	session.Add(Message{Role: RoleTool, ToolCall: &ToolCall{CallID: "call_3", Name: "refund", Output: `{"error":"denied"}`, Completed: true, Failed: true}})
+	session.Add(Message{Role: RoleAgent, Content: "Order 42 has shipped."})
+	return session
+}
+
+func TestSessionAdd(t *testing.T) {
+	session := sampleSession()
+
+	if session.Title != "Where is order 42?" {
+		t.Errorf("Expected the first prompt as title, got %q", session.Title)
+	}
+	if !session.UpdatedAt.Equal(session.Messages[len(session.Messages)-1].Time) {
+		t.Error("Expected UpdatedAt to follow the last message")
+	}
+
+	long := Title(strings.Repeat("word ", 40))
+	if len([]rune(long)) != titleLength || !strings.HasSuffix(long, "…") {
+		t.Errorf("Expected a title of %d runes ending in an ellipsis, got %q", titleLength, long)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	t.Run("list without a directory", func(t *testing.T) {
+		sessions, err := store.List()
+		if err != nil || len(sessions) != 0 {
+			t.Errorf("Expected no sessions, got %v (%v)", sessions, err)
+		}
+	})
+
+	older := sampleSession()
+	older.UpdatedAt = time.Now().Add(-time.Hour)
+	newer := New("Other")
+	newer.Add(Message{Role: RoleUser, Content: "hi"})
+	for _, session := range []*Session{older, newer} {
+		if err := store.Save(session); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	t.Run("load round trip", func(t *testing.T) {
+		loaded, err := store.Load(older.ID)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if loaded.ThreadID != "thread_123" || len(loaded.Messages) != 5 {
+			t.Errorf("Unexpected session: %+v", loaded)
+		}
+		if call := loaded.Messages[1].ToolCall; call == nil || call.Name != "lookup_order" || !call.Completed || call.DurationMs != 1500 {
+			t.Errorf("Expected the tool call to be kept, got %+v", call)
+		}
+	})
+
+	t.Run("list newest first", func(t *testing.T) {
+		sessions, err := store.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(sessions) != 2 || sessions[0].ID != newer.ID || sessions[1].ID != older.ID {
+			t.Errorf("Unexpected order: %v", sessions)
+		}
+	})
+
+	t.Run("unknown and unsafe IDs", func(t *testing.T) {
+		for _, id := range []string{"missing", "../escape", ""} {
+			if _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Load(%q): expected ErrNotFound, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := store.Delete(newer.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Load(newer.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected the session to be gone, got %v", err)
+		}
+		entries, _ := os.ReadDir(store.Dir())
+		if len(entries) != 1 {
+			t.Errorf("Expected only the remaining session file, got %d entries", len(entries))
+		}
+	})
+}
+
+func TestExport(t *testing.T) {
+	session := sampleSession()
+
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, session, FormatMarkdown); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		out := buf.String()
+		for _, expected := range []string{
+			"# Where is order 42?",
+			"- **Thread:** `thread_123`",
+			"- `receipt.pdf`",
+			"**Tool call** `lookup_order`",
+			"`call_1` · 1.5s",
+			"**Tool call failed** `refund`",
+			"\"id\": 42",
+			"\"to\": \"ops\"",
+			"````\n```shipped```\n````",
+			"_The tool call did not complete._",
+			"Order 42 has shipped.",
+		} {
+			if !strings.Contains(out, expected) {
+				t.Errorf("Expected transcript to contain %q:\n%s", expected, out)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Export(&buf, session, FormatJSON); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		var decoded Session
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("Transcript is not JSON: %v", err)
+		}
+		if decoded.ID != session.ID || decoded.Messages[1].ToolCall.Output != "```shipped```" {
+			t.Errorf("Unexpected transcript: %+v", decoded)
+		}
+	})
+
+	t.Run("file format follows the extension", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "nested", "bug.json")
+		if err := ExportFile(session, path); err != nil {
+			t.Fatalf("ExportFile failed: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if !json.Valid(data) {
+			t.Errorf("Expected a JSON transcript, got %s", data)
+		}
+		if err := ExportFile(session, filepath.Join(dir, "bug.txt")); err == nil {
+			t.Error("Expected an error for an unknown extension")
+		}
+		if got := ExportPath(dir, session, FormatMarkdown); got != filepath.Join(dir, ".shuttl", "exports", session.ID+".md") {
+			t.Errorf("Unexpected default export path %s", got)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	history, err := LoadHistory(dir, "SupportBot")
+	if err != nil || history.Len() != 0 {
+		t.Fatalf("Expected an empty history, got %v (%v)", history.Entries(), err)
+	}
+	for _, prompt := range []string{"first", "second\nline", "second\nline", "  ", "third"} {
+		if err := history.Add(prompt); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	loaded, err := LoadHistory(dir, "SupportBot")
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	expected := []string{"first", "second\nline", "third"}
+	if strings.Join(loaded.Entries(), "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, loaded.Entries())
+	}
+
+	t.Run("per agent", func(t *testing.T) {
+		other, _ := LoadHistory(dir, "../Other")
+		if other.Len() != 0 {
+			t.Errorf("Expected another agent to have its own history, got %q", other.Entries())
+		}
+		if err := other.Add("hi"); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".shuttl", "history", ".._Other.json")); err != nil {
+			t.Errorf("Expected the history to stay in the history directory: %v", err)
+		}
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		capped := NewHistory()
+		for i := 0; i < MaxHistory+10; i++ {
+			capped.Add(strings.Repeat("x", i+1))
+		}
+		if capped.Len() != MaxHistory || len(capped.Entries()[0]) != 11 {
+			t.Errorf("Expected the oldest prompts to be dropped, got %d entries", capped.Len())
+		}
+	})
+}
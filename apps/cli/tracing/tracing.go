@@ -0,0 +1,132 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Setup
+const (
+	ExporterNone = ""
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// ServiceName is the service.name of spans created by the CLI
+const ServiceName = "shuttl-cli"
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is ExporterOTLP, ExporterFile or ExporterNone to disable
+	// tracing
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector, e.g. http://localhost:4318. When
+	// empty the OTEL_EXPORTER_OTLP_* environment variables apply, falling
+	// back to http://localhost:4318.
+	Endpoint string
+	// File receives one JSON span per line with ExporterFile
+	File string
+	// Version is reported as service.version
+	Version string
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		otlpExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("a trace file is required for the file exporter")
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		fileExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		exporter = fileExporter
+		closeFile = file.Close
+
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q; use %s or %s", cfg.Exporter, ExporterOTLP, ExporterFile)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(cfg.Version),
+	))
+	if err != nil {
+		res = resource.Default()
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			if closeErr := closeFile(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer for spans created by package name
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer("github.com/shuttl-ai/cli/" + name)
+}
+
+// Extract returns ctx with the trace context of incoming request headers,
+// so that spans continue the caller's trace
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the trace context of ctx into headers, replacing any trace
+// context already there
+func Inject(ctx context.Context, headers map[string][]string) {
+	carrier := propagation.HeaderCarrier(http.Header(headers))
+	for _, field := range otel.GetTextMapPropagator().Fields() {
+		for key := range headers {
+			if strings.EqualFold(key, field) {
+				delete(headers, key)
+			}
+		}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+	if err == nil || !strings.Contains(err.Error(), `unknown trace exporter "zipkin"`) {
+		t.Errorf("Expected an unknown exporter error, got %v", err)
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: path, Version: "test"})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	_, span := Tracer("test").Start(context.Background(), "POST /Bot/api")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace file: %v", err)
+	}
+	for _, want := range []string{`"Name":"POST /Bot/api"`, `"Value":"shuttl-cli"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected the trace file to contain %s, got %s", want, data)
+		}
+	}
+}
+
+func TestInjectReplacesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	headers := map[string][]string{
+		"traceparent":  {"00-11111111111111111111111111111111-2222222222222222-01"},
+		"Content-Type": {"application/json"},
+	}
+	Inject(ctx, headers)
+
+	if _, ok := headers["traceparent"]; ok {
+		t.Error("Expected the caller's traceparent header to be replaced")
+	}
+	expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if got := http.Header(headers).Get("Traceparent"); got != expected {
+		t.Errorf("Expected traceparent %s, got %s", expected, got)
+	}
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), http.Header(headers)))
+	if extracted.TraceID() != traceID || extracted.SpanID() != spanID {
+		t.Errorf("Expected the trace context to round trip, got %v", extracted)
+	}
+}
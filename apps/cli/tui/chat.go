@@ -2,18 +2,20 @@ package tui
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/shuttl-ai/cli/ipc"
 	"github.com/shuttl-ai/cli/log"
+	"github.com/shuttl-ai/cli/sessions"
 )
 
 // ChatModel handles the chat interface
@@ -21,8 +23,6 @@ type ChatModel struct {
 	sessions      map[string]*ChatSession // Map of agent ID to chat session
 	activeAgentID string                  // Currently active agent
 	agentOrder    []string                // Order of agents (for tab switching)
-	input         string
-	cursorPos     int
 	width         int
 	height        int
 	scrollOffset  int
@@ -34,6 +34,27 @@ type ChatModel struct {
 	showFilePicker  bool
 	attachedFiles   []ipc.FileAttachment
 	filePickerError string
+
+	// Session persistence; see sessions.go
+	store        *sessions.Store
+	projectDir   string
+	agents       map[string]Agent
+	showSessions bool
+	browser      sessionBrowser
+	notice       string
+
+	// Renders committed agent replies; see markdown.go
+	markdown markdownRenderer
+
+	// The input and the prompt history of each agent; see editor.go
+	editor       textarea.Model
+	histories    map[string]*sessions.History
+	historyIndex int // Index of the recalled prompt; -1 when not browsing
+	historyDraft string
+
+	// Slash commands; see commands.go
+	completions []string
+	tools       []ipc.SingleToolInfo // nil until loaded
 }
 
 // NewChatModel creates a new chat model
@@ -53,6 +74,10 @@ func NewChatModel(ipcClient *ipc.Client) *ChatModel {
 		ipcClient:     ipcClient,
 		filePicker:    fp,
 		attachedFiles: []ipc.FileAttachment{},
+		agents:        make(map[string]Agent),
+		editor:        newEditor(),
+		histories:     make(map[string]*sessions.History),
+		historyIndex:  -1,
 	}
 }
 
@@ -72,21 +97,36 @@ func (m *ChatModel) GetTitle() string {
 func (m *ChatModel) SetSize(width, height int) {
 	m.width = width
 	m.height = height
+	m.editor.SetWidth(max(width-8, 10))
+	m.fitEditor()
 }
 
 // StartSession starts a new chat session with an agent
 func (m *ChatModel) StartSession(agent Agent) {
 	if _, exists := m.sessions[agent.ID]; !exists {
-		m.sessions[agent.ID] = &ChatSession{
-			Agent:               agent,
-			Messages:            []*ChatMessage{},
-			currentMessageIndex: -1,
-		}
+		m.sessions[agent.ID] = newChatSession(agent)
 		m.agentOrder = append(m.agentOrder, agent.ID)
 	}
 	m.activeAgentID = agent.ID
 }
 
+// openSession shows a session, replacing the one open with the same agent
+func (m *ChatModel) openSession(session *ChatSession) {
+	if _, exists := m.sessions[session.Agent.ID]; !exists {
+		m.agentOrder = append(m.agentOrder, session.Agent.ID)
+	}
+	m.sessions[session.Agent.ID] = session
+	m.activeAgentID = session.Agent.ID
+	m.scrollOffset = 0
+}
+
+// InModal reports whether the file picker or session browser is open, or a
+// slash command is being typed, which takes tab to complete and esc to
+// cancel
+func (m *ChatModel) InModal() bool {
+	return m.showFilePicker || m.showSessions || m.typingCommand()
+}
+
 // HasSessions returns true if there are active sessions
 func (m ChatModel) HasSessions() bool {
 	return len(m.sessions) > 0
@@ -134,19 +174,28 @@ func (m *ChatModel) SwitchToPrevAgent() {
 func (m *ChatModel) AddMessage(role, content string) {
 	session := m.GetActiveSession()
 	if session != nil {
+		var attachments []string
+		if role == "user" {
+			for _, file := range m.attachedFiles {
+				attachments = append(attachments, file.Name)
+			}
+		}
 		session.Messages = append(session.Messages, &ChatMessage{
 			Role:        role,
 			Content:     content,
 			AgentID:     m.activeAgentID,
 			IsCompleted: true,
+			Time:        time.Now(),
+			Attachments: attachments,
 		})
 	}
 }
 
 // ClearInput clears the input field
 func (m *ChatModel) ClearInput() {
-	m.input = ""
-	m.cursorPos = 0
+	m.editor.Reset()
+	m.fitEditor()
+	m.resetHistoryNavigation()
 }
 
 // ClearAttachments clears all attached files
@@ -154,9 +203,17 @@ func (m *ChatModel) ClearAttachments() {
 	m.attachedFiles = []ipc.FileAttachment{}
 }
 
-// AttachFile reads a file and adds it to the attachments
+// AttachFile adds a file to the attachments. The IPC client reads it when the
+// prompt is sent, unless the app reads it by path itself.
 func (m *ChatModel) AttachFile(filePath string) error {
-	content, err := os.ReadFile(filePath)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("failed to read file: %s is a directory", filePath)
+	}
+	path, err := filepath.Abs(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
@@ -166,8 +223,7 @@ func (m *ChatModel) AttachFile(filePath string) error {
 
 	attachment := ipc.FileAttachment{
 		Name:     filepath.Base(filePath),
-		Path:     filePath,
-		Content:  base64.StdEncoding.EncodeToString(content),
+		Path:     path,
 		MimeType: mimeType,
 	}
 
@@ -258,7 +314,7 @@ func getMimeType(filePath string) string {
 
 // GetInput returns the current input
 func (m *ChatModel) GetInput() string {
-	return m.input
+	return m.editor.Value()
 }
 
 type chatStreamMsg struct {
@@ -270,6 +326,8 @@ type chatStreamMsg struct {
 }
 
 type endChatStreamMsg struct {
+	agentID string
+	err     error
 }
 
 type toolCallMsg struct {
@@ -280,7 +338,12 @@ func streamChat(channel chan *ipc.ChatParsedResult, errChan chan error, agentID
 	return func() tea.Msg {
 		currentMessage, ok := <-channel
 		if !ok {
-			return endChatStreamMsg{}
+			end := endChatStreamMsg{agentID: agentID}
+			select {
+			case end.err = <-errChan:
+			default:
+			}
+			return end
 		}
 		var err error
 		select {
@@ -299,18 +362,17 @@ func streamChat(channel chan *ipc.ChatParsedResult, errChan chan error, agentID
 	}
 }
 
-func updateMessage(textDelta *ipc.TextDeltaResult) tea.Cmd {
-	return func() tea.Msg {
-		return *textDelta
-	}
-}
-
 // Update handles input for the chat
 func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle file picker mode first
 	if m.showFilePicker {
 		return m.updateFilePicker(msg)
 	}
+	if m.showSessions {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			return m.updateSessionBrowser(msg)
+		}
+	}
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -323,34 +385,98 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Role == "user" {
 			// Set waiting state before starting the chat
 			session := m.GetActiveSession()
-			if session != nil {
-				session.IsWaiting = true
+			if session == nil || m.ipcClient == nil {
+				return m, nil
 			}
-			channel, errChan := m.ipcClient.StartChatWithAttachments(context.Background(), m.activeAgentID, msg.Content, m.attachedFiles)
+			session.IsWaiting = true
+			m.saveSession(session)
+			channel, errChan := m.ipcClient.StartChatInThread(context.Background(), m.activeAgentID, session.ThreadID, msg.Content, m.attachedFiles)
 			m.ClearAttachments()
 			return m, streamChat(channel, errChan, m.activeAgentID)
 		}
 		return m, nil
 
-	case ipc.TextDeltaResult:
-		m.GetActiveSession().UpdateMessage(msg.OutputTextDelta.Delta, msg.OutputTextDelta.SequenceNumber)
+	case inputRespondedMsg:
+		m.handleInputResponded(msg)
+		return m, nil
+
+	case externalEditorMsg:
+		m.handleExternalEditor(msg)
+		return m, nil
+
+	case toolsLoadedMsg:
+		if msg.err != nil {
+			log.Warn("Tools not loaded for /tool: %v", msg.err)
+			return m, nil
+		}
+		m.tools = append([]ipc.SingleToolInfo{}, msg.tools...)
 		return m, nil
 
+	case toolInvokedMsg:
+		m.handleToolInvoked(msg)
+		return m, nil
+
+	case endChatStreamMsg:
+		if session := m.sessions[msg.agentID]; session != nil {
+			session.IsWaiting = false
+			session.InputRequest = nil
+		}
+		if msg.err != nil {
+			log.Error("Chat with %s failed: %v", msg.agentID, msg.err)
+			m.notice = fmt.Sprintf("⚠️  Chat with %s failed: %v", msg.agentID, msg.err)
+		}
+		return m, nil
+
+	case AgentsLoadedMsg:
+		for _, agent := range msg.Agents {
+			m.agents[agent.ID] = agent
+		}
+		if msg.Err != nil || m.ipcClient == nil {
+			return m, nil
+		}
+		// Agents are reloaded with the app, so its tools may have changed too
+		return m, requestToolsCmd(m.ipcClient)
+
 	case chatStreamMsg:
-		session := m.GetActiveSession()
+		// Apply the result to the session it belongs to, which may not be
+		// the one on screen
+		session := m.sessions[msg.agentID]
 		if session == nil {
-			log.Error("No active session")
+			log.Error("No session for agent %s", msg.agentID)
 			return m, nil
 		}
 
-		if msg.currentMessage.Type == "output_text_delta" {
-			return m, tea.Batch(
-				updateMessage(msg.currentMessage.TextDelta),
-				streamChat(msg.channel, msg.errChan, msg.agentID),
-			)
-		}
-		if msg.currentMessage.Type == "output_text" {
-			session.CommitMessage()
+		result := msg.currentMessage
+		switch result.Type {
+		case "output_text_delta":
+			session.UpdateMessage(result.TextDelta.OutputTextDelta.Delta, result.TextDelta.OutputTextDelta.SequenceNumber)
+			session.IsWaiting = false
+		case "output_text":
+			session.CommitMessage(result.FinalOutput.OutputText.Text)
+			m.saveSession(session)
+		case "tool_call":
+			session.AddToolCall(result.ToolCall)
+			m.saveSession(session)
+		case "tool_calls_completed":
+			session.CompleteToolCalls(result.ToolCallsCompleted)
+			m.saveSession(session)
+		case "response.requested":
+			// Only requests with a prompt wait on the user
+			if request := result.InputRequest; request != nil {
+				session.RequestInput(request)
+				m.saveSession(session)
+				if msg.agentID != m.activeAgentID {
+					m.notice = fmt.Sprintf("🙋 %s needs your input (ctrl+n/p to switch)", session.Agent.Name)
+				}
+			}
+		default:
+			if result.Status != nil && result.Status.ThreadID != "" && result.Status.ThreadID != session.ThreadID {
+				session.ThreadID = result.Status.ThreadID
+				m.saveSession(session)
+			}
+			if result.Status != nil && result.Status.Status == "completed" {
+				session.IsWaiting = false
+			}
 		}
 
 		return m, streamChat(msg.channel, msg.errChan, msg.agentID)
@@ -365,7 +491,24 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.StartSession(*msg.agent)
 		return m, nil
 	case tea.KeyMsg:
+		if session := m.GetActiveSession(); session != nil && session.InputRequest != nil {
+			if cmd, handled := m.updateInputRequest(session, msg); handled {
+				return m, cmd
+			}
+		}
+		if msg.String() != "tab" {
+			m.completions = nil
+		}
 		switch msg.String() {
+		case "tab":
+			if m.typingCommand() {
+				m.completeSlashCommand()
+			}
+		case "esc":
+			// Cancel the command being typed
+			if m.typingCommand() {
+				m.ClearInput()
+			}
 		case "ctrl+f":
 			// Open file picker
 			if m.GetActiveSession() != nil {
@@ -373,36 +516,51 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.filePickerError = ""
 				return m, m.filePicker.Init()
 			}
+		case "ctrl+o":
+			// Browse saved sessions
+			if m.store != nil {
+				m.openSessionBrowser()
+				return m, nil
+			}
 		case "ctrl+x":
 			// Remove last attached file
 			if len(m.attachedFiles) > 0 {
 				m.RemoveAttachment(len(m.attachedFiles) - 1)
 			}
-		case "left":
-			if m.cursorPos > 0 {
-				m.cursorPos--
+		case "ctrl+g":
+			// Write a long prompt in $EDITOR
+			return m, m.openExternalEditor()
+		case "up":
+			if !m.historyUp() {
+				return m, m.updateEditor(msg)
+			}
+		case "down":
+			if !m.historyDown() {
+				return m, m.updateEditor(msg)
 			}
-		case "right":
-			if m.cursorPos < len(m.input) {
-				m.cursorPos++
+		case "ctrl+t":
+			// Expand or collapse the selected tool call
+			if session := m.GetActiveSession(); session != nil {
+				session.ToggleToolCall()
 			}
-		case "backspace":
-			if m.cursorPos > 0 {
-				m.input = m.input[:m.cursorPos-1] + m.input[m.cursorPos:]
-				m.cursorPos--
+		case "ctrl+y":
+			if session := m.GetActiveSession(); session != nil {
+				session.ToggleAllToolCalls()
 			}
-		case "delete":
-			if m.cursorPos < len(m.input) {
-				m.input = m.input[:m.cursorPos] + m.input[m.cursorPos+1:]
+		case "shift+up":
+			if session := m.GetActiveSession(); session != nil {
+				session.SelectToolCall(-1)
+			}
+		case "shift+down":
+			if session := m.GetActiveSession(); session != nil {
+				session.SelectToolCall(1)
 			}
-		case "ctrl+a":
-			m.cursorPos = 0
-		case "ctrl+e":
-			m.cursorPos = len(m.input)
 		case "ctrl+n":
 			m.SwitchToNextAgent()
+			m.resetHistoryNavigation()
 		case "ctrl+p":
 			m.SwitchToPrevAgent()
+			m.resetHistoryNavigation()
 		case "pgup":
 			m.scrollOffset += 5
 		case "pgdown":
@@ -414,18 +572,19 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "enter":
-			// m.AddMessage("user", m.input)
-			input := m.input
+			input := m.editor.Value()
+			m.addToHistory(input)
 			m.ClearInput()
+			if name, args, ok := parseSlashCommand(input); ok {
+				return m, m.runSlashCommand(name, args)
+			}
+			input = strings.TrimPrefix(input, "/")
 			return m, func() tea.Msg {
 				return ChatMessage{Role: "user", Content: input, AgentID: m.activeAgentID}
 			}
 		default:
-			// Handle regular character input
-			if len(msg.String()) == 1 {
-				m.input = m.input[:m.cursorPos] + msg.String() + m.input[m.cursorPos:]
-				m.cursorPos++
-			}
+			// Typing, pastes and cursor motion go to the input
+			return m, m.updateEditor(msg)
 		}
 	}
 	return m, nil
@@ -474,6 +633,9 @@ func (m *ChatModel) View() string {
 	if m.showFilePicker {
 		return m.renderFilePicker()
 	}
+	if m.showSessions {
+		return m.renderSessionBrowser()
+	}
 
 	var b strings.Builder
 
@@ -496,7 +658,11 @@ func (m *ChatModel) View() string {
 	if session == nil {
 		b.WriteString(HelpStyle.Render("No active chat session. Select an agent from the Agents tab."))
 		b.WriteString("\n\n")
-		b.WriteString(HelpStyle.Render("tab switch screen • esc quit"))
+		if m.store != nil {
+			b.WriteString(HelpStyle.Render("ctrl+o past sessions • tab switch screen • esc quit"))
+		} else {
+			b.WriteString(HelpStyle.Render("tab switch screen • esc quit"))
+		}
 		return b.String()
 	}
 
@@ -531,11 +697,17 @@ func (m *ChatModel) View() string {
 			endIdx = 0
 		}
 
+		selectedTool := session.selectedToolCall()
 		for i := startIdx; i < endIdx; i++ {
 			msg := messages[i]
 			var style lipgloss.Style
 			var prefix string
 
+			if msg.ToolCall != nil {
+				b.WriteString(renderToolCall(msg.ToolCall, i == selectedTool, m.width-10))
+				b.WriteString("\n")
+				continue
+			}
 			if msg.Role == "user" {
 				style = UserMessageStyle
 				prefix = "You: "
@@ -549,6 +721,15 @@ func (m *ChatModel) View() string {
 			if maxWidth < 20 {
 				maxWidth = 20
 			}
+			if msg.Role != "user" && msg.IsCompleted {
+				// Replies are rendered as Markdown once they are complete;
+				// streaming deltas stay raw
+				b.WriteString(style.MarginBottom(0).Render(session.Agent.Name))
+				b.WriteString("\n")
+				b.WriteString(m.renderMarkdown(msg, maxWidth))
+				b.WriteString("\n\n")
+				continue
+			}
 			// content := wrapText(prefix+msg.Content, maxWidth)
 			b.WriteString(style.Width(maxWidth).Render(prefix + msg.String()))
 			b.WriteString("\n")
@@ -574,26 +755,40 @@ func (m *ChatModel) View() string {
 		b.WriteString("\n")
 	}
 
-	// Input area
-	inputDisplay := m.input
-	if m.cursorPos <= len(inputDisplay) {
-		// Show cursor
-		before := inputDisplay[:m.cursorPos]
-		after := ""
-		if m.cursorPos < len(inputDisplay) {
-			after = inputDisplay[m.cursorPos:]
+	if session.InputRequest != nil {
+		b.WriteString(m.renderInputRequest(session))
+		b.WriteString("\n")
+		if len(inputChoices(session.InputRequest)) > 0 {
+			if m.notice != "" {
+				b.WriteString(HelpStyle.Render(m.notice))
+			}
+			return b.String()
 		}
-		inputDisplay = before + "█" + after
 	}
 
+	// Input area
+	if m.typingCommand() {
+		b.WriteString(m.renderCommandSuggestions())
+	}
 	prompt := InputPromptStyle.Render("> ")
-	inputBox := InputStyle.Width(m.width - 6).Render(inputDisplay)
-	b.WriteString(prompt + inputBox)
+	inputBox := InputStyle.Width(m.width - 6).Render(m.editor.View())
+	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, prompt, inputBox))
 
 	b.WriteString("\n\n")
 
+	if m.notice != "" {
+		b.WriteString(HelpStyle.Render(m.notice))
+		b.WriteString("\n")
+	}
+
 	// Help
-	helpText := "enter send • ctrl+f attach file • ctrl+x remove file • ctrl+n/p switch agent • tab switch screen • esc quit"
+	helpText := "enter send • alt+enter newline • ↑/↓ history • ctrl+g $EDITOR • / commands\nctrl+f attach file • ctrl+x remove file • ctrl+n/p switch agent • tab switch screen • esc quit"
+	if m.store != nil {
+		helpText = "enter send • alt+enter newline • ↑/↓ history • ctrl+g $EDITOR • / commands\nctrl+f attach file • ctrl+x remove file • ctrl+n/p switch agent • ctrl+o sessions • tab switch screen • esc quit"
+	}
+	if session.selectedToolCall() >= 0 {
+		helpText = "shift+↑/↓ select tool call • ctrl+t expand/collapse • ctrl+y all tool calls\n" + helpText
+	}
 	b.WriteString(HelpStyle.Render(helpText))
 
 	return b.String()
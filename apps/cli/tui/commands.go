@@ -0,0 +1,531 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/sessions"
+)
+
+// slashCommand is a command typed in the chat input, such as /new
+type slashCommand struct {
+	name        string
+	usage       string // The arguments, shown in completions and /help
+	description string
+	run         func(m *ChatModel, args string) tea.Cmd
+	// complete returns what the arguments typed so far can be completed
+	// to. Commands without it only complete their name.
+	complete func(m *ChatModel, args string) []string
+}
+
+// slashCommands are the chat's commands by name
+var slashCommands = map[string]*slashCommand{}
+
+// registerSlashCommand adds a command to the chat
+func registerSlashCommand(command *slashCommand) {
+	slashCommands[command.name] = command
+}
+
+func init() {
+	for _, command := range []*slashCommand{
+		{name: "new", description: "Start a new thread with the agent", run: (*ChatModel).commandNew},
+		{name: "thread", usage: "<id>", description: "Resume a saved session or an app thread", run: (*ChatModel).commandThread, complete: (*ChatModel).completeThread},
+		{name: "attach", usage: "<path>", description: "Attach a file to the next prompt", run: (*ChatModel).commandAttach, complete: completePath},
+		{name: "tool", usage: "<toolkit>/<tool> {json}", description: "Invoke a tool directly", run: (*ChatModel).commandTool, complete: (*ChatModel).completeTool},
+		{name: "export", usage: "[path]", description: "Export the transcript (.md or .json)", run: (*ChatModel).commandExport, complete: completePath},
+		{name: "clear", description: "Clear the chat on screen, keeping the thread", run: (*ChatModel).commandClear},
+		{name: "agent", usage: "<name>", description: "Chat with another agent", run: (*ChatModel).commandAgent, complete: (*ChatModel).completeAgent},
+		{name: "retry", description: "Send the last prompt again", run: (*ChatModel).commandRetry},
+		{name: "help", description: "List the commands", run: (*ChatModel).commandHelp},
+	} {
+		registerSlashCommand(command)
+	}
+}
+
+// slashCommandNames returns the names of the commands starting with prefix,
+// sorted
+func slashCommandNames(prefix string) []string {
+	var names []string
+	for name := range slashCommands {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseSlashCommand splits input such as "/attach notes.md" into the command
+// name and its arguments. ok is false for input that is not a command; a
+// leading "//" sends the message with a single slash.
+func parseSlashCommand(input string) (name, args string, ok bool) {
+	if !strings.HasPrefix(input, "/") || strings.HasPrefix(input, "//") {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(strings.TrimSpace(input[1:]), " ")
+	return name, strings.TrimSpace(args), true
+}
+
+// typingCommand reports whether the input holds a slash command
+func (m *ChatModel) typingCommand() bool {
+	_, _, ok := parseSlashCommand(m.editor.Value())
+	return ok
+}
+
+// runSlashCommand runs a command typed in the input
+func (m *ChatModel) runSlashCommand(name, args string) tea.Cmd {
+	m.notice = ""
+	m.completions = nil
+	command, ok := slashCommands[name]
+	if !ok {
+		m.notice = fmt.Sprintf("⚠️  Unknown command /%s; /help lists the commands", name)
+		return nil
+	}
+	return command.run(m, args)
+}
+
+// completeSlashCommand completes the command name or arguments being typed.
+// With several candidates, the input is completed as far as they agree and
+// the candidates are listed.
+func (m *ChatModel) completeSlashCommand() {
+	input := m.editor.Value()
+	name, args, hasArgs := strings.Cut(input[1:], " ")
+	m.completions = nil
+
+	if !hasArgs {
+		names := slashCommandNames(name)
+		if len(names) == 1 {
+			m.setInput("/" + names[0] + " ")
+			return
+		}
+		if prefix := commonPrefix(names); len(prefix) > len(name) {
+			m.setInput("/" + prefix)
+		}
+		return
+	}
+
+	command, ok := slashCommands[name]
+	if !ok || command.complete == nil {
+		return
+	}
+	args = strings.TrimLeft(args, " ")
+	candidates := command.complete(m, args)
+	switch {
+	case len(candidates) == 1:
+		m.setInput("/" + name + " " + candidates[0])
+	case len(candidates) > 1:
+		if prefix := commonPrefix(candidates); len(prefix) > len(args) {
+			m.setInput("/" + name + " " + prefix)
+		}
+		m.completions = candidates
+	}
+}
+
+// commonPrefix returns the longest prefix shared by all values, ending on a
+// whole rune
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// renderCommandSuggestions lists the commands matching the input, or the
+// argument completions found by the last tab
+func (m *ChatModel) renderCommandSuggestions() string {
+	const maxSuggestions = 10
+
+	var lines []string
+	name, _, hasArgs := strings.Cut(m.editor.Value()[1:], " ")
+	if !hasArgs {
+		for _, match := range slashCommandNames(name) {
+			command := slashCommands[match]
+			lines = append(lines, fmt.Sprintf("/%-8s %-24s %s", command.name, command.usage, command.description))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No matching command")
+		}
+	} else {
+		for _, candidate := range m.completions {
+			lines = append(lines, strings.TrimSpace(candidate))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if len(lines) > maxSuggestions {
+		more := len(lines) - maxSuggestions
+		lines = append(lines[:maxSuggestions], fmt.Sprintf("… %d more", more))
+	}
+	return HelpStyle.MarginTop(0).Render(strings.Join(lines, "\n")) + "\n"
+}
+
+// commandNew starts over with the active agent in a new thread
+func (m *ChatModel) commandNew(string) tea.Cmd {
+	if session := m.GetActiveSession(); session == nil {
+		m.notice = "⚠️  Pick an agent first"
+	} else if err := m.startNewThread(); err != nil {
+		m.notice = "⚠️  " + err.Error()
+	}
+	return nil
+}
+
+// commandThread resumes a saved session by session or thread ID. An ID not
+// saved in the project is taken as an app thread to continue with the
+// active agent.
+func (m *ChatModel) commandThread(id string) tea.Cmd {
+	if id == "" {
+		m.notice = "⚠️  Usage: /thread <id>"
+		return nil
+	}
+	if m.store != nil {
+		list, err := m.store.List()
+		if err != nil {
+			m.notice = "⚠️  " + err.Error()
+			return nil
+		}
+		for _, record := range list {
+			if record.ID == id || record.ThreadID == id {
+				if err := m.resumeSession(record); err != nil {
+					m.notice = "⚠️  " + err.Error()
+				}
+				return nil
+			}
+		}
+	}
+
+	current := m.GetActiveSession()
+	if current == nil {
+		m.notice = fmt.Sprintf("⚠️  No saved session %q; pick an agent to continue the thread with", id)
+		return nil
+	}
+	if current.IsWaiting {
+		m.notice = fmt.Sprintf("⚠️  %s is still answering; try again when it is done", current.Agent.Name)
+		return nil
+	}
+	session := newChatSession(current.Agent)
+	session.ThreadID = id
+	m.openSession(session)
+	m.notice = fmt.Sprintf("Continuing thread %s with %s", id, current.Agent.Name)
+	return nil
+}
+
+// completeThread completes the saved sessions of the active agent
+func (m *ChatModel) completeThread(args string) []string {
+	if m.store == nil {
+		return nil
+	}
+	list, err := m.store.List()
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, record := range list {
+		if m.activeAgentID != "" && record.Agent != m.activeAgentID {
+			continue
+		}
+		if strings.HasPrefix(record.ID, args) {
+			candidates = append(candidates, record.ID)
+		} else if record.ThreadID != "" && strings.HasPrefix(record.ThreadID, args) {
+			candidates = append(candidates, record.ThreadID)
+		}
+	}
+	return candidates
+}
+
+// commandAttach attaches a file to the next prompt
+func (m *ChatModel) commandAttach(path string) tea.Cmd {
+	if path == "" {
+		m.notice = "⚠️  Usage: /attach <path>"
+		return nil
+	}
+	if err := m.AttachFile(expandHome(path)); err != nil {
+		m.notice = "⚠️  " + err.Error()
+		return nil
+	}
+	m.notice = "📎 Attached " + filepath.Base(path)
+	return nil
+}
+
+// completePath completes a file path. Directories end in a slash, so tab
+// can go on into them.
+func completePath(_ *ChatModel, args string) []string {
+	dir, base := filepath.Split(args)
+	readDir := expandHome(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		candidates = append(candidates, dir+name)
+	}
+	return candidates
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+type toolsLoadedMsg struct {
+	tools []ipc.SingleToolInfo
+	err   error
+}
+
+// requestToolsCmd loads the app's tools for /tool
+func requestToolsCmd(client *ipc.Client) tea.Cmd {
+	return func() tea.Msg {
+		if client == nil || !client.IsRunning() {
+			return toolsLoadedMsg{err: fmt.Errorf("IPC client not running")}
+		}
+		ctx, cancel := context.WithTimeout(client.Context(), 10*time.Second)
+		defer cancel()
+		tools, err := client.GetTools(ctx)
+		return toolsLoadedMsg{tools: tools, err: err}
+	}
+}
+
+type toolInvokedMsg struct {
+	agentID string
+	call    *ToolCall
+	result  json.RawMessage
+	err     error
+}
+
+// commandTool invokes a tool with JSON arguments and shows the call in the
+// chat like the agent's own tool calls
+func (m *ChatModel) commandTool(args string) tea.Cmd {
+	target, rawArgs, _ := strings.Cut(args, " ")
+	toolkitName, toolName, ok := strings.Cut(target, "/")
+	if !ok || toolkitName == "" || toolName == "" {
+		m.notice = "⚠️  Usage: /tool <toolkit>/<tool> {json}"
+		return nil
+	}
+	rawArgs = strings.TrimSpace(rawArgs)
+	if rawArgs == "" {
+		rawArgs = "{}"
+	}
+	var toolArgs map[string]any
+	if err := json.Unmarshal([]byte(rawArgs), &toolArgs); err != nil {
+		m.notice = fmt.Sprintf("⚠️  Tool arguments must be a JSON object: %v", err)
+		return nil
+	}
+	if tool := m.findTool(toolkitName, toolName); tool != nil {
+		if err := ipc.ValidateToolArgs(*tool, toolArgs); err != nil {
+			m.notice = "⚠️  " + err.Error()
+			return nil
+		}
+	} else if m.tools != nil {
+		m.notice = fmt.Sprintf("⚠️  Tool %s/%s not found in app", toolkitName, toolName)
+		return nil
+	}
+	session := m.GetActiveSession()
+	if session == nil {
+		m.notice = "⚠️  Pick an agent first; the tool call is shown in its chat"
+		return nil
+	}
+	if m.ipcClient == nil {
+		m.notice = "⚠️  No app is running"
+		return nil
+	}
+
+	now := time.Now()
+	call := &ToolCall{
+		Name:      target,
+		Arguments: json.RawMessage(rawArgs),
+		StartedAt: now,
+	}
+	session.Messages = append(session.Messages, &ChatMessage{
+		Role:     sessions.RoleTool,
+		AgentID:  session.Agent.ID,
+		Time:     now,
+		ToolCall: call,
+	})
+	client, agentID := m.ipcClient, session.Agent.ID
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(client.Context(), 2*time.Minute)
+		defer cancel()
+		result, err := client.InvokeTool(ctx, toolkitName, toolName, toolArgs)
+		return toolInvokedMsg{agentID: agentID, call: call, result: result, err: err}
+	}
+}
+
+// handleToolInvoked shows the result of /tool
+func (m *ChatModel) handleToolInvoked(msg toolInvokedMsg) {
+	call := msg.call
+	call.Completed = true
+	call.Duration = time.Since(call.StartedAt)
+	if msg.err != nil {
+		call.Output = msg.err.Error()
+		call.Failed = true
+	} else {
+		call.Output = sessions.PrettyJSON(msg.result)
+		call.Failed = ipc.ToolCallOutput{Output: string(msg.result)}.Failed()
+	}
+	call.Expanded = true
+	if session := m.sessions[msg.agentID]; session != nil {
+		m.saveSession(session)
+	}
+}
+
+// findTool returns a tool of the app, if the tools are loaded
+func (m *ChatModel) findTool(toolkitName, toolName string) *ipc.SingleToolInfo {
+	for i := range m.tools {
+		if m.tools[i].ToolkitName == toolkitName && m.tools[i].Name == toolName {
+			return &m.tools[i]
+		}
+	}
+	return nil
+}
+
+// completeTool completes the name of a tool
+func (m *ChatModel) completeTool(args string) []string {
+	if strings.Contains(args, " ") {
+		return nil
+	}
+	var candidates []string
+	for _, tool := range m.tools {
+		name := tool.ToolkitName + "/" + tool.Name
+		if strings.HasPrefix(name, args) {
+			candidates = append(candidates, name+" ")
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// commandExport writes the transcript of the active session to path, or to
+// the project's export directory as Markdown
+func (m *ChatModel) commandExport(path string) tea.Cmd {
+	session := m.GetActiveSession()
+	if session == nil || len(session.Messages) == 0 {
+		m.notice = "⚠️  Nothing to export yet"
+		return nil
+	}
+	record := session.Record()
+	if path == "" {
+		path = sessions.ExportPath(m.projectDir, record, sessions.FormatMarkdown)
+	}
+	path = expandHome(path)
+	if err := sessions.ExportFile(record, path); err != nil {
+		m.notice = "⚠️  " + err.Error()
+		return nil
+	}
+	m.notice = "📄 Exported transcript to " + path
+	return nil
+}
+
+// commandClear clears the chat on screen. The agent's thread and the saved
+// session are kept.
+func (m *ChatModel) commandClear(string) tea.Cmd {
+	session := m.GetActiveSession()
+	if session == nil {
+		return nil
+	}
+	if session.IsWaiting || session.currentMessageIndex != -1 {
+		m.notice = fmt.Sprintf("⚠️  %s is still answering; try again when it is done", session.Agent.Name)
+		return nil
+	}
+	session.Messages = []*ChatMessage{}
+	session.selectedTool = -1
+	m.scrollOffset = 0
+	return nil
+}
+
+// commandAgent switches to the chat with an agent, by name or ID
+func (m *ChatModel) commandAgent(name string) tea.Cmd {
+	if name == "" {
+		m.notice = "⚠️  Usage: /agent <name>"
+		return nil
+	}
+	for _, agent := range m.agents {
+		if strings.EqualFold(agent.Name, name) || strings.EqualFold(agent.ID, name) {
+			m.StartSession(agent)
+			m.resetHistoryNavigation()
+			m.scrollOffset = 0
+			return nil
+		}
+	}
+	m.notice = fmt.Sprintf("⚠️  No agent named %q", name)
+	return nil
+}
+
+// completeAgent completes the names of the app's agents
+func (m *ChatModel) completeAgent(args string) []string {
+	var candidates []string
+	for _, agent := range m.agents {
+		if strings.HasPrefix(strings.ToLower(agent.Name), strings.ToLower(args)) {
+			candidates = append(candidates, agent.Name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// commandRetry sends the last prompt of the active session again.
+// Attachments are not sent again.
+func (m *ChatModel) commandRetry(string) tea.Cmd {
+	session := m.GetActiveSession()
+	if session == nil {
+		return nil
+	}
+	if session.IsWaiting {
+		m.notice = fmt.Sprintf("⚠️  %s is still answering; try again when it is done", session.Agent.Name)
+		return nil
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if message := session.Messages[i]; message.Role == sessions.RoleUser {
+			prompt, agentID := message.Content, session.Agent.ID
+			return func() tea.Msg {
+				return ChatMessage{Role: "user", Content: prompt, AgentID: agentID}
+			}
+		}
+	}
+	m.notice = "⚠️  No prompt to retry"
+	return nil
+}
+
+// commandHelp lists the commands
+func (m *ChatModel) commandHelp(string) tea.Cmd {
+	lines := []string{"Commands (tab completes, // sends a message starting with /):"}
+	for _, name := range slashCommandNames("") {
+		command := slashCommands[name]
+		lines = append(lines, fmt.Sprintf("  /%-8s %-24s %s", command.name, command.usage, command.description))
+	}
+	m.notice = strings.Join(lines, "\n")
+	return nil
+}
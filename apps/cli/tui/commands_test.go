@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shuttl-ai/cli/ipc"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	testCases := []struct {
+		input string
+		name  string
+		args  string
+		ok    bool
+	}{
+		{"/new", "new", "", true},
+		{"/attach notes.md", "attach", "notes.md", true},
+		{"/tool Math/add  {\"a\": 1} ", "tool", "Math/add  {\"a\": 1}", true},
+		{"/ help", "help", "", true},
+		{"/", "", "", true},
+		{"hello /new", "", "", false},
+		{"//etc/hosts is a file", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			name, args, ok := parseSlashCommand(tc.input)
+			if name != tc.name || args != tc.args || ok != tc.ok {
+				t.Errorf("parseSlashCommand(%q) = %q, %q, %v; expected %q, %q, %v", tc.input, name, args, ok, tc.name, tc.args, tc.ok)
+			}
+		})
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	testCases := []struct {
+		name   string
+		values []string
+		prefix string
+	}{
+		{"none", nil, ""},
+		{"one", []string{"thread"}, "thread"},
+		{"shared", []string{"Math/add ", "Math/abs "}, "Math/a"},
+		{"nothing shared", []string{"new", "help"}, ""},
+		{"one is a prefix of another", []string{"notes", "notes.md"}, "notes"},
+		{"multibyte runes that differ", []string{"été", "èté"}, ""},
+		{"multibyte runes that agree", []string{"café/a", "café/b"}, "café/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if prefix := commonPrefix(tc.values); prefix != tc.prefix {
+				t.Errorf("commonPrefix(%q) = %q, expected %q", tc.values, prefix, tc.prefix)
+			}
+		})
+	}
+}
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.md", "notes.txt", "report.pdf", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	base := dir + string(filepath.Separator)
+
+	testCases := []struct {
+		name       string
+		args       string
+		candidates []string
+	}{
+		{"prefix", base + "no", []string{base + "notes.md", base + "notes.txt"}},
+		{"directories end in a separator", base + "ne", []string{base + "nested" + string(filepath.Separator)}},
+		{"hidden files only when asked", base + ".", []string{base + ".hidden"}},
+		{"no match", base + "zzz", nil},
+		{"missing directory", filepath.Join(dir, "missing", "x"), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if candidates := completePath(nil, tc.args); !reflect.DeepEqual(candidates, tc.candidates) {
+				t.Errorf("completePath(%q) = %q, expected %q", tc.args, candidates, tc.candidates)
+			}
+		})
+	}
+
+	t.Run("all entries but hidden ones", func(t *testing.T) {
+		if candidates := completePath(nil, base); len(candidates) != 4 {
+			t.Errorf("Expected 4 candidates, got %q", candidates)
+		}
+	})
+}
+
+func TestCompleteSlashCommand(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    string
+		completions []string
+	}{
+		{"unique command", "/ne", "/new ", nil},
+		{"ambiguous commands", "/t", "/t", nil},
+		{"unknown command", "/zzz", "/zzz", nil},
+		{"command without completion", "/new x", "/new x", nil},
+		{"unique agent", "/agent sup", "/agent Support", nil},
+		{"several tools", "/tool Math/a", "/tool Math/a", []string{"Math/abs ", "Math/add "}},
+		{"tools sharing a prefix", "/tool Ma", "/tool Math/a", []string{"Math/abs ", "Math/add "}},
+		{"unique tool", "/tool Text/", "/tool Text/upper ", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewChatModel(nil)
+			m.agents["support"] = Agent{ID: "support", Name: "Support"}
+			m.agents["sales"] = Agent{ID: "sales", Name: "Sales"}
+			m.tools = []ipc.SingleToolInfo{
+				{ToolkitName: "Math", Name: "add"},
+				{ToolkitName: "Math", Name: "abs"},
+				{ToolkitName: "Text", Name: "upper"},
+			}
+			m.setInput(tc.input)
+
+			m.completeSlashCommand()
+
+			if value := m.editor.Value(); value != tc.expected {
+				t.Errorf("Expected input %q, got %q", tc.expected, value)
+			}
+			if !reflect.DeepEqual(m.completions, tc.completions) {
+				t.Errorf("Expected completions %q, got %q", tc.completions, m.completions)
+			}
+		})
+	}
+}
+
+func TestCommandTool(t *testing.T) {
+	tools := []ipc.SingleToolInfo{{
+		ToolkitName: "Math",
+		Name:        "add",
+		Args:        map[string]any{"a": map[string]any{"type": "number"}},
+	}}
+
+	testCases := []struct {
+		name    string
+		args    string
+		tools   []ipc.SingleToolInfo
+		session bool
+		notice  string
+	}{
+		{"missing tool", "", tools, true, "Usage: /tool"},
+		{"missing toolkit", "/add", tools, true, "Usage: /tool"},
+		{"missing slash", "add {}", tools, true, "Usage: /tool"},
+		{"arguments not an object", "Math/add [1]", tools, true, "must be a JSON object"},
+		{"invalid JSON", "Math/add {a:1}", tools, true, "must be a JSON object"},
+		{"arguments against the schema", `Math/add {"a": "one"}`, tools, true, "a: expected number, got string"},
+		{"unknown tool", "Math/sub {}", tools, true, "Tool Math/sub not found"},
+		{"tools not loaded", "Math/sub {}", nil, false, "Pick an agent first"},
+		{"no agent", "Math/add {}", tools, false, "Pick an agent first"},
+		{"no app", `Math/add {"a": 1}`, tools, true, "No app is running"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewChatModel(nil)
+			m.tools = tc.tools
+			if tc.session {
+				m.StartSession(Agent{ID: "support", Name: "Support"})
+			}
+
+			if cmd := m.commandTool(tc.args); cmd != nil {
+				t.Error("Expected no command")
+			}
+			if !strings.Contains(m.notice, tc.notice) {
+				t.Errorf("Expected a notice containing %q, got %q", tc.notice, m.notice)
+			}
+		})
+	}
+
+	t.Run("valid call", func(t *testing.T) {
+		m := NewChatModel(ipc.NewClient([]string{"app"}))
+		m.tools = tools
+		m.StartSession(Agent{ID: "support", Name: "Support"})
+
+		if cmd := m.commandTool("Math/add"); cmd == nil {
+			t.Fatalf("Expected a command, got notice %q", m.notice)
+		}
+		messages := m.GetActiveSession().Messages
+		if len(messages) != 1 || messages[0].ToolCall == nil {
+			t.Fatalf("Expected the tool call in the chat, got %+v", messages)
+		}
+		call := messages[0].ToolCall
+		if call.Name != "Math/add" || string(call.Arguments) != "{}" {
+			t.Errorf("Unexpected tool call %s %s", call.Name, call.Arguments)
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/shuttl-ai/cli/log"
+	"github.com/shuttl-ai/cli/sessions"
+)
+
+// maxEditorHeight is how many rows the input grows to before it scrolls
+const maxEditorHeight = 8
+
+// Escape sequences that turn xterm's modifyOtherKeys on for the TUI and back
+// to the terminal's default. Level 1 makes the terminal report modified keys
+// it would otherwise send as plain keys, such as shift+enter, and leaves
+// ctrl+letter, esc and the other keys Bubble Tea reads alone. The kitty
+// keyboard protocol is not enabled because it also re-encodes those.
+const (
+	enableModifyOtherKeys = "\x1b[>4;1m"
+	resetModifyOtherKeys  = "\x1b[>4m"
+)
+
+// Terminals that report shift+enter send it as a CSI sequence Bubble Tea
+// does not know: "CSI 27;2;13~" (xterm modifyOtherKeys) or, in terminals set
+// up to use it, "CSI 13;2u" (kitty keyboard protocol). Bubble Tea passes them
+// on as a message printing the sequence's bytes.
+var shiftEnterSequences = map[string]bool{
+	"?CSI[49 51 59 50 117]?":          true,
+	"?CSI[50 55 59 50 59 49 51 126]?": true,
+}
+
+// isShiftEnter reports whether msg is shift+enter from a terminal that
+// reports it
+func isShiftEnter(msg tea.Msg) bool {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		return false
+	}
+	sequence, ok := msg.(fmt.Stringer)
+	return ok && shiftEnterSequences[sequence.String()]
+}
+
+// newlineKey is what shift+enter is turned into. Alt+enter and ctrl+j insert
+// a newline in every terminal, including the many that do not report
+// shift+enter.
+var newlineKey = tea.KeyMsg{Type: tea.KeyEnter, Alt: true}
+
+// newEditor creates the chat input. Enter is left to the chat to send the
+// prompt.
+func newEditor() textarea.Model {
+	editor := textarea.New()
+	editor.Prompt = ""
+	editor.Placeholder = "Type a message…"
+	editor.ShowLineNumbers = false
+	editor.CharLimit = 0
+	// A MaxHeight also limits the number of lines, so the height is kept in
+	// check by fitEditor instead
+	editor.MaxHeight = 0
+	editor.SetHeight(1)
+	editor.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	editor.FocusedStyle.Placeholder = lipgloss.NewStyle().Foreground(mutedColor)
+	editor.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	editor.Cursor.SetMode(cursor.CursorStatic)
+	editor.Focus()
+	return editor
+}
+
+// fitEditor grows the input with its content, up to maxEditorHeight rows
+func (m *ChatModel) fitEditor() {
+	width := m.editor.Width()
+	if width <= 0 {
+		m.editor.SetHeight(1)
+		return
+	}
+	rows := 0
+	for _, line := range strings.Split(m.editor.Value(), "\n") {
+		// The last column is kept for the cursor
+		rows += lipgloss.Width(line)/width + 1
+	}
+	m.editor.SetHeight(min(rows, maxEditorHeight))
+}
+
+// setInput replaces the input, leaving the cursor at its end
+func (m *ChatModel) setInput(value string) {
+	m.editor.SetValue(value)
+	m.fitEditor()
+}
+
+// updateEditor passes a key to the input
+func (m *ChatModel) updateEditor(msg tea.KeyMsg) tea.Cmd {
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	m.fitEditor()
+	return cmd
+}
+
+// history returns the prompts sent to an agent. They are saved in the
+// project when sessions are enabled, and kept in memory otherwise.
+func (m *ChatModel) history(agentID string) *sessions.History {
+	if history, ok := m.histories[agentID]; ok {
+		return history
+	}
+	history := sessions.NewHistory()
+	if m.projectDir != "" {
+		loaded, err := sessions.LoadHistory(m.projectDir, agentID)
+		if err != nil {
+			log.Warn("Prompt history of %s not loaded: %v", agentID, err)
+		} else {
+			history = loaded
+		}
+	}
+	m.histories[agentID] = history
+	return history
+}
+
+// addToHistory records a prompt sent to the active agent
+func (m *ChatModel) addToHistory(prompt string) {
+	m.historyIndex = -1
+	if m.activeAgentID == "" {
+		return
+	}
+	if err := m.history(m.activeAgentID).Add(prompt); err != nil {
+		log.Warn("Prompt history not saved: %v", err)
+	}
+}
+
+// resetHistoryNavigation stops browsing the history, keeping the input
+func (m *ChatModel) resetHistoryNavigation() {
+	m.historyIndex = -1
+	m.historyDraft = ""
+}
+
+// historyUp recalls the previous prompt when the cursor is on the first row
+// of the input. It returns false if the key is left to the input.
+func (m *ChatModel) historyUp() bool {
+	if m.activeAgentID == "" || m.editor.Line() > 0 || m.editor.LineInfo().RowOffset > 0 {
+		return false
+	}
+	entries := m.history(m.activeAgentID).Entries()
+	if len(entries) == 0 {
+		return false
+	}
+	index := m.historyIndex
+	if index < 0 {
+		// Keep what was being typed to come back to
+		m.historyDraft = m.editor.Value()
+		index = len(entries)
+	}
+	if index > 0 {
+		index--
+	}
+	m.historyIndex = index
+	m.setInput(entries[index])
+	return true
+}
+
+// historyDown goes back toward the prompt being typed when the cursor is on
+// the last row of the input. It returns false if the key is left to the
+// input.
+func (m *ChatModel) historyDown() bool {
+	if m.historyIndex < 0 || m.editor.Line() < m.editor.LineCount()-1 {
+		return false
+	}
+	if info := m.editor.LineInfo(); info.RowOffset < info.Height-1 {
+		return false
+	}
+	entries := m.history(m.activeAgentID).Entries()
+	m.historyIndex++
+	if m.historyIndex >= len(entries) {
+		m.setInput(m.historyDraft)
+		m.resetHistoryNavigation()
+		return true
+	}
+	m.setInput(entries[m.historyIndex])
+	return true
+}
+
+type externalEditorMsg struct {
+	path string
+	err  error
+}
+
+// externalEditor returns the command to edit a prompt with: $VISUAL, then
+// $EDITOR, then vi
+func externalEditor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if args := strings.Fields(os.Getenv(env)); len(args) > 0 {
+			return args
+		}
+	}
+	return []string{"vi"}
+}
+
+// openExternalEditor suspends the TUI to edit the input in the user's
+// editor
+func (m *ChatModel) openExternalEditor() tea.Cmd {
+	file, err := os.CreateTemp("", "shuttl-prompt-*.md")
+	if err != nil {
+		m.notice = fmt.Sprintf("⚠️  Could not open an editor: %v", err)
+		return nil
+	}
+	_, err = file.WriteString(m.editor.Value())
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		m.notice = fmt.Sprintf("⚠️  Could not open an editor: %v", err)
+		return nil
+	}
+
+	args := append(externalEditor(), file.Name())
+	command := exec.Command(args[0], args[1:]...)
+	path := file.Name()
+	return tea.ExecProcess(command, func(err error) tea.Msg {
+		return externalEditorMsg{path: path, err: err}
+	})
+}
+
+// handleExternalEditor takes the prompt written in the editor as the input
+func (m *ChatModel) handleExternalEditor(msg externalEditorMsg) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.notice = fmt.Sprintf("⚠️  Editor failed: %v", msg.err)
+		return
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.notice = fmt.Sprintf("⚠️  Could not read the edited prompt: %v", err)
+		return
+	}
+	m.notice = ""
+	m.setInput(strings.TrimRight(string(data), "\n"))
+}
@@ -0,0 +1,210 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/log"
+)
+
+// respondTimeout bounds how long sending a reply to the app may take
+const respondTimeout = 30 * time.Second
+
+// inputRespondedMsg reports whether a reply reached the app
+type inputRespondedMsg struct {
+	agentID string
+	request *ipc.InputRequest
+	err     error
+}
+
+// RequestInput shows the agent's question in the transcript and waits for
+// the user to answer it
+func (c *ChatSession) RequestInput(request *ipc.InputRequest) {
+	c.IsWaiting = false
+	c.currentMessageIndex = -1
+	if request.ThreadID == "" {
+		request.ThreadID = c.ThreadID
+	}
+	if c.ThreadID == "" {
+		c.ThreadID = request.ThreadID
+	}
+	c.Messages = append(c.Messages, &ChatMessage{
+		Role:        "agent",
+		Content:     request.Prompt,
+		AgentID:     c.Agent.ID,
+		IsCompleted: true,
+		Time:        time.Now(),
+	})
+	c.InputRequest = request
+	c.inputChoice = 0
+}
+
+// inputChoices returns the buttons of an approval, or the options to pick
+// from; nil means the answer is typed
+func inputChoices(request *ipc.InputRequest) []string {
+	if request.IsApproval() {
+		return []string{"Approve", "Reject"}
+	}
+	return request.Options
+}
+
+// respond sends the user's answer to the agent, recording it in the
+// transcript. The agent's reply arrives on the chat's stream.
+func (m *ChatModel) respond(session *ChatSession, response ipc.InputResponse, shown string) tea.Cmd {
+	request := session.InputRequest
+	session.InputRequest = nil
+	session.IsWaiting = true
+	session.Messages = append(session.Messages, &ChatMessage{
+		Role:        "user",
+		Content:     shown,
+		AgentID:     session.Agent.ID,
+		IsCompleted: true,
+		Time:        time.Now(),
+	})
+	m.saveSession(session)
+
+	response.ThreadID = request.ThreadID
+	response.RequestID = request.RequestID
+	client := m.ipcClient
+	agentID := session.Agent.ID
+	return func() tea.Msg {
+		if client == nil {
+			return inputRespondedMsg{agentID: agentID, request: request, err: fmt.Errorf("not connected to the app")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), respondTimeout)
+		defer cancel()
+		return inputRespondedMsg{agentID: agentID, request: request, err: client.Respond(ctx, response)}
+	}
+}
+
+// updateInputRequest handles keys while the active session's agent waits
+// for input. Typed answers use the normal input field, so only enter is
+// taken for them; handled is false for keys left to the chat.
+func (m *ChatModel) updateInputRequest(session *ChatSession, key tea.KeyMsg) (cmd tea.Cmd, handled bool) {
+	request := session.InputRequest
+	choices := inputChoices(request)
+
+	if len(choices) == 0 {
+		if key.String() != "enter" {
+			return nil, false
+		}
+		answer := strings.TrimSpace(m.editor.Value())
+		if answer == "" {
+			return nil, true
+		}
+		m.ClearInput()
+		return m.respond(session, ipc.InputResponse{Response: answer}, answer), true
+	}
+
+	switch key.String() {
+	case "left", "up", "shift+tab":
+		if session.inputChoice > 0 {
+			session.inputChoice--
+		}
+	case "right", "down":
+		if session.inputChoice < len(choices)-1 {
+			session.inputChoice++
+		}
+	case "y":
+		if request.IsApproval() {
+			return m.respondWithChoice(session, 0), true
+		}
+	case "n":
+		if request.IsApproval() {
+			return m.respondWithChoice(session, 1), true
+		}
+	case "enter":
+		return m.respondWithChoice(session, session.inputChoice), true
+	default:
+		// Pick an option by its number
+		if s := key.String(); len(s) == 1 && s[0] >= '1' && s[0] <= '9' {
+			if i := int(s[0] - '1'); i < len(choices) {
+				return m.respondWithChoice(session, i), true
+			}
+		}
+		// Typing has nowhere to go; leave the other keys, such as ctrl+n to
+		// switch agents, to the chat
+		return nil, len(key.Runes) > 0
+	}
+	return nil, true
+}
+
+func (m *ChatModel) respondWithChoice(session *ChatSession, choice int) tea.Cmd {
+	request := session.InputRequest
+	if request.IsApproval() {
+		approved := choice == 0
+		shown := "✅ Approved"
+		if !approved {
+			shown = "❌ Rejected"
+		}
+		return m.respond(session, ipc.InputResponse{Approved: &approved}, shown)
+	}
+	option := request.Options[choice]
+	return m.respond(session, ipc.InputResponse{Response: option}, option)
+}
+
+// handleInputResponded reopens the question if the reply did not reach the
+// app, so it can be answered again
+func (m *ChatModel) handleInputResponded(msg inputRespondedMsg) {
+	if msg.err == nil {
+		return
+	}
+	log.Error("Failed to reply to %s: %v", msg.agentID, msg.err)
+	m.notice = fmt.Sprintf("⚠️  Failed to send your reply: %v", msg.err)
+	if session := m.sessions[msg.agentID]; session != nil && session.InputRequest == nil {
+		session.InputRequest = msg.request
+		session.IsWaiting = false
+	}
+}
+
+// renderInputRequest renders the question the agent is waiting on, in place
+// of the input field for approvals and options
+func (m *ChatModel) renderInputRequest(session *ChatSession) string {
+	request := session.InputRequest
+	var b strings.Builder
+
+	title := fmt.Sprintf("🙋 %s needs your input", session.Agent.Name)
+	if request.IsApproval() {
+		title = fmt.Sprintf("🙋 %s is asking for approval", session.Agent.Name)
+	}
+	b.WriteString(TitleStyle.Render(title))
+	b.WriteString("\n")
+	b.WriteString(request.Prompt)
+	b.WriteString("\n\n")
+
+	choices := inputChoices(request)
+	if len(choices) == 0 {
+		b.WriteString(HelpStyle.Render("Type your answer below and press enter"))
+		return BoxStyle.Width(m.width - 6).Render(b.String())
+	}
+
+	if request.IsApproval() {
+		var buttons []string
+		for i, choice := range choices {
+			if i == session.inputChoice {
+				buttons = append(buttons, SelectedItemStyle.Render(" "+choice+" "))
+			} else {
+				buttons = append(buttons, NormalItemStyle.Render(" "+choice+" "))
+			}
+		}
+		b.WriteString(strings.Join(buttons, "  "))
+		b.WriteString("\n")
+		b.WriteString(HelpStyle.Render("y approve • n reject • ←/→ select • enter confirm"))
+	} else {
+		for i, choice := range choices {
+			line := fmt.Sprintf("%d. %s", i+1, choice)
+			if i == session.inputChoice {
+				b.WriteString(SelectedItemStyle.Render("▸ " + line))
+			} else {
+				b.WriteString(NormalItemStyle.Render("  " + line))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(HelpStyle.Render("↑/↓ select • 1-9 pick • enter confirm"))
+	}
+	return BoxStyle.Width(m.width - 6).Render(b.String())
+}
@@ -7,6 +7,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/shuttl-ai/cli/ipc"
 	"github.com/shuttl-ai/cli/log"
 )
 
@@ -29,6 +30,40 @@ type LogMessagesBatch struct {
 	Messages []LogEntry
 }
 
+// AppLogMsg is sent for each log event or stderr line from the app
+type AppLogMsg struct {
+	Event ipc.LogEvent
+}
+
+// waitForLogCmd waits for the next log event from the app
+func waitForLogCmd(client *ipc.Client) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-client.Logs()
+		if !ok {
+			return nil
+		}
+		return AppLogMsg{Event: event}
+	}
+}
+
+// newLogEntry converts an app log event into an entry for the logs view
+func newLogEntry(event ipc.LogEvent) LogEntry {
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	message := event.Message
+	if event.Source == ipc.LogSourceStderr {
+		message = "stderr: " + message
+	}
+	return LogEntry{
+		Timestamp: timestamp.Local().Format("15:04:05"),
+		Level:     event.Level,
+		AgentID:   event.AgentID,
+		Message:   message,
+	}
+}
+
 // NewLogsModel creates a new logs model
 func NewLogsModel() *LogsModel {
 	return &LogsModel{
@@ -62,8 +97,12 @@ func (m *LogsModel) SetAgents(agents []Agent) {
 	m.agents = agents
 }
 
-// AddLog adds a log entry
+// AddLog adds a log entry. When scrolled back, the view stays on the same
+// entries instead of following new ones.
 func (m *LogsModel) AddLog(entry LogEntry) {
+	if m.scrollOffset > 0 && m.matches(entry) {
+		m.scrollOffset++
+	}
 	m.logs = append(m.logs, entry)
 	// Keep only last 1000 logs
 	if len(m.logs) > 1000 {
@@ -75,20 +114,43 @@ func (m *LogsModel) AddLog(entry LogEntry) {
 func (m LogsModel) GetFilteredLogs() []LogEntry {
 	var filtered []LogEntry
 	for _, log := range m.logs {
-		if m.agentFilter != "" && log.AgentID != m.agentFilter {
-			continue
-		}
-		if m.levelFilter != "" && m.levelFilter != "all" && log.Level != m.levelFilter {
-			continue
+		if m.matches(log) {
+			filtered = append(filtered, log)
 		}
-		filtered = append(filtered, log)
 	}
 	return filtered
 }
 
+// matches reports whether an entry passes the current filters
+func (m LogsModel) matches(log LogEntry) bool {
+	if m.agentFilter != "" && log.AgentID != m.agentFilter {
+		return false
+	}
+	if m.levelFilter != "" && m.levelFilter != "all" && log.Level != m.levelFilter {
+		return false
+	}
+	return true
+}
+
 // Update handles input for the logs view
 func (m *LogsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case AppLogMsg:
+		m.AddLog(newLogEntry(msg.Event))
+		return m, nil
+
+	case LogMessagesBatch:
+		for _, entry := range msg.Messages {
+			m.AddLog(entry)
+		}
+		return m, nil
+
+	case AgentsLoadedMsg:
+		if msg.Err == nil {
+			m.SetAgents(msg.Agents)
+		}
+		return m, nil
+
 	case ChatMessage:
 		m.AddLog(LogEntry{
 			Timestamp: time.Now().Format("15:04:05"),
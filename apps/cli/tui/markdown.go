@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// defaultMarkdownStyle is used unless GLAMOUR_STYLE names another style, such
+// as "light", "notty", "dracula" or the path of a JSON style file. Detecting
+// the terminal's background is avoided since it races with bubbletea for
+// stdin.
+const defaultMarkdownStyle = "dark"
+
+// markdownRenderer renders agent replies as terminal Markdown, keeping one
+// glamour renderer for the current width
+type markdownRenderer struct {
+	width    int
+	renderer *glamour.TermRenderer
+}
+
+// render renders text to fit width. The renderer is rebuilt when the width
+// changes.
+func (r *markdownRenderer) render(text string, width int) (string, error) {
+	if r.renderer == nil || r.width != width {
+		style := os.Getenv("GLAMOUR_STYLE")
+		if style == "" {
+			style = defaultMarkdownStyle
+		}
+		renderer, err := glamour.NewTermRenderer(
+			glamour.WithStylePath(style),
+			glamour.WithWordWrap(width),
+			glamour.WithEmoji(),
+		)
+		if err != nil {
+			return "", err
+		}
+		r.renderer = renderer
+		r.width = width
+	}
+	out, err := r.renderer.Render(text)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(out, "\n"), nil
+}
+
+// renderMarkdown returns a committed message rendered as Markdown, caching
+// the result until the width changes. Messages that fail to render are shown
+// as they are.
+func (m *ChatModel) renderMarkdown(msg *ChatMessage, width int) string {
+	if msg.rendered != "" && msg.renderedWidth == width {
+		return msg.rendered
+	}
+	out, err := m.markdown.render(msg.Content, width)
+	if err != nil {
+		return msg.Content
+	}
+	msg.rendered = out
+	msg.renderedWidth = width
+	return out
+}
@@ -33,6 +33,12 @@ type DisconnectionWarning struct {
 	Reason string
 }
 
+// AppReloadedMsg is sent after the app is reloaded because its files changed
+type AppReloadedMsg struct {
+	Files []string
+	Err   error
+}
+
 // IPCOutputMsg is sent when output is received from the IPC client
 type IPCOutputMsg struct {
 	Output ipc.OutputLine
@@ -59,6 +65,9 @@ type Model struct {
 	cancel               context.CancelFunc
 	warningScreenDetails *WarningScreenDetails
 	disconnectionWarning *DisconnectionWarning
+	restartsSeen         int
+	reloads              <-chan AppReloadedMsg
+	lastReload           time.Time
 }
 
 // NewModel creates a new TUI model
@@ -190,6 +199,17 @@ func requestAgentsCmd(client *ipc.Client) tea.Cmd {
 	}
 }
 
+// waitForReloadCmd waits for the next app reload
+func waitForReloadCmd(reloads <-chan AppReloadedMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-reloads
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
 	if m.showingWarning {
@@ -200,6 +220,17 @@ func (m Model) Init() tea.Cmd {
 		activateScreen(0),
 	}
 
+	// Poll the app process so crashes and restarts show up
+	if m.ipcClient != nil {
+		cmds = append(cmds, tickCmd())
+	}
+	if m.reloads != nil {
+		cmds = append(cmds, waitForReloadCmd(m.reloads))
+	}
+	if m.ipcClient != nil {
+		cmds = append(cmds, waitForLogCmd(m.ipcClient))
+	}
+
 	for i, screen := range m.screens {
 		screen.SetScreenIndex(i)
 		cmd := screen.Init()
@@ -216,6 +247,9 @@ func (m Model) Init() tea.Cmd {
 
 // Update handles messages
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if isShiftEnter(msg) {
+		msg = newlineKey
+	}
 	switch msg := msg.(type) {
 	case activateScreenMsg:
 		m.activeScreenIndex = int(msg)
@@ -241,6 +275,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			Action:      "Available models: " + strings.Join(availableModels, ", "),
 		}
 		return m, tickCmd()
+	case AppReloadedMsg:
+		next := waitForReloadCmd(m.reloads)
+		if msg.Err != nil {
+			log.Error("Failed to reload app: %v", msg.Err)
+			return m, next
+		}
+		log.Info("Reloaded app after changes to %s", strings.Join(msg.Files, ", "))
+		m.lastReload = time.Now()
+		// Chat sessions live in the TUI, so only the agent list needs refreshing
+		return m, tea.Batch(next, requestAgentsCmd(m.ipcClient))
+	case AppLogMsg:
+		// Keep listening, and let the logs screen show the event
+		model, cmd := m.updateScreens(msg)
+		return model, tea.Batch(cmd, waitForLogCmd(m.ipcClient))
 	case activateScreenObjMsg:
 		obj := msg.obj
 		for i, screen := range m.screens {
@@ -266,10 +314,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if m.ipcClient != nil && m.ipcClient.IsRunning() {
 			log.Debug("IPC client is running, sending tick")
+			// The supervisor restarted the app, so reload its agents
+			if restarts := m.ipcClient.Restarts(); restarts > m.restartsSeen {
+				m.restartsSeen = restarts
+				log.Warn("App was restarted (%d restarts so far)", restarts)
+				return m, tea.Batch(tickCmd(), requestAgentsCmd(m.ipcClient))
+			}
+		} else if m.ipcClient != nil && m.ipcClient.State() == ipc.StateRestarting {
+			log.Debug("IPC client is restarting")
+		} else if m.ipcClient != nil && m.ipcClient.State() == ipc.StateCrashed {
+			log.Debug("IPC client crashed, waiting for a reload")
 		} else if m.ipcClient != nil && !m.ipcClient.IsRunning() && m.disconnectionWarning == nil {
 			log.Error("Unexpectedly lost IPC client connection")
+			reason := "The child process has unexpectedly terminated."
+			if status := m.ipcClient.Status(); status.GaveUp != "" {
+				reason = fmt.Sprintf("The child process kept crashing after %d restarts: %s", status.Restarts, status.GaveUp)
+			}
 			m.disconnectionWarning = &DisconnectionWarning{
-				Reason: "The child process has unexpectedly terminated.",
+				Reason: reason,
 			}
 		}
 		return m, tickCmd()
@@ -300,6 +362,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		// A dialog on the active screen handles its own esc and tab
+		if modal, ok := m.screens[m.activeScreenIndex].(modalScreen); ok && modal.InModal() {
+			switch msg.String() {
+			case "ctrl+c", "ctrl+q":
+				m.quitting = true
+				m.cancel()
+				return m, tea.Quit
+			}
+			return m.updateScreens(msg)
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "ctrl+q":
 			m.quitting = true
@@ -328,6 +401,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	return m.updateScreens(msg)
+}
+
+// updateScreens passes msg to every screen, or only to the active screen for
+// key presses
+func (m Model) updateScreens(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	for i, screen := range m.screens {
 		_, ok := msg.(tea.KeyMsg)
@@ -614,9 +693,28 @@ func (m Model) renderHeader() string {
 
 	appInfo := ""
 	if m.ipcClient != nil && m.ipcClient.IsRunning() {
+		app := fmt.Sprintf("PID: %d", m.ipcClient.ProcessID())
+		if m.ipcClient.ProcessID() == 0 {
+			// Attached to an app we did not start
+			app = m.ipcClient.Transport().String()
+		}
+		info := fmt.Sprintf(" [%s]", app)
+		if restarts := m.ipcClient.Restarts(); restarts > 0 {
+			info = fmt.Sprintf(" [%s, restarted %d×]", app, restarts)
+		} else if !m.lastReload.IsZero() {
+			info = fmt.Sprintf(" [%s, reloaded %s]", app, m.lastReload.Format("15:04:05"))
+		}
 		appInfo = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#10B981")).
-			Render(fmt.Sprintf(" [PID: %d]", m.ipcClient.ProcessID()))
+			Render(info)
+	} else if m.ipcClient != nil && m.ipcClient.State() == ipc.StateRestarting {
+		appInfo = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#F59E0B")).
+			Render(" [RESTARTING...]")
+	} else if m.ipcClient != nil && m.ipcClient.State() == ipc.StateCrashed {
+		appInfo = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#EF4444")).
+			Render(" [CRASHED - save a file to reload]")
 	} else if m.demoMode {
 		appInfo = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#F59E0B")).
@@ -632,8 +730,28 @@ func (m Model) renderHeader() string {
 // If client is nil, the TUI will run in demo mode
 // The IPC client will be stopped when the TUI exits
 func Run(client *ipc.Client) error {
+	return RunWithReloads(client, nil)
+}
+
+// RunWithReloads starts the TUI like Run and refreshes it whenever a reload
+// is reported on reloads
+func RunWithReloads(client *ipc.Client, reloads <-chan AppReloadedMsg) error {
+	return RunWithOptions(client, Options{Reloads: reloads})
+}
+
+// Options configures RunWithOptions
+type Options struct {
+	// Reloads reports app reloads, after which the TUI refreshes
+	Reloads <-chan AppReloadedMsg
+	// ProjectDir is where chat sessions are saved; empty disables saving
+	ProjectDir string
+}
+
+// RunWithOptions starts the TUI like Run with the given options
+func RunWithOptions(client *ipc.Client, options Options) error {
+	previousMode := log.Default.Mode()
 	log.Default.SetMode(log.LogToEntries)
-	defer log.Default.SetMode(log.LogToConsole)
+	defer log.Default.SetMode(previousMode)
 	log.Info("Starting TUI")
 	if client != nil {
 		log.IPC("IPC client provided, command: %v", client.Command())
@@ -641,13 +759,26 @@ func Run(client *ipc.Client) error {
 		log.Info("No IPC client, will run in demo mode")
 	}
 
+	model := NewModel(client)
+	model.reloads = options.Reloads
+	if options.ProjectDir != "" {
+		for _, screen := range model.screens {
+			if chat, ok := screen.(*ChatModel); ok {
+				chat.EnableSessions(options.ProjectDir)
+			}
+		}
+	}
+
 	p := tea.NewProgram(
-		NewModel(client),
+		model,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
 
+	// Let terminals that support it report shift+enter for the editor
+	fmt.Print(enableModifyOtherKeys)
 	_, err := p.Run()
+	fmt.Print(resetModifyOtherKeys)
 
 	// Stop the IPC client when TUI exits
 	if client != nil {
@@ -0,0 +1,201 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shuttl-ai/cli/log"
+	"github.com/shuttl-ai/cli/sessions"
+)
+
+// sessionBrowser lists the sessions saved in the project
+type sessionBrowser struct {
+	sessions []*sessions.Session
+	cursor   int
+	err      string
+}
+
+// EnableSessions saves chat sessions in the project in projectDir and lets
+// them be resumed and exported from the session browser
+func (m *ChatModel) EnableSessions(projectDir string) {
+	m.projectDir = projectDir
+	m.store = sessions.NewStore(projectDir)
+}
+
+// saveSession writes a session to the store. Empty sessions are not saved.
+func (m *ChatModel) saveSession(session *ChatSession) {
+	if m.store == nil || len(session.Messages) == 0 {
+		return
+	}
+	if err := m.store.Save(session.Record()); err != nil {
+		log.Error("Failed to save session %s: %v", session.ID, err)
+		m.notice = fmt.Sprintf("⚠️  %v", err)
+	}
+}
+
+// openSessionBrowser loads the saved sessions and shows them
+func (m *ChatModel) openSessionBrowser() {
+	m.showSessions = true
+	m.browser = sessionBrowser{}
+	list, err := m.store.List()
+	if err != nil {
+		m.browser.err = err.Error()
+		return
+	}
+	m.browser.sessions = list
+}
+
+// resumeSession opens a saved session so the conversation continues in its
+// thread
+func (m *ChatModel) resumeSession(record *sessions.Session) error {
+	agent, ok := m.agents[record.Agent]
+	if !ok {
+		agent = Agent{ID: record.Agent, Name: record.Agent}
+	}
+	if current := m.sessions[agent.ID]; current != nil && current.IsWaiting {
+		return fmt.Errorf("%s is still answering; try again when it is done", agent.Name)
+	}
+	m.openSession(chatSessionFromRecord(record, agent))
+	m.showSessions = false
+	m.notice = fmt.Sprintf("Resumed %q", sessionTitle(record))
+	return nil
+}
+
+// startNewThread starts over with the active agent in a new thread
+func (m *ChatModel) startNewThread() error {
+	session := m.GetActiveSession()
+	if session.IsWaiting {
+		return fmt.Errorf("%s is still answering; try again when it is done", session.Agent.Name)
+	}
+	m.openSession(newChatSession(session.Agent))
+	m.showSessions = false
+	m.notice = "Started a new thread with " + session.Agent.Name
+	return nil
+}
+
+// exportSession writes a session's transcript to the project's export
+// directory
+func (m *ChatModel) exportSession(record *sessions.Session, format string) {
+	path := sessions.ExportPath(m.projectDir, record, format)
+	if err := sessions.ExportFile(record, path); err != nil {
+		m.browser.err = err.Error()
+		return
+	}
+	m.notice = "📄 Exported transcript to " + path
+	m.showSessions = false
+}
+
+// updateSessionBrowser handles keys while the session browser is shown
+func (m *ChatModel) updateSessionBrowser(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	var selected *sessions.Session
+	if m.browser.cursor < len(m.browser.sessions) {
+		selected = m.browser.sessions[m.browser.cursor]
+	}
+
+	switch key.String() {
+	case "esc", "ctrl+o":
+		m.showSessions = false
+	case "up", "k":
+		if m.browser.cursor > 0 {
+			m.browser.cursor--
+		}
+	case "down", "j":
+		if m.browser.cursor < len(m.browser.sessions)-1 {
+			m.browser.cursor++
+		}
+	case "enter":
+		if selected != nil {
+			if err := m.resumeSession(selected); err != nil {
+				m.browser.err = err.Error()
+			}
+		}
+	case "n":
+		if m.GetActiveSession() != nil {
+			if err := m.startNewThread(); err != nil {
+				m.browser.err = err.Error()
+			}
+		}
+	case "m":
+		if selected != nil {
+			m.exportSession(selected, sessions.FormatMarkdown)
+		}
+	case "J":
+		if selected != nil {
+			m.exportSession(selected, sessions.FormatJSON)
+		}
+	case "d":
+		if selected != nil {
+			if err := m.store.Delete(selected.ID); err != nil {
+				m.browser.err = err.Error()
+				return m, nil
+			}
+			m.browser.sessions = append(m.browser.sessions[:m.browser.cursor], m.browser.sessions[m.browser.cursor+1:]...)
+			if m.browser.cursor > 0 && m.browser.cursor >= len(m.browser.sessions) {
+				m.browser.cursor--
+			}
+		}
+	}
+	return m, nil
+}
+
+// renderSessionBrowser renders the list of saved sessions
+func (m *ChatModel) renderSessionBrowser() string {
+	var b strings.Builder
+
+	b.WriteString(TitleStyle.Render("🗂  Chat Sessions"))
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("Saved in " + m.store.Dir()))
+	b.WriteString("\n\n")
+
+	if len(m.browser.sessions) == 0 {
+		b.WriteString(HelpStyle.Render("No saved sessions yet."))
+		b.WriteString("\n")
+	}
+
+	visible := m.height - 12
+	if visible < 5 {
+		visible = 5
+	}
+	start := 0
+	if m.browser.cursor >= visible {
+		start = m.browser.cursor - visible + 1
+	}
+	for i := start; i < len(m.browser.sessions) && i < start+visible; i++ {
+		session := m.browser.sessions[i]
+		line := fmt.Sprintf("%s  %-16s %s  (%d messages)",
+			session.UpdatedAt.Format("2006-01-02 15:04"),
+			session.Agent,
+			sessionTitle(session),
+			len(session.Messages),
+		)
+		if i == m.browser.cursor {
+			b.WriteString(SelectedItemStyle.Render("▸ " + line))
+		} else {
+			b.WriteString(NormalItemStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	if m.browser.err != "" {
+		b.WriteString("\n")
+		b.WriteString(LogErrorStyle.Render("⚠️  " + m.browser.err))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("enter resume • n new thread • m export markdown • J export json • d delete • esc close"))
+	return b.String()
+}
+
+func sessionTitle(session *sessions.Session) string {
+	if session.Title == "" {
+		return "(untitled)"
+	}
+	return session.Title
+}
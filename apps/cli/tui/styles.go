@@ -133,6 +133,24 @@ var (
 	AttachedFileStyle = lipgloss.NewStyle().
 				Foreground(secondaryColor).
 				Italic(true)
+
+	// Tool call styles
+	ToolCallStyle = lipgloss.NewStyle().
+			Foreground(mutedColor)
+
+	ToolCallSelectedStyle = lipgloss.NewStyle().
+				Foreground(accentColor).
+				Bold(true)
+
+	ToolCallFailedStyle = lipgloss.NewStyle().
+				Foreground(errorColor).
+				Bold(true)
+
+	ToolCallBodyStyle = lipgloss.NewStyle().
+				Border(lipgloss.NormalBorder(), false, false, false, true).
+				BorderForeground(mutedColor).
+				PaddingLeft(1).
+				MarginLeft(2)
 )
 
 func StatusStyle(status string) lipgloss.Style {
@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shuttl-ai/cli/sessions"
+)
+
+// maxToolOutputLines is how much of a tool call's output an expanded block
+// shows; the full output is in the exported transcript
+const maxToolOutputLines = 20
+
+// toolCallIndexes returns the indexes of the session's tool call messages
+func (c *ChatSession) toolCallIndexes() []int {
+	var indexes []int
+	for i, msg := range c.Messages {
+		if msg.ToolCall != nil {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// selectedToolCall returns the index of the selected tool call message, which
+// is the latest one unless another was picked, or -1 if there is none
+func (c *ChatSession) selectedToolCall() int {
+	if c.selectedTool >= 0 && c.selectedTool < len(c.Messages) && c.Messages[c.selectedTool].ToolCall != nil {
+		return c.selectedTool
+	}
+	indexes := c.toolCallIndexes()
+	if len(indexes) == 0 {
+		return -1
+	}
+	return indexes[len(indexes)-1]
+}
+
+// SelectToolCall moves the selection step tool calls back or forward.
+// Moving past the latest call follows new calls again.
+func (c *ChatSession) SelectToolCall(step int) {
+	indexes := c.toolCallIndexes()
+	if len(indexes) == 0 {
+		return
+	}
+	current := len(indexes) - 1
+	selected := c.selectedToolCall()
+	for i, index := range indexes {
+		if index == selected {
+			current = i
+		}
+	}
+	next := current + step
+	switch {
+	case next < 0:
+		next = 0
+	case next >= len(indexes):
+		c.selectedTool = -1
+		return
+	}
+	c.selectedTool = indexes[next]
+}
+
+// ToggleToolCall expands or collapses the selected tool call
+func (c *ChatSession) ToggleToolCall() {
+	if i := c.selectedToolCall(); i >= 0 {
+		call := c.Messages[i].ToolCall
+		call.Expanded = !call.Expanded
+	}
+}
+
+// ToggleAllToolCalls collapses every tool call if any is expanded, and
+// expands them all otherwise
+func (c *ChatSession) ToggleAllToolCalls() {
+	expand := true
+	for _, i := range c.toolCallIndexes() {
+		if c.Messages[i].ToolCall.Expanded {
+			expand = false
+			break
+		}
+	}
+	for _, i := range c.toolCallIndexes() {
+		c.Messages[i].ToolCall.Expanded = expand
+	}
+}
+
+// renderToolCall renders a tool call as a one-line header, followed by its
+// call ID, arguments and output when expanded
+func renderToolCall(call *ToolCall, selected bool, width int) string {
+	marker := "▸"
+	if call.Expanded {
+		marker = "▾"
+	}
+	status := "running…"
+	switch {
+	case call.Failed:
+		status = "✗ failed"
+	case call.Completed:
+		status = "✓"
+	}
+	header := fmt.Sprintf("%s 🔧 %s %s", marker, call.Name, status)
+	if call.Completed && call.Duration > 0 {
+		header += " · " + formatElapsed(call.Duration)
+	}
+
+	style := ToolCallStyle
+	switch {
+	case call.Failed:
+		style = ToolCallFailedStyle
+	case selected:
+		style = ToolCallSelectedStyle
+	}
+	if selected {
+		header += "  ◂"
+	}
+	if !call.Expanded {
+		return style.Render(header)
+	}
+
+	var body strings.Builder
+	if call.CallID != "" {
+		body.WriteString("call ID: " + call.CallID + "\n")
+	}
+	body.WriteString("arguments:\n")
+	body.WriteString(indent(sessions.PrettyJSON(call.Arguments)))
+	if call.Completed {
+		body.WriteString("\noutput:\n")
+		output := "(no output)"
+		if call.Output != "" {
+			output = truncateLines(sessions.PrettyJSON([]byte(call.Output)), maxToolOutputLines)
+		}
+		if call.Failed {
+			output = LogErrorStyle.Render(output)
+		}
+		body.WriteString(indent(output))
+	}
+
+	bodyStyle := ToolCallBodyStyle
+	if call.Failed {
+		bodyStyle = bodyStyle.BorderForeground(errorColor)
+	}
+	if width > 8 {
+		bodyStyle = bodyStyle.Width(width - 4)
+	}
+	return style.Render(header) + "\n" + bodyStyle.Render(body.String())
+}
+
+// formatElapsed rounds a duration for display, e.g. 850ms or 1.2s
+func formatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func indent(text string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n  ")
+}
+
+// truncateLines keeps the first max lines of text, noting how many were cut
+func truncateLines(text string, max int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= max {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[:max], "\n") + fmt.Sprintf("\n… %d more lines", len(lines)-max)
+}
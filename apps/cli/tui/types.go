@@ -1,10 +1,14 @@
 package tui
 
 import (
+	"encoding/json"
 	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shuttl-ai/cli/ipc"
+	"github.com/shuttl-ai/cli/sessions"
 )
 
 // Screen represents the different screens in the TUI
@@ -34,6 +38,12 @@ type Screen interface {
 	SetScreenIndex(index int)
 }
 
+// modalScreen is implemented by screens that can show a dialog. While it is
+// open, esc and tab go to the screen instead of quitting or switching.
+type modalScreen interface {
+	InModal() bool
+}
+
 const (
 	ScreenAgentPicker ScreenNumber = iota
 	ScreenChat
@@ -51,7 +61,7 @@ type Agent struct {
 
 // ChatMessage represents a message in a chat session
 type ChatMessage struct {
-	Role    string // "user" or "assistant"
+	Role    string // "user", "agent" or "tool"
 	Content string
 	AgentID string
 	Deltas  []struct {
@@ -59,6 +69,13 @@ type ChatMessage struct {
 		SequenceNumber int
 	}
 	IsCompleted bool
+	Time        time.Time
+	Attachments []string  // Names of the files sent with a prompt
+	ToolCall    *ToolCall // Set for "tool" messages
+
+	// The reply rendered as Markdown, for the width it was rendered at
+	rendered      string
+	renderedWidth int
 }
 
 func (c ChatMessage) String() string {
@@ -84,8 +101,14 @@ func (c *ChatMessage) Commit() {
 
 type ToolCall struct {
 	Name      string
-	Arguments map[string]any
+	Arguments json.RawMessage
 	CallID    string
+	Output    string
+	Completed bool
+	Failed    bool
+	StartedAt time.Time
+	Duration  time.Duration
+	Expanded  bool // Whether the call is shown in full in the chat
 }
 
 // ChatSession represents an active chat session with an agent
@@ -94,6 +117,141 @@ type ChatSession struct {
 	Messages            []*ChatMessage
 	currentMessageIndex int
 	IsWaiting           bool // True when waiting for AI response after user sends message
+	selectedTool        int  // Index of the selected tool call message; -1 for the latest
+
+	// InputRequest is the question the agent waits on the user to answer
+	InputRequest *ipc.InputRequest
+	inputChoice  int
+
+	// ThreadID is the app's thread, sent with each prompt once known
+	ThreadID string
+	// ID and CreatedAt identify the session in the session store
+	ID        string
+	CreatedAt time.Time
+}
+
+// newChatSession starts an empty session with an agent
+func newChatSession(agent Agent) *ChatSession {
+	record := sessions.New(agent.ID)
+	return &ChatSession{
+		Agent:               agent,
+		Messages:            []*ChatMessage{},
+		currentMessageIndex: -1,
+		selectedTool:        -1,
+		ID:                  record.ID,
+		CreatedAt:           record.CreatedAt,
+	}
+}
+
+// chatSessionFromRecord restores a stored session
+func chatSessionFromRecord(record *sessions.Session, agent Agent) *ChatSession {
+	session := &ChatSession{
+		Agent:               agent,
+		Messages:            make([]*ChatMessage, 0, len(record.Messages)),
+		currentMessageIndex: -1,
+		selectedTool:        -1,
+		ThreadID:            record.ThreadID,
+		ID:                  record.ID,
+		CreatedAt:           record.CreatedAt,
+	}
+	for _, message := range record.Messages {
+		restored := &ChatMessage{
+			Role:        message.Role,
+			Content:     message.Content,
+			AgentID:     agent.ID,
+			IsCompleted: true,
+			Time:        message.Time,
+			Attachments: message.Attachments,
+		}
+		if call := message.ToolCall; call != nil {
+			restored.ToolCall = &ToolCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+				CallID:    call.CallID,
+				Output:    call.Output,
+				Completed: call.Completed,
+				Failed:    call.Failed,
+				StartedAt: message.Time,
+				Duration:  time.Duration(call.DurationMs) * time.Millisecond,
+			}
+		}
+		session.Messages = append(session.Messages, restored)
+	}
+	return session
+}
+
+// Record returns the session in its stored form. Messages still streaming
+// are left out.
+func (c *ChatSession) Record() *sessions.Session {
+	record := &sessions.Session{
+		ID:        c.ID,
+		Agent:     c.Agent.ID,
+		ThreadID:  c.ThreadID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.CreatedAt,
+		Messages:  []sessions.Message{},
+	}
+	for _, msg := range c.Messages {
+		if !msg.IsCompleted && msg.ToolCall == nil {
+			continue
+		}
+		message := sessions.Message{
+			Role:        msg.Role,
+			Content:     msg.Content,
+			Attachments: msg.Attachments,
+			Time:        msg.Time,
+		}
+		if call := msg.ToolCall; call != nil {
+			message.ToolCall = &sessions.ToolCall{
+				CallID:     call.CallID,
+				Name:       call.Name,
+				Arguments:  call.Arguments,
+				Output:     call.Output,
+				Completed:  call.Completed,
+				Failed:     call.Failed,
+				DurationMs: call.Duration.Milliseconds(),
+			}
+		}
+		record.Add(message)
+	}
+	return record
+}
+
+// AddToolCall records a tool call made by the agent
+func (c *ChatSession) AddToolCall(call *ipc.ToolCallResult) {
+	now := time.Now()
+	c.Messages = append(c.Messages, &ChatMessage{
+		Role:    sessions.RoleTool,
+		AgentID: c.Agent.ID,
+		Time:    now,
+		ToolCall: &ToolCall{
+			Name:      call.ToolCall.Name,
+			Arguments: call.ToolCall.Arguments,
+			CallID:    call.ToolCall.CallID,
+			StartedAt: now,
+		},
+	})
+}
+
+// CompleteToolCalls records the output of finished tool calls and how long
+// they took. Failed calls are expanded so their output is seen.
+func (c *ChatSession) CompleteToolCalls(completed *ipc.ToolCallCompletedResult) {
+	now := time.Now()
+	for _, result := range *completed {
+		for i := len(c.Messages) - 1; i >= 0; i-- {
+			call := c.Messages[i].ToolCall
+			if call != nil && call.CallID == result.CallID {
+				call.Output = result.Output
+				call.Completed = true
+				call.Failed = result.Failed()
+				call.Duration = now.Sub(call.StartedAt)
+				if call.Failed {
+					call.Expanded = true
+				}
+				break
+			}
+		}
+	}
 }
 
 func (c *ChatSession) UpdateMessage(delta string, index int) {
@@ -117,6 +275,7 @@ func (c *ChatSession) StartNewMessageDelta() {
 		Content:     "",
 		AgentID:     c.Agent.ID,
 		IsCompleted: false,
+		Time:        time.Now(),
 		Deltas: []struct {
 			Delta          string
 			SequenceNumber int
@@ -125,7 +284,20 @@ func (c *ChatSession) StartNewMessageDelta() {
 	c.currentMessageIndex = len(c.Messages) - 1
 }
 
-func (c *ChatSession) CommitMessage() {
+// CommitMessage completes the message being streamed. If no deltas were
+// streamed, the final text becomes a new message.
+func (c *ChatSession) CommitMessage(text string) {
+	c.IsWaiting = false
+	if c.currentMessageIndex == -1 {
+		c.Messages = append(c.Messages, &ChatMessage{
+			Role:        "agent",
+			Content:     text,
+			AgentID:     c.Agent.ID,
+			IsCompleted: true,
+			Time:        time.Now(),
+		})
+		return
+	}
 	msg := c.Messages[c.currentMessageIndex]
 	msg.Commit()
 	c.currentMessageIndex = -1
@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultInclude is used when shuttl.json has no include patterns
+var DefaultInclude = []string{"**"}
+
+// DefaultExclude is always excluded in addition to the configured patterns
+var DefaultExclude = []string{
+	".git/**",
+	"node_modules/**",
+	"**/__pycache__/**",
+	"shuttl-manifest.json",
+	".shuttl/**",
+}
+
+// Matcher decides which paths, relative to the watched root, trigger a reload.
+// Patterns use forward slashes and filepath.Match syntax per path segment,
+// plus "**" for any number of segments.
+type Matcher struct {
+	Include []string
+	Exclude []string
+}
+
+// NewMatcher validates the patterns and returns a Matcher. An empty include
+// list falls back to DefaultInclude; DefaultExclude is always applied.
+func NewMatcher(include, exclude []string) (Matcher, error) {
+	if len(include) == 0 {
+		include = DefaultInclude
+	}
+	exclude = append(append([]string{}, DefaultExclude...), exclude...)
+
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Matcher{}, fmt.Errorf("invalid watch pattern %q: %w", pattern, err)
+		}
+	}
+	return Matcher{Include: include, Exclude: exclude}, nil
+}
+
+// FilePattern returns a pattern matching only the given file, for excluding
+// files the CLI itself writes inside the watched root. It reports false if the
+// file is outside root.
+func FilePattern(root, file string) (string, bool) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	// Escape the characters path.Match would otherwise treat as a pattern
+	var pattern strings.Builder
+	for _, r := range filepath.ToSlash(rel) {
+		if strings.ContainsRune(`*?[\`, r) {
+			pattern.WriteByte('\\')
+		}
+		pattern.WriteRune(r)
+	}
+	return pattern.String(), true
+}
+
+// Match reports whether a file path triggers a reload
+func (m Matcher) Match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	return matchAny(m.Include, rel) && !m.Excluded(rel)
+}
+
+// Excluded reports whether a path, or a directory and everything below it,
+// is excluded
+func (m Matcher) Excluded(rel string) bool {
+	return matchAny(m.Exclude, filepath.ToSlash(rel))
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob reports whether a slash-separated path matches a pattern where
+// "**" matches zero or more path segments
+func MatchGlob(pattern, rel string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of segments for "**"
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
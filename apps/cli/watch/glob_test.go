@@ -0,0 +1,122 @@
+package watch
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"**", "main.ts", true},
+		{"**", "src/agents/bot.ts", true},
+		{"*.ts", "main.ts", true},
+		{"*.ts", "src/main.ts", false},
+		{"src/*.ts", "src/main.ts", true},
+		{"src/*.ts", "src/agents/bot.ts", false},
+		{"src/**/*.ts", "src/main.ts", true},
+		{"src/**/*.ts", "src/agents/bot.ts", true},
+		{"src/**/*.ts", "lib/main.ts", false},
+		{"**/*.py", "app/tools/search.py", true},
+		{"node_modules/**", "node_modules", true},
+		{"node_modules/**", "node_modules/pkg/index.js", true},
+		{"**/__pycache__/**", "app/__pycache__/x.pyc", true},
+		{"dist/**/*.map", "dist/main.js", false},
+	}
+
+	for _, tc := range testCases {
+		if got := MatchGlob(tc.pattern, tc.path); got != tc.expected {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tc.pattern, tc.path, got, tc.expected)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		matcher, err := NewMatcher(nil, nil)
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+
+		if !matcher.Match("src/main.ts") {
+			t.Error("Expected source files to match by default")
+		}
+		if matcher.Match("node_modules/pkg/index.js") {
+			t.Error("Expected node_modules to be excluded by default")
+		}
+		if matcher.Match(".git/HEAD") {
+			t.Error("Expected .git to be excluded by default")
+		}
+		if matcher.Match("shuttl-manifest.json") {
+			t.Error("Expected the manifest to be excluded by default")
+		}
+	})
+
+	t.Run("configured patterns", func(t *testing.T) {
+		matcher, err := NewMatcher([]string{"src/**/*.ts"}, []string{"src/**/*.test.ts"})
+		if err != nil {
+			t.Fatalf("NewMatcher failed: %v", err)
+		}
+
+		if !matcher.Match("src/agents/bot.ts") {
+			t.Error("Expected included file to match")
+		}
+		if matcher.Match("src/agents/bot.test.ts") {
+			t.Error("Expected excluded file not to match")
+		}
+		if matcher.Match("README.md") {
+			t.Error("Expected file outside include patterns not to match")
+		}
+		if !matcher.Excluded("node_modules") {
+			t.Error("Expected default excludes to still apply")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := NewMatcher([]string{"src/[.ts"}, nil); err == nil {
+			t.Error("Expected error for invalid pattern, got nil")
+		}
+	})
+}
+
+func TestFilePattern(t *testing.T) {
+	root := t.TempDir()
+	testCases := []struct {
+		name   string
+		file   string
+		ok     bool
+		match  string
+		ignore string
+	}{
+		{"file in root", filepath.Join(root, "session.jsonl"), true, "session.jsonl", "other.jsonl"},
+		{"nested file", filepath.Join(root, "logs", "dev.log"), true, "logs/dev.log", "logs/dev.log.1"},
+		{"glob characters", filepath.Join(root, "run[1]*.log"), true, "run[1]*.log", "run1x.log"},
+		{"outside root", filepath.Join(filepath.Dir(root), "dev.log"), false, "", ""},
+		{"root itself", root, false, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pattern, ok := FilePattern(root, tc.file)
+			if ok != tc.ok {
+				t.Fatalf("FilePattern(%q) ok = %v, expected %v", tc.file, ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			matcher, err := NewMatcher(nil, []string{pattern})
+			if err != nil {
+				t.Fatalf("NewMatcher(%q) failed: %v", pattern, err)
+			}
+			if matcher.Match(tc.match) {
+				t.Errorf("Expected %q to be excluded by %q", tc.match, pattern)
+			}
+			if !matcher.Match(tc.ignore) {
+				t.Errorf("Expected %q not to be excluded by %q", tc.ignore, pattern)
+			}
+		})
+	}
+}
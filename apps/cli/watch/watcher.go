@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/shuttl-ai/cli/log"
+)
+
+// DefaultDebounce is how long the watcher waits for changes to settle before
+// reporting them, so saving many files at once causes a single reload
+const DefaultDebounce = 300 * time.Millisecond
+
+// Watcher watches a directory tree and reports batches of changed files that
+// match its Matcher
+type Watcher struct {
+	root     string
+	matcher  Matcher
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+	changes  chan []string
+	done     chan struct{}
+}
+
+// New starts watching root and every directory below it that is not excluded
+func New(root string, matcher Matcher, debounce time.Duration) (*Watcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		root:     root,
+		matcher:  matcher,
+		debounce: debounce,
+		fs:       fsw,
+		changes:  make(chan []string, 1),
+		done:     make(chan struct{}),
+	}
+	if err := w.addTree(root); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Changes returns the channel of changed files, relative to the root. It is
+// closed when the watcher is closed.
+func (w *Watcher) Changes() <-chan []string {
+	return w.changes
+}
+
+// Root returns the absolute path of the watched directory
+func (w *Watcher) Root() string {
+	return w.root
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fs.Close()
+}
+
+// addTree watches dir and its subdirectories, skipping excluded ones
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories can disappear while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if rel := w.rel(path); rel != "." && w.matcher.Excluded(rel) {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+}
+
+func (w *Watcher) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+func (w *Watcher) run() {
+	defer close(w.changes)
+
+	pending := make(map[string]struct{})
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			// Watch directories created after startup
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						log.Warn("Failed to watch %s: %v", event.Name, err)
+					}
+					continue
+				}
+			}
+
+			rel := w.rel(event.Name)
+			if !w.matcher.Match(rel) {
+				continue
+			}
+			log.Debug("File changed: %s (%s)", rel, event.Op)
+			pending[rel] = struct{}{}
+			timer.Reset(w.debounce)
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("File watcher error: %v", err)
+
+		case <-timer.C:
+			files := make([]string, 0, len(pending))
+			for file := range pending {
+				files = append(files, file)
+			}
+			sort.Strings(files)
+
+			// If the last batch is still being handled, keep collecting and
+			// report everything together once it is done
+			select {
+			case w.changes <- files:
+				pending = make(map[string]struct{})
+			default:
+				timer.Reset(w.debounce)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherReportsMatchingChanges(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "node_modules"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	matcher, err := NewMatcher([]string{"src/**/*.ts"}, nil)
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+	watcher, err := New(root, matcher, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer watcher.Close()
+
+	write := func(rel string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, rel), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+
+	// Ignored files, then two matching files that should arrive as one batch
+	write("README.md")
+	write("node_modules/index.ts")
+	write("src/a.ts")
+	write("src/b.ts")
+
+	select {
+	case files := <-watcher.Changes():
+		expected := []string{filepath.Join("src", "a.ts"), filepath.Join("src", "b.ts")}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Expected %v, got %v", expected, files)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for changes")
+	}
+
+	// Directories created after startup are watched too
+	if err := os.MkdirAll(filepath.Join(root, "src", "agents"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	write("src/agents/bot.ts")
+
+	select {
+	case files := <-watcher.Changes():
+		expected := []string{filepath.Join("src", "agents", "bot.ts")}
+		if !reflect.DeepEqual(files, expected) {
+			t.Errorf("Expected %v, got %v", expected, files)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for changes in new directory")
+	}
+}
@@ -4,8 +4,9 @@ import { ITrigger } from "./trigger/ITrigger";
 import { IOutcome } from "./outcomes/IOutcomes";
 import { IModelStreamer, ModelResponseData } from "./models/types";
 import { IModel } from "./models/types";
-import { ITool } from "./tools/tool";
+import { InputReply, InputRequestProps, ITool, IToolContext } from "./tools/tool";
 import { stdout } from "process";
+import { randomUUID } from "crypto";
 import { ApiTrigger } from "./trigger/ApiTrigger";
 import { StreamingOutcome } from "./outcomes/StreamingOutcome";
 
@@ -29,6 +30,7 @@ export class AgentStreamer implements IModelStreamer {
     private jobs: number = 0;
     private calls: Record<string, Promise<{ callId: string, result: unknown }>> = {};
     private readonly writer: IAgentStreamerWriter
+    private threadId?: string;
 
     public constructor(
         private readonly agent: Agent,
@@ -72,8 +74,31 @@ export class AgentStreamer implements IModelStreamer {
         }
     }
 
+    /**
+     * Ask the user a question with a response.requested event and wait for
+     * the answer the CLI sends with the respond method
+     */
+    public requestInput(request: InputRequestProps): Promise<InputReply> {
+        const threadId = this.threadId;
+        if (!threadId) {
+            return Promise.reject(new Error("Cannot ask for input before the thread has started"));
+        }
+        const requestId = randomUUID();
+        const reply = this.agent.awaitInput(threadId, requestId);
+        this.write("response.requested", {
+            typeName: "response.requested",
+            threadId,
+            requestId,
+            prompt: request.prompt,
+            kind: request.kind ?? "input",
+            options: request.options,
+        }, true);
+        return reply;
+    }
+
     public async recieve(model: IModel, content: ModelResponse): Promise<void> {
         this.jobs++;
+        this.threadId = model.threadId ?? content.threadId ?? this.threadId;
         try {
             if (content.data === undefined) {
                 return;
@@ -129,15 +154,21 @@ export class AgentStreamer implements IModelStreamer {
                     this.write("tool_call", data, true);
                     if (data.toolCall) {
                         const tool = this.agent.getTool(data.toolCall.name);
+                        const context: IToolContext = {
+                            threadId: this.threadId,
+                            requestInput: (request) => this.requestInput(request),
+                        };
                         const toolCallExecutor = async () => {
-                            const result = await tool.execute(data.toolCall!.arguments);
+                            const result = await tool.execute(data.toolCall!.arguments, context);
                             return {
                                 callId: data.toolCall!.callId,
                                 result: result,
                             };
                         }
-                        this.resultPromise.push(toolCallExecutor());
-                        this.calls[data.toolCall!.callId] = toolCallExecutor();
+                        // Run the tool once; it may be waiting on the user
+                        const call = toolCallExecutor();
+                        this.resultPromise.push(call);
+                        this.calls[data.toolCall!.callId] = call;
                     }
                     break;
                 case "output_text": {
@@ -181,6 +212,7 @@ export class Agent {
     public readonly outcomes: IOutcome[];
     private modelInstances: Record<string, IModel> = {};
     private readonly toolRecords: Record<string, ITool> = {};
+    private readonly pendingInputs: Record<string, { requestId: string, resolve: (reply: InputReply) => void }> = {};
 
     public constructor(props: AgentProps) {
         this.name = props.name;
@@ -269,6 +301,30 @@ export class Agent {
         }
     }
 
+    /**
+     * Wait for the answer to an input request in a thread. A thread waits on
+     * one request at a time; a new request replaces the old one.
+     */
+    public awaitInput(threadId: string, requestId: string): Promise<InputReply> {
+        return new Promise((resolve) => {
+            this.pendingInputs[threadId] = { requestId, resolve };
+        });
+    }
+
+    /**
+     * Pass the user's answer to the input request a thread waits on. Returns
+     * false if the thread waits on nothing, or on a different request.
+     */
+    public respond(threadId: string, reply: InputReply): boolean {
+        const pending = this.pendingInputs[threadId];
+        if (!pending || (reply.requestId && reply.requestId !== pending.requestId)) {
+            return false;
+        }
+        delete this.pendingInputs[threadId];
+        pending.resolve({ ...reply, requestId: pending.requestId });
+        return true;
+    }
+
     public getTool(name: string): ITool {
         const tool = this.toolRecords[name];
         if (!tool) {
@@ -1,11 +1,23 @@
 import { App } from "../app";
 import { IServer } from "../Server";
+import { readFile } from "fs/promises";
 import { stdin, stdout } from "process";
 import { createInterface, Interface } from "readline";
 import { Agent, AgentStreamer, IAgentStreamerWriter } from "../agent";
 import { FileAttachment, InputContent, isFileAttachmentArray, IModelResponseStream, ModelResponse, ModelResponseStreamValue } from "../models/types";
 import { ITriggerInvoker, SerializedHTTPRequest } from "../trigger/ITrigger";
 
+/** Messages are written one JSON object per line unless the CLI asks for frames */
+const FRAMING_NDJSON = "ndjson";
+/** Each message is preceded by a Content-Length header, so any size can be read */
+const FRAMING_CONTENT_LENGTH = "content-length";
+
+/**
+ * Optional protocol features the server reports in answer to a ping.
+ * attachment_paths: attachments may be sent with a path and no content.
+ */
+const CAPABILITIES = ["attachment_paths"];
+
 /**
  * Request message format from the host CLI
  */
@@ -28,6 +40,8 @@ export interface IPCResponseError {
 export interface IPCResponse {
     /** Correlates to the request ID */
     id: string;
+    /** The type of a streamed event */
+    type?: string;
     /** Whether the request was successful */
     success: boolean;
     /** The result data (on success) */
@@ -36,10 +50,35 @@ export interface IPCResponse {
     errorObj?: IPCResponseError;
 }
 
+/**
+ * Whether an attachment was sent with a path instead of its content
+ */
+const sentByPath = (attachment: any): boolean => {
+    return typeof attachment === "object"
+        && attachment !== null
+        && typeof attachment.path === "string"
+        && attachment.path !== ""
+        && attachment.content === undefined;
+};
+
+/**
+ * Fill in the base64 content of an attachment sent by path
+ */
+const readAttachment = async (attachment: any): Promise<any> => {
+    if (!sentByPath(attachment)) {
+        return attachment;
+    }
+    const content = await readFile(attachment.path);
+    return { ...attachment, content: content.toString("base64") };
+};
+
 /**
  * A server that communicates via STDIN/STDOUT using JSON messages.
  * Uses newline-delimited JSON (NDJSON) protocol where each message
- * is a single line of JSON followed by a newline character.
+ * is a single line of JSON followed by a newline character. When the
+ * CLI's ping offers content-length framing, responses are instead
+ * preceded by a "Content-Length: <bytes>" header and a blank line, so
+ * messages of any size can be read.
  * 
  * Host CLI writes requests to the process's STDIN.
  * This server writes responses to STDOUT.
@@ -49,6 +88,7 @@ export class StdInServer implements IServer {
     private app?: App;
     private running: boolean = false;
     private rl?: Interface;
+    private framing: string = FRAMING_NDJSON;
 
     public constructor() {}
 
@@ -164,13 +204,24 @@ export class StdInServer implements IServer {
     private handleRequest(request: IPCRequest): void {
         try {
             switch (request.method) {
-                case "ping":
+                case "ping": {
+                    const framing = request.body?.framing;
+                    if (Array.isArray(framing) && framing.includes(FRAMING_CONTENT_LENGTH)) {
+                        this.framing = FRAMING_CONTENT_LENGTH;
+                    }
                     this.sendResponse({
                         id: request.id,
                         success: true,
-                        result: { pong: true, timestamp: Date.now(), protocol_version: "1.0" },
+                        result: {
+                            pong: true,
+                            timestamp: Date.now(),
+                            protocol_version: "1.0",
+                            framing: this.framing,
+                            capabilities: CAPABILITIES,
+                        },
                     });
                     break;
+                }
 
                 case "getAppInfo":
                     this.sendResponse({
@@ -221,6 +272,7 @@ export class StdInServer implements IServer {
                             agent.triggers.map((trigger) => ({
                                 name: trigger.name,
                                 triggerType: trigger.triggerType,
+                                args: trigger.triggerConfig,
                                 agentName: agent.name,
                             }))
                         ),
@@ -303,6 +355,10 @@ export class StdInServer implements IServer {
                     this.handleInvokeTrigger(request);
                     break;
 
+                case "respond":
+                    this.handleRespond(request);
+                    break;
+
                 default:
                     this.sendResponse({
                         id: request.id,
@@ -329,9 +385,27 @@ export class StdInServer implements IServer {
         const params = request.body ?? {};
         const agentName = params.agent as string | undefined;
         const prompt = (params.prompt as string) ?? "";
-        const threadId = params.threadId as string | undefined ?? undefined;
-        const rawAttachments = params.attachments;
-        
+        // The CLI sends thread_id; threadId is accepted for older clients
+        const threadId = (params.thread_id ?? params.threadId) as string | undefined ?? undefined;
+        let rawAttachments = params.attachments;
+
+        // Attachments sent by path are read here so large files stay out of the request
+        try {
+            if (Array.isArray(rawAttachments) && rawAttachments.some(sentByPath)) {
+                rawAttachments = await Promise.all(rawAttachments.map(readAttachment));
+            }
+        } catch (e) {
+            this.sendResponse({
+                id: request.id,
+                success: false,
+                errorObj: {
+                    code: "INVALID_PARAMS",
+                    message: `failed to read attachment: ${(e as Error).message}`,
+                },
+            });
+            return;
+        }
+
         // Parse and validate attachments
         let attachments: FileAttachment[] | undefined;
         if (rawAttachments !== undefined) {
@@ -374,7 +448,11 @@ export class StdInServer implements IServer {
             });
             return;
         }
-        const streamer = new AgentStreamer(agent, request.id);
+        // Stream events through sendResponse so they use the negotiated framing
+        const streamer = new AgentStreamer(agent, request.id, {
+            write: (value: string) => stdout.write(value),
+            writeObject: (value: IPCResponse) => this.sendResponse(value),
+        });
         try {
             const model = await agent.invoke(prompt, threadId, streamer, attachments);
             this.sendResponse({
@@ -402,6 +480,53 @@ export class StdInServer implements IServer {
         }
     }
 
+    /**
+     * Pass the user's answer on to the agent waiting for input in a thread
+     */
+    private handleRespond(request: IPCRequest): void {
+        const params = request.body ?? {};
+        const threadId = params.thread_id as string | undefined;
+        const requestId = params.request_id as string | undefined;
+
+        if (!threadId) {
+            this.sendResponse({
+                id: request.id,
+                success: false,
+                errorObj: {
+                    code: "INVALID_PARAMS",
+                    message: "respond requires 'thread_id' param",
+                },
+            });
+            return;
+        }
+
+        const reply = {
+            requestId,
+            response: params.response as string | undefined,
+            approved: params.approved as boolean | undefined,
+        };
+        const agent = this.app!.agents.find((a) => a.respond(threadId, reply));
+        if (!agent) {
+            this.sendResponse({
+                id: request.id,
+                success: false,
+                errorObj: {
+                    code: "NOT_FOUND",
+                    message: requestId
+                        ? `No agent is waiting on input request ${requestId} in thread ${threadId}`
+                        : `No agent is waiting for input in thread ${threadId}`,
+                },
+            });
+            return;
+        }
+
+        this.sendResponse({
+            id: request.id,
+            success: true,
+            result: { threadId, agentName: agent.name, status: "resumed" },
+        });
+    }
+
     /**
      * Handle tool invocation request
      */
@@ -654,7 +779,11 @@ export class StdInServer implements IServer {
      */
     private sendResponse(response: IPCResponse): void {
         const json = JSON.stringify(response);
-        stdout.write(json + "\n");
+        if (this.framing === FRAMING_CONTENT_LENGTH) {
+            stdout.write(`Content-Length: ${Buffer.byteLength(json)}\r\n\r\n${json}`);
+        } else {
+            stdout.write(json + "\n");
+        }
     }
 
 } 
@@ -6,11 +6,41 @@ export interface ToolArg {
     readonly defaultValue?: unknown;
     readonly enumValues?: string[];
 }
+/**
+ * A question a tool asks the user while the agent waits
+ */
+export interface InputRequestProps {
+    readonly prompt: string;
+    /** "approval" for a yes or no question, or "input" (the default) for free text */
+    readonly kind?: string;
+    /** Answers to pick from for an "input" question */
+    readonly options?: string[];
+}
+
+/**
+ * The user's answer to an InputRequestProps. approved is set for approvals;
+ * response holds the text typed or the option picked.
+ */
+export interface InputReply {
+    readonly requestId?: string;
+    readonly response?: string;
+    readonly approved?: boolean;
+}
+
+/**
+ * What a tool knows about the agent run that called it
+ */
+export interface IToolContext {
+    readonly threadId?: string;
+    /** Ask the user a question and wait for the answer */
+    requestInput(request: InputRequestProps): Promise<InputReply>;
+}
+
 export interface ITool {
     name: string;
     description: string;
     schema?: Schema;
-    execute(args: Record<string, unknown>): unknown;
+    execute(args: Record<string, unknown>, context?: IToolContext): unknown;
 }
 
 export class ToolArgBuilder {
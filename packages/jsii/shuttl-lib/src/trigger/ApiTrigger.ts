@@ -1,7 +1,6 @@
 import { z } from "zod";
 import { InputContent } from "../models/types";
 import { BaseTrigger, TriggerOutput } from "./ITrigger";
-import { IOutcome } from "../outcomes/IOutcomes";
 
 function assertNever(value: never): never {
     throw new Error(`Unexpected value: ${value}`);
@@ -14,6 +13,16 @@ export interface IApiAuthenticator {
 export interface ApiTriggerConfig {
     readonly cors?: string[];
     readonly authenticator?: IApiAuthenticator;
+    /**
+     * HTTP methods the trigger accepts: POST, GET, PUT, PATCH or DELETE.
+     * Defaults to POST.
+     */
+    readonly methods?: string[];
+    /**
+     * Path template the trigger is served at, such as `/orders/{id}`.
+     * Defaults to `/<agent>/<trigger>`.
+     */
+    readonly route?: string;
 }
 
 const ApiTriggerSchema = z.object({
@@ -74,9 +83,6 @@ export interface ApiTriggerArgs {
  * This API trigger is the default trigger for agents.
  */
 export class ApiTrigger extends BaseTrigger  {
-    public triggerType: string = "api";
-    public triggerConfig: Record<string, unknown> = {};
-    public outcome?: IOutcome;
     private authenticator?: IApiAuthenticator;
 
     public constructor(config?: ApiTriggerConfig) {
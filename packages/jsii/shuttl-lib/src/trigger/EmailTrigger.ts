@@ -1,5 +1,4 @@
 import { BaseTrigger, TriggerOutput } from "./ITrigger";
-import { IOutcome } from "../outcomes/IOutcomes";
 
 export interface EmailTriggerConfig {
     readonly inboxName: string;
@@ -10,9 +9,6 @@ export interface EmailTriggerConfig {
 }
 
 export class EmailTrigger extends BaseTrigger {
-    public triggerType: string = "email";
-    public outcome?: IOutcome;
-
     public constructor(config: EmailTriggerConfig) {
         super("email", config as any);
     }
@@ -1,4 +1,3 @@
-import { IOutcome } from "../outcomes/IOutcomes";
 import { BaseTrigger, TriggerOutput } from "./ITrigger";
 import { z } from "zod";
 
@@ -17,10 +16,6 @@ const FileTriggerSchema = z.object({
 });
 
 export class FileTrigger extends BaseTrigger {
-    public triggerType: string = "file";
-
-    public outcome?: IOutcome;
-
     public constructor(config: FileTriggerConfig) {
         super("file", config as any);
     }
@@ -22,6 +22,10 @@ export interface SerializedHTTPRequest {
     readonly headers: Record<string, string[]>;
     /** Query parameters as key-value pairs with array values */
     readonly query: Record<string, string[]>;
+    /** Path parameters matched by the trigger's route template */
+    readonly pathParams?: Record<string, string>;
+    /** Cookies sent with the request */
+    readonly cookies?: Record<string, string>;
     /** The request body (parsed JSON or raw) */
     readonly body?: unknown;
     /** The Content-Type header value */
@@ -34,6 +38,22 @@ export interface SerializedHTTPRequest {
     readonly proto: string;
     /** Timestamp of when the request was received */
     readonly timestamp: string;
+    /** The caller verified by the serve command's auth providers, if the endpoint requires authentication */
+    readonly principal?: RequestPrincipal;
+}
+
+/**
+ * The verified caller of a trigger endpoint
+ */
+export interface RequestPrincipal {
+    /** The name of the auth provider in shuttl.json that verified the request */
+    readonly provider: string;
+    /** The provider type: "api_key", "hmac" or "jwt" */
+    readonly type: string;
+    /** The API key name, the HMAC provider name or the JWT "sub" claim */
+    readonly subject: string;
+    /** The claims of a verified JWT */
+    readonly claims?: Record<string, unknown>;
 }
 
 /**
@@ -87,6 +107,12 @@ export interface ITrigger {
     withName(name: string): ITrigger;
 }
 
+/**
+ * Base class of the built-in triggers. Subclasses pass their type and config
+ * to the constructor and must not redeclare triggerType, triggerConfig or
+ * outcome: a field initializer in a subclass runs after this constructor and
+ * would overwrite the values it set.
+ */
 export abstract class BaseTrigger implements ITrigger {
     public name: string;
     public triggerType: string;
@@ -1,6 +1,5 @@
 import { BaseTrigger, TriggerOutput } from "./ITrigger";
 import { InputContent } from "../models/types";
-import { IOutcome } from "../outcomes/IOutcomes";
 
 interface RateTriggerConfig {
     /**
@@ -25,16 +24,15 @@ export interface IRateTriggerOnTrigger {
     onTrigger(): Promise<InputContent[]>;
 }
 
+/**
+ * Runs an agent on a schedule. Triggers made with Rate.cron report the
+ * "cron" trigger type; the fixed intervals report "rate".
+ */
 export class Rate extends BaseTrigger {
-    public triggerType: string = "rate";
-    public triggerConfig: Record<string, unknown> = {};
-
-    public outcome?: IOutcome;
-
     private onTrigger: (() => Promise<InputContent[]>) | null;
 
     private constructor(config: RateTriggerConfig) {
-        super("rate", config as any);
+        super(config.cronExpression !== undefined ? "cron" : "rate", config as any);
         this.onTrigger = config.onTrigger?.onTrigger ?? null;
     }
 
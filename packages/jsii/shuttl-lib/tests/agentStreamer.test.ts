@@ -13,7 +13,7 @@ jest.mock("process", () => ({
 
 import { Agent, AgentStreamer } from "../src/agent";
 import { IModel, IModelFactory, IModelStreamer, ModelContent, ModelResponse, ToolCallResponse } from "../src/models/types";
-import { ITool, Schema } from "../src/tools/tool";
+import { ITool, Schema, IToolContext } from "../src/tools/tool";
 import { Toolkit } from "../src/tools/toolkit";
 
 // Mock tool implementation for testing
@@ -266,6 +266,82 @@ describe("AgentStreamer", () => {
         });
     });
 
+    describe("requestInput()", () => {
+        let agent: Agent;
+        let streamer: AgentStreamer;
+        let mockModel: MockModel;
+
+        beforeEach(() => {
+            mockModel = new MockModel();
+            agent = new Agent({
+                name: "TestAgent",
+                toolkits: [],
+                systemPrompt: "Test prompt",
+                model: new MockModelFactory(mockModel),
+            });
+            streamer = new AgentStreamer(agent, "input-control-id");
+        });
+
+        it("should reject before the thread has started", async () => {
+            await expect(streamer.requestInput({ prompt: "Continue?" })).rejects.toThrow("thread");
+        });
+
+        it("should ask with response.requested and resolve with the reply", async () => {
+            await streamer.recieve(mockModel, {
+                eventName: "response.requested",
+                data: { typeName: "response.requested", requested: {} },
+            });
+
+            const reply = streamer.requestInput({ prompt: "Refund $30?", kind: "approval" });
+
+            const message = getLastMessage();
+            expect(message.id).toBe("input-control-id");
+            expect(message.type).toBe("response.requested");
+            expect(message.result).toMatchObject({
+                threadId: "mock-thread-id",
+                prompt: "Refund $30?",
+                kind: "approval",
+            });
+            const requestId = message.result.requestId;
+            expect(requestId).toBeTruthy();
+
+            expect(agent.respond("mock-thread-id", { requestId: "stale" })).toBe(false);
+            expect(agent.respond("mock-thread-id", { requestId, approved: true })).toBe(true);
+            await expect(reply).resolves.toEqual({ requestId, approved: true });
+            expect(agent.respond("mock-thread-id", { approved: true })).toBe(false);
+        });
+
+        it("should let a tool ask the user and run it once", async () => {
+            const execute = jest.fn(async (_args: Record<string, unknown>, context?: IToolContext) => {
+                const reply = await context!.requestInput({ prompt: "Which account?", options: ["personal", "business"] });
+                return { account: reply.response };
+            });
+            agent = new Agent({
+                name: "TestAgent",
+                toolkits: [],
+                tools: [{ name: "pick_account", description: "Pick an account", execute }],
+                systemPrompt: "Test prompt",
+                model: new MockModelFactory(mockModel),
+            });
+            streamer = new AgentStreamer(agent, "input-control-id");
+
+            await streamer.recieve(mockModel, {
+                eventName: "response.function_call",
+                data: {
+                    typeName: "tool_call",
+                    toolCall: { outputType: "tool_call", name: "pick_account", arguments: {}, callId: "call-1" },
+                },
+            });
+            await new Promise((resolve) => setImmediate(resolve));
+
+            const message = getLastMessage();
+            expect(message.type).toBe("response.requested");
+            expect(message.result.options).toEqual(["personal", "business"]);
+            expect(agent.respond("mock-thread-id", { response: "business" })).toBe(true);
+            expect(execute).toHaveBeenCalledTimes(1);
+        });
+    });
+
     describe("write() output format", () => {
         let agent: Agent;
         let streamer: AgentStreamer;
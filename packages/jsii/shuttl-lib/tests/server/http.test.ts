@@ -1,4 +1,7 @@
 import { EventEmitter } from "events";
+import { mkdtempSync, writeFileSync } from "fs";
+import { tmpdir } from "os";
+import { join } from "path";
 
 // Create mock instances before jest.mock calls
 const mockStdin = new EventEmitter();
@@ -24,6 +27,7 @@ jest.mock("readline", () => ({
 
 import { StdInServer, IPCRequest, IPCResponse } from "../../src/server/http";
 import { Schema } from "../../src/tools/tool";
+import { ApiTrigger, Rate } from "../../src/trigger";
 
 describe("StdInServer", () => {
     let server: StdInServer;
@@ -183,6 +187,30 @@ describe("StdInServer", () => {
                 });
                 expect((response.result as Record<string, unknown>).timestamp).toBeGreaterThanOrEqual(now);
             });
+
+            it("should report ndjson framing and capabilities", () => {
+                sendRequest({ id: "1", method: "ping" });
+
+                expect(getLastResponse().result).toMatchObject({
+                    framing: "ndjson",
+                    capabilities: ["attachment_paths"],
+                });
+            });
+
+            it("should switch to content-length framing when offered", () => {
+                sendRequest({ id: "1", method: "ping", body: { framing: ["content-length", "ndjson"] } });
+                sendRequest({ id: "2", method: "getAppInfo" });
+
+                const frames = mockStdoutWrite.mock.calls.map((call) => call[0] as string);
+                for (const frame of frames) {
+                    const match = /^Content-Length: (\d+)\r\n\r\n(.*)$/s.exec(frame);
+                    expect(match).not.toBeNull();
+                    expect(Buffer.byteLength(match![2])).toBe(Number(match![1]));
+                }
+                const ping = JSON.parse(frames[0].split("\r\n\r\n")[1]) as IPCResponse;
+                expect(ping.result).toMatchObject({ pong: true, framing: "content-length" });
+                expect(JSON.parse(frames[1].split("\r\n\r\n")[1]).id).toBe("2");
+            });
         });
 
         describe("getAppInfo", () => {
@@ -245,6 +273,40 @@ describe("StdInServer", () => {
             });
         });
 
+        describe("listTriggers", () => {
+            it("should report each trigger's config as args", async () => {
+                const mockApp = {
+                    name: "TestApp",
+                    agents: [{
+                        name: "TestAgent",
+                        triggers: [
+                            Rate.minutes(5),
+                            Rate.cron("0 9 * * 1-5"),
+                            new ApiTrigger({ methods: ["GET"], route: "/orders/{id}" }).withName("orders"),
+                        ],
+                    }],
+                    toolkits: new Set(),
+                };
+
+                await server.stop();
+                server = new StdInServer();
+                server.accept(mockApp);
+                void server.start();
+                await new Promise((resolve) => setTimeout(resolve, 10));
+                jest.clearAllMocks();
+
+                sendRequest({ id: "4t", method: "listTriggers" });
+
+                const response = getLastResponse();
+                expect(response.success).toBe(true);
+                expect(response.result).toEqual([
+                    { name: "rate", triggerType: "rate", args: { ms_rate: 300000 }, agentName: "TestAgent" },
+                    { name: "cron", triggerType: "cron", args: { cronExpression: "0 9 * * 1-5", timezone: "UTC" }, agentName: "TestAgent" },
+                    { name: "orders", triggerType: "api", args: { methods: ["GET"], route: "/orders/{id}" }, agentName: "TestAgent" },
+                ]);
+            });
+        });
+
         describe("listToolkits", () => {
             it("should return empty array when no toolkits", () => {
                 sendRequest({ id: "5", method: "listToolkits" });
@@ -604,6 +666,23 @@ describe("StdInServer", () => {
             );
         });
 
+        it("should pass thread_id as sent by the CLI", async () => {
+            sendRequest({
+                id: "24b",
+                method: "invokeAgent",
+                body: { agent: "TestAgent", prompt: "Continue...", thread_id: "cli-thread" },
+            });
+
+            await new Promise((resolve) => setTimeout(resolve, 10));
+
+            expect(mockAgent.invoke).toHaveBeenCalledWith(
+                "Continue...",
+                "cli-thread",
+                expect.anything(),
+                undefined
+            );
+        });
+
         it("should pass attachments if provided", async () => {
             const attachments = [
                 { name: "test.txt", content: "SGVsbG8gV29ybGQ=", mimeType: "text/plain" },
@@ -624,6 +703,49 @@ describe("StdInServer", () => {
             );
         });
 
+        it("should read attachments sent by path", async () => {
+            const dir = mkdtempSync(join(tmpdir(), "shuttl-"));
+            const path = join(dir, "report.pdf");
+            writeFileSync(path, "%PDF-1.7");
+            sendRequest({
+                id: "25b",
+                method: "invokeAgent",
+                body: {
+                    agent: "TestAgent",
+                    prompt: "Summarize this",
+                    attachments: [{ name: "report.pdf", path, mimeType: "application/pdf" }],
+                },
+            });
+
+            await new Promise((resolve) => setTimeout(resolve, 10));
+
+            expect(mockAgent.invoke).toHaveBeenCalledWith(
+                "Summarize this",
+                undefined,
+                expect.anything(),
+                [{ name: "report.pdf", path, mimeType: "application/pdf", content: "JVBERi0xLjc=" }]
+            );
+        });
+
+        it("should return error if an attachment path cannot be read", async () => {
+            sendRequest({
+                id: "25c",
+                method: "invokeAgent",
+                body: {
+                    agent: "TestAgent",
+                    prompt: "Summarize this",
+                    attachments: [{ name: "gone.pdf", path: join(tmpdir(), "shuttl-missing", "gone.pdf") }],
+                },
+            });
+
+            await new Promise((resolve) => setTimeout(resolve, 10));
+
+            const response = getLastResponse();
+            expect(response.id).toBe("25c");
+            expect(response.errorObj?.code).toBe("INVALID_PARAMS");
+            expect(mockAgent.invoke).not.toHaveBeenCalled();
+        });
+
         it("should return error on agent invoke failure", async () => {
             mockAgent.invoke.mockRejectedValue(new Error("Agent failed"));
 
@@ -654,6 +776,69 @@ describe("StdInServer", () => {
         });
     });
 
+    describe("handleRespond()", () => {
+        let waitingAgent: { name: string; respond: jest.Mock };
+        let idleAgent: { name: string; respond: jest.Mock };
+
+        beforeEach(async () => {
+            idleAgent = { name: "IdleAgent", respond: jest.fn().mockReturnValue(false) };
+            waitingAgent = {
+                name: "WaitingAgent",
+                respond: jest.fn((threadId: string) => threadId === "thread-123"),
+            };
+            const mockApp = {
+                name: "TestApp",
+                agents: [idleAgent, waitingAgent],
+                toolkits: new Set(),
+            };
+
+            server.accept(mockApp);
+            void server.start();
+            await new Promise((resolve) => setTimeout(resolve, 10));
+            jest.clearAllMocks();
+        });
+
+        it("should pass the reply to the agent waiting in the thread", () => {
+            sendRequest({
+                id: "30",
+                method: "respond",
+                body: { thread_id: "thread-123", request_id: "r1", approved: true, response: "yes" },
+            });
+
+            expect(waitingAgent.respond).toHaveBeenCalledWith("thread-123", {
+                requestId: "r1",
+                response: "yes",
+                approved: true,
+            });
+            const response = getLastResponse();
+            expect(response.id).toBe("30");
+            expect(response.success).toBe(true);
+            expect(response.result).toMatchObject({
+                threadId: "thread-123",
+                agentName: "WaitingAgent",
+                status: "resumed",
+            });
+        });
+
+        it("should return error if thread_id is missing", () => {
+            sendRequest({ id: "31", method: "respond", body: { response: "yes" } });
+
+            const response = getLastResponse();
+            expect(response.success).toBe(false);
+            expect(response.errorObj?.code).toBe("INVALID_PARAMS");
+            expect(response.errorObj?.message).toContain("'thread_id'");
+        });
+
+        it("should return error if no agent is waiting in the thread", () => {
+            sendRequest({ id: "32", method: "respond", body: { thread_id: "other-thread", request_id: "r9" } });
+
+            const response = getLastResponse();
+            expect(response.success).toBe(false);
+            expect(response.errorObj?.code).toBe("NOT_FOUND");
+            expect(response.errorObj?.message).toContain("r9");
+        });
+    });
+
     describe("stop()", () => {
         it("should stop the server gracefully", async () => {
             const mockApp = { name: "TestApp", agents: [], toolkits: new Set() };
@@ -1,6 +1,6 @@
-import { Rate, ITrigger } from "../src/trigger";
+import { ApiTrigger, Rate, ITrigger } from "../src/trigger";
 
-describe.skip("Rate", () => {
+describe("Rate", () => {
     describe("milliseconds()", () => {
         it("should create a Rate with the exact millisecond value", () => {
             const rate = Rate.milliseconds(500);
@@ -200,12 +200,13 @@ describe.skip("Rate", () => {
     });
 });
 
-describe.skip("Trigger", () => {
+describe("Trigger", () => {
     describe("onCron()", () => {
         it("should create a cron trigger with expression and default name", () => {
             const trigger = Rate.cron("0 0 * * *");
 
             expect(trigger.triggerType).toBe("cron");
+            expect(trigger.name).toBe("cron");
             expect(trigger.triggerConfig.cronExpression).toEqual("0 0 * * *");
         });
 
@@ -275,5 +276,18 @@ describe.skip("Trigger", () => {
 
 });
 
+describe("ApiTrigger", () => {
+    it("should keep the configured methods and route", () => {
+        const trigger = new ApiTrigger({ methods: ["GET", "PUT"], route: "/orders/{id}" });
 
+        expect(trigger.triggerType).toBe("api");
+        expect(trigger.triggerConfig.methods).toEqual(["GET", "PUT"]);
+        expect(trigger.triggerConfig.route).toBe("/orders/{id}");
+    });
+
+    it("should default to allowing any origin", () => {
+        const trigger = new ApiTrigger();
 
+        expect(trigger.triggerConfig.cors).toEqual(["*"]);
+    });
+});